@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/backup"
+	"github.com/playmixer/short-link/internal/adapters/config"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+)
+
+// runBackup выполняет команду backup --out FILE: записывает резервную копию ссылок хранилища в файл.
+func runBackup(ctx context.Context, cfg *config.Config, lgr *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := flags.String("out", "", "backup file, gzip compressed JSONL")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("failed parse backup flags: %w", err)
+	}
+	if *out == "" {
+		return errors.New("usage: backup --out FILE")
+	}
+
+	store, err := storage.NewStore(ctx, &cfg.Store, lgr)
+	if err != nil {
+		return fmt.Errorf("failed initialize storage: %w", err)
+	}
+	defer store.Close()
+
+	info, err := backup.WriteFile(ctx, store, *out)
+	if err != nil {
+		return fmt.Errorf("failed backup: %w", err)
+	}
+	fmt.Printf("backup: %s, links: %d, deleted: %d, snapshot: %s\n",
+		info.File, info.Links, info.Deleted, info.CreatedAt.Format("2006-01-02T15:04:05Z07:00"))
+	return nil
+}
+
+// runRestore выполняет команду restore --in FILE [--mode merge|replace]: восстанавливает ссылки из резервной копии.
+// Ссылки, которые нельзя восстановить, выводятся как конфликты, команда при этом завершается ошибкой.
+func runRestore(ctx context.Context, cfg *config.Config, lgr *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	in := flags.String("in", "", "backup file")
+	mode := flags.String("mode", backup.ModeMerge, "restore mode: merge or replace")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("failed parse restore flags: %w", err)
+	}
+	if *in == "" {
+		return errors.New("usage: restore --in FILE [--mode merge|replace]")
+	}
+
+	store, err := storage.NewStore(ctx, &cfg.Store, lgr)
+	if err != nil {
+		return fmt.Errorf("failed initialize storage: %w", err)
+	}
+	defer store.Close()
+
+	res, err := backup.Restore(ctx, store, *in, *mode)
+	for _, c := range res.Conflicts {
+		fmt.Printf("conflict: domain: %q, short: %q, user: %q: %s\n", c.Link.Domain, c.Link.ShortURL, c.Link.UserID, c.Reason)
+	}
+	fmt.Printf("restore: %s, mode: %s, links: %d, restored: %d, skipped: %d, removed: %d, conflicts: %d\n",
+		*in, *mode, res.Info.Links, res.Restored, res.Skipped, res.Removed, len(res.Conflicts))
+	if err != nil {
+		return fmt.Errorf("failed restore: %w", err)
+	}
+	if len(res.Conflicts) > 0 {
+		return fmt.Errorf("restore finished with %d conflicts", len(res.Conflicts))
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,9 +15,13 @@ import (
 
 	"github.com/playmixer/short-link/internal/adapters/api/grpch"
 	"github.com/playmixer/short-link/internal/adapters/api/rest"
+	"github.com/playmixer/short-link/internal/adapters/archive"
+	"github.com/playmixer/short-link/internal/adapters/audit"
 	"github.com/playmixer/short-link/internal/adapters/auth"
+	"github.com/playmixer/short-link/internal/adapters/backup"
 	"github.com/playmixer/short-link/internal/adapters/config"
 	"github.com/playmixer/short-link/internal/adapters/logger"
+	"github.com/playmixer/short-link/internal/adapters/outbox"
 	"github.com/playmixer/short-link/internal/adapters/storage"
 	"github.com/playmixer/short-link/internal/core/shortner"
 	"github.com/playmixer/short-link/pkg/util"
@@ -47,11 +52,22 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("failed initialize config: %w", err)
 	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		return runMigrate(cfg, args[1:])
+	}
 
 	lgr, err := logger.New(cfg.LogLevel)
 	if err != nil {
 		return fmt.Errorf("failed initialize logger: %w", err)
 	}
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "backup":
+			return runBackup(ctx, cfg, lgr, args[1:])
+		case "restore":
+			return runRestore(ctx, cfg, lgr, args[1:])
+		}
+	}
 
 	store, err := storage.NewStore(ctx, &cfg.Store, lgr)
 	if err != nil {
@@ -59,23 +75,52 @@ func run() error {
 		return fmt.Errorf("failed initialize storage: %w", err)
 	}
 
+	relayDone, err := runOutboxRelay(ctx, cfg, store, lgr)
+	if err != nil {
+		return fmt.Errorf("failed initialize outbox relay: %w", err)
+	}
+
+	archiver, archiveDone, err := runArchiver(ctx, cfg, store, lgr)
+	if err != nil {
+		return fmt.Errorf("failed initialize archive: %w", err)
+	}
+
+	auditLog, err := audit.New(ctx, &cfg.Audit, lgr)
+	if err != nil {
+		return fmt.Errorf("failed initialize audit log: %w", err)
+	}
+
 	authManager, err := auth.New(auth.SetLogger(lgr), auth.SetSecretKey([]byte(cfg.API.SecretKey)))
 	if err != nil {
 		return fmt.Errorf("failed initializa auth manager: %w", err)
 	}
 
-	short := shortner.New(ctx, store, shortner.SetLogger(lgr))
+	shortOptions := []shortner.Option{
+		shortner.SetLogger(lgr),
+		shortner.SetDomains(cfg.Shortner.Domains),
+		shortner.SetRedirectCode(cfg.Shortner.RedirectCode),
+		shortner.SetReservedCodes(cfg.Shortner.ReservedCodes),
+		shortner.SetAuditor(auditLog),
+	}
+	if archiver != nil {
+		shortOptions = append(shortOptions, shortner.SetArchive(archiver))
+	}
+	short := shortner.New(ctx, store, shortOptions...)
 
-	httpServer := rest.New(
-		short,
-		authManager,
+	restOptions := []rest.Option{
 		rest.Addr(cfg.API.Rest.Addr),
 		rest.BaseURL(cfg.API.BaseURL),
 		rest.Logger(lgr),
 		rest.SecretKey([]byte(cfg.API.SecretKey)),
 		rest.HTTPSEnable(cfg.API.Rest.HTTPSEnable),
 		rest.TrastedSubnet(cfg.API.TrustedSubnet),
-	)
+		rest.TrustedProxies(cfg.API.TrustedProxies),
+		rest.Audit(auditLog),
+	}
+	if cfg.Backup.Enabled() {
+		restOptions = append(restOptions, rest.Backup(backup.NewService(store, cfg.Backup.Dir)))
+	}
+	httpServer := rest.New(short, authManager, restOptions...)
 
 	grpcServer, err := grpch.New(
 		short,
@@ -84,6 +129,7 @@ func run() error {
 		grpch.Logger(lgr),
 		grpch.SecretKey([]byte(cfg.API.SecretKey)),
 		grpch.TrustedSubnet(cfg.API.TrustedSubnet),
+		grpch.TrustedProxies(cfg.API.TrustedProxies),
 	)
 	if err != nil {
 		return fmt.Errorf("failed initialize grpc server: %w", err)
@@ -110,9 +156,79 @@ func run() error {
 	httpServer.Stop() // отключаем http сервер.
 	grpcServer.Stop() // отключаем grpc сервер.
 	short.Wait()      // ждем завершения горитин.
+	<-relayDone       // ждем завершения отправки событий.
+	<-archiveDone     // ждем завершения архивирования.
 	store.Close()     // закрываем соединение с бд.
+	auditLog.Close()  // закрываем журнал аудита.
 
 	<-ctxShutdown.Done()
 	lgr.Info("Service stoped")
 	return nil
 }
+
+// runOutboxRelay запускает отправку событий изменения ссылок до отмены контекста.
+// Возвращаемый канал закрывается после остановки отправки.
+func runOutboxRelay(ctx context.Context, cfg *config.Config, store storage.Store, lgr *zap.Logger) (
+	<-chan struct{},
+	error,
+) {
+	done := make(chan struct{})
+	if !cfg.Outbox.Enabled() {
+		close(done)
+		return done, nil
+	}
+	source, ok := store.(outbox.Source)
+	if !ok {
+		return nil, outbox.ErrNotSupported
+	}
+	sink, err := outbox.NewSink(&cfg.Outbox, lgr)
+	if err != nil {
+		return nil, fmt.Errorf("failed initialize outbox sink: %w", err)
+	}
+	relay := outbox.NewRelay(
+		source,
+		sink,
+		outbox.SetLogger(lgr),
+		outbox.SetInterval(cfg.Outbox.Interval),
+		outbox.SetBatchSize(cfg.Outbox.BatchSize),
+	)
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+	lgr.Info("outbox relay started", zap.String("sink", cfg.Outbox.Sink))
+	return done, nil
+}
+
+// runArchiver запускает архивирование ссылок до отмены контекста.
+// Возвращаемый канал закрывается после остановки архивирования и закрытия архива.
+func runArchiver(ctx context.Context, cfg *config.Config, store storage.Store, lgr *zap.Logger) (
+	*archive.Archiver,
+	<-chan struct{},
+	error,
+) {
+	done := make(chan struct{})
+	if !cfg.Archive.Enabled() {
+		close(done)
+		return nil, done, nil
+	}
+	source, ok := store.(archive.Store)
+	if !ok {
+		return nil, nil, archive.ErrNotSupported
+	}
+	if cfg.Archive.Mode == archive.ModeTable && cfg.Archive.DSN == "" {
+		cfg.Archive.DSN = cfg.Store.DatabaseDSN()
+	}
+	arch, err := archive.New(ctx, &cfg.Archive, lgr)
+	if err != nil {
+		return nil, nil, err
+	}
+	archiver := archive.NewArchiverFromConfig(source, arch, &cfg.Archive, lgr)
+	go func() {
+		defer close(done)
+		archiver.Run(ctx)
+		arch.Close()
+	}()
+	lgr.Info("archiver started", zap.String("mode", cfg.Archive.Mode))
+	return archiver, done, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/playmixer/short-link/internal/adapters/config"
+	"github.com/playmixer/short-link/internal/adapters/storage/database"
+	"github.com/playmixer/short-link/internal/adapters/storage/sqlite"
+)
+
+// runMigrate выполняет команду migrate: up, down [N], status, force VERSION.
+func runMigrate(cfg *config.Config, args []string) error {
+	if sqlite.IsDSN(cfg.Store.DSN) || sqlite.IsDSN(cfg.Store.Database.DSN) {
+		return errors.New("sqlite migrations are applied on storage start")
+	}
+	dsn := cfg.Store.DatabaseDSN()
+	if dsn == "" {
+		return errors.New("database dsn is not set")
+	}
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [N]|status|force VERSION")
+	}
+
+	m, err := database.NewMigrator(dsn)
+	if err != nil {
+		return fmt.Errorf("failed initialize migrator: %w", err)
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid number of steps `%s`", args[1])
+			}
+		}
+		err = m.Down(steps)
+	case "force":
+		if len(args) < 2 {
+			return errors.New("usage: migrate force VERSION")
+		}
+		var version int
+		if version, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("invalid version `%s`", args[1])
+		}
+		err = m.Force(version)
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate command `%s`", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed migrate %s: %w", args[0], err)
+	}
+
+	version, dirty, err := m.Status()
+	if err != nil {
+		return fmt.Errorf("failed migrate status: %w", err)
+	}
+	fmt.Printf("version: %d, dirty: %t\n", version, dirty)
+	return nil
+}
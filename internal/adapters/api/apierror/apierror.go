@@ -0,0 +1,199 @@
+// Модуль apierror сопоставляет ошибки сервиса и хранилищ кодам ответов REST и gRPC.
+//
+// Ошибки хранилищ сопоставляются по виду storeerror.Kind, ошибки параметров запроса сервиса - как некорректный запрос,
+// errors.ErrUnsupported - как неподдерживаемая операция, ErrForbidden - как запрет доступа.
+// Ошибки без вида считаются внутренними.
+// Кроме кода ответа каждой ошибке соответствует стабильный машиночитаемый код ошибки API.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+	"github.com/playmixer/short-link/internal/core/shortner"
+)
+
+// Коды ошибок API.
+const (
+	CodeInvalidArgument     = "invalid_argument"      // некорректный параметр запроса.
+	CodeInvalidBody         = "invalid_body"          // тело запроса не читается или не разбирается.
+	CodeInvalidURL          = "invalid_url"           // некорректная оригинальная ссылка.
+	CodeUnknownDomain       = "unknown_domain"        // домен коротких ссылок не настроен.
+	CodeInvalidRedirectCode = "invalid_redirect_code" // недопустимый код перенаправления.
+	CodeInvalidAlias        = "invalid_alias"         // недопустимый код короткой ссылки.
+	CodeReservedCode        = "reserved_code"         // код короткой ссылки занят маршрутом сервера.
+	CodeInvalidCursor       = "invalid_cursor"        // некорректный курсор страницы.
+	CodeInvalidPageLimit    = "invalid_page_limit"    // недопустимый размер страницы.
+	CodeInvalidStatsDays    = "invalid_stats_days"    // недопустимое окно статистики.
+	CodeInvalidStatsTop     = "invalid_stats_top"     // недопустимое количество пользователей статистики.
+	CodeUnauthorized        = "unauthorized"          // пользователь не авторизован.
+	CodeForbidden           = "forbidden"             // доступ запрещен.
+	CodeNotFound            = "not_found"             // ресурс не найден.
+	CodeConflict            = "conflict"              // ресурс уже существует.
+	CodeDeleted             = "deleted"               // ссылка удалена.
+	CodeExpired             = "expired"               // истек срок действия ссылки.
+	CodeNotImplemented      = "not_implemented"       // операция не поддерживается.
+	CodeUnavailable         = "unavailable"           // хранилище недоступно.
+	CodeTimeout             = "timeout"               // истекло время ожидания хранилища.
+	CodeInternal            = "internal"              // внутренняя ошибка.
+)
+
+// ErrForbidden - доступ к методу запрещен для адреса клиента.
+var ErrForbidden = errors.New("forbidden")
+
+// invalidArgument - ошибки некорректных параметров запроса и их коды.
+var invalidArgument = []struct {
+	err  error
+	code string
+}{
+	{shortner.ErrUnknownDomain, CodeUnknownDomain},
+	{shortner.ErrInvalidRedirectCode, CodeInvalidRedirectCode},
+	{shortner.ErrInvalidAlias, CodeInvalidAlias},
+	{shortner.ErrReservedCode, CodeReservedCode},
+	{shortner.ErrInvalidCursor, CodeInvalidCursor},
+	{shortner.ErrInvalidPageLimit, CodeInvalidPageLimit},
+	{shortner.ErrInvalidStatsDays, CodeInvalidStatsDays},
+	{shortner.ErrInvalidStatsTop, CodeInvalidStatsTop},
+}
+
+// invalidArgumentCode возвращает код ошибки некорректного параметра запроса, пустая строка - ошибка другого вида.
+func invalidArgumentCode(err error) string {
+	for _, target := range invalidArgument {
+		if errors.Is(err, target.err) {
+			return target.code
+		}
+	}
+	return ""
+}
+
+// IsInvalidArgument - ошибка вызвана некорректными параметрами запроса.
+func IsInvalidArgument(err error) bool {
+	return invalidArgumentCode(err) != ""
+}
+
+// Code возвращает код ошибки API, пустая строка - ошибки нет.
+func Code(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, ErrForbidden) {
+		return CodeForbidden
+	}
+	if code := invalidArgumentCode(err); code != "" {
+		return code
+	}
+	if errors.Is(err, errors.ErrUnsupported) {
+		return CodeNotImplemented
+	}
+	switch storeerror.KindOf(err) {
+	case storeerror.KindNotFound:
+		return CodeNotFound
+	case storeerror.KindConflict:
+		return CodeConflict
+	case storeerror.KindDeleted:
+		return CodeDeleted
+	case storeerror.KindExpired:
+		return CodeExpired
+	case storeerror.KindUnavailable:
+		return CodeUnavailable
+	case storeerror.KindTimeout:
+		return CodeTimeout
+	default:
+		return CodeInternal
+	}
+}
+
+// HTTPStatus возвращает код HTTP ответа для ошибки.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if errors.Is(err, ErrForbidden) {
+		return http.StatusForbidden
+	}
+	if IsInvalidArgument(err) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, errors.ErrUnsupported) {
+		return http.StatusNotImplemented
+	}
+	switch storeerror.KindOf(err) {
+	case storeerror.KindNotFound:
+		return http.StatusNotFound
+	case storeerror.KindConflict:
+		return http.StatusConflict
+	case storeerror.KindDeleted, storeerror.KindExpired:
+		return http.StatusGone
+	case storeerror.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case storeerror.KindTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCCode возвращает код gRPC для ошибки.
+func GRPCCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	if errors.Is(err, ErrForbidden) {
+		return codes.PermissionDenied
+	}
+	if IsInvalidArgument(err) {
+		return codes.InvalidArgument
+	}
+	if errors.Is(err, errors.ErrUnsupported) {
+		return codes.Unimplemented
+	}
+	switch storeerror.KindOf(err) {
+	case storeerror.KindNotFound, storeerror.KindDeleted, storeerror.KindExpired:
+		return codes.NotFound
+	case storeerror.KindConflict:
+		return codes.AlreadyExists
+	case storeerror.KindUnavailable:
+		return codes.Unavailable
+	case storeerror.KindTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// messages - описания ошибок сервиса и хранилищ для клиентов.
+var messages = map[string]string{
+	CodeForbidden:      "forbidden",
+	CodeNotFound:       "short link not found",
+	CodeConflict:       "short link already exists",
+	CodeDeleted:        "short link is deleted",
+	CodeExpired:        "short link is expired",
+	CodeNotImplemented: "operation is not supported by storage",
+	CodeUnavailable:    "storage is unavailable",
+	CodeTimeout:        "storage timeout",
+	CodeInternal:       "internal server error",
+}
+
+// Message возвращает описание ошибки, которое можно передать клиенту.
+// Текст ошибки возвращается только для ошибок параметров запроса, текст ошибок хранилищ и драйверов клиенту не передается.
+func Message(err error) string {
+	if IsInvalidArgument(err) {
+		return err.Error()
+	}
+	return messages[Code(err)]
+}
+
+// IsInternal - ошибка вызвана сбоем сервиса или хранилища, а не запросом клиента.
+func IsInternal(err error) bool {
+	return HTTPStatus(err) >= http.StatusInternalServerError
+}
+
+// GRPCError возвращает статус gRPC, соответствующий ошибке, с сообщением msg.
+// Сама ошибка в статус не попадает, внутренние ошибки записывает в журнал вызывающий.
+func GRPCError(err error, msg string) error {
+	return status.Error(GRPCCode(err), msg)
+}
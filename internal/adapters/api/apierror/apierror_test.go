@@ -0,0 +1,72 @@
+package apierror_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/playmixer/short-link/internal/adapters/api/apierror"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+	"github.com/playmixer/short-link/internal/core/shortner"
+)
+
+func TestMapping(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		http int
+		grpc codes.Code
+		code string
+	}{
+		{"nil", nil, http.StatusOK, codes.OK, ""},
+		{"invalid argument", fmt.Errorf("failed: %w", shortner.ErrInvalidAlias), http.StatusBadRequest, codes.InvalidArgument,
+			apierror.CodeInvalidAlias},
+		{"invalid stats", shortner.ErrInvalidStatsTop, http.StatusBadRequest, codes.InvalidArgument,
+			apierror.CodeInvalidStatsTop},
+		{"not found", storeerror.ErrNotFoundKey, http.StatusNotFound, codes.NotFound, apierror.CodeNotFound},
+		{"not unique", fmt.Errorf("failed: %w", storeerror.ErrNotUnique), http.StatusConflict, codes.AlreadyExists,
+			apierror.CodeConflict},
+		{"duplicate short", storeerror.ErrDuplicateShortURL, http.StatusConflict, codes.AlreadyExists, apierror.CodeConflict},
+		{"deleted", storeerror.ErrShortURLDeleted, http.StatusGone, codes.NotFound, apierror.CodeDeleted},
+		{"expired", storeerror.ErrExpired, http.StatusGone, codes.NotFound, apierror.CodeExpired},
+		{"unavailable", fmt.Errorf("%w: %w", storeerror.ErrUnavailable, errors.New("refused")),
+			http.StatusServiceUnavailable, codes.Unavailable, apierror.CodeUnavailable},
+		{"timeout", storeerror.ErrTimeout, http.StatusGatewayTimeout, codes.DeadlineExceeded, apierror.CodeTimeout},
+		{"unsupported", fmt.Errorf("failed: %w", errors.ErrUnsupported), http.StatusNotImplemented, codes.Unimplemented,
+			apierror.CodeNotImplemented},
+		{"forbidden", apierror.ErrForbidden, http.StatusForbidden, codes.PermissionDenied, apierror.CodeForbidden},
+		{"unknown", errors.New("failed"), http.StatusInternalServerError, codes.Internal, apierror.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.http, apierror.HTTPStatus(tt.err))
+			assert.Equal(t, tt.grpc, apierror.GRPCCode(tt.err))
+			assert.Equal(t, tt.code, apierror.Code(tt.err))
+		})
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	err := apierror.GRPCError(fmt.Errorf("failed get link: %w", storeerror.ErrNotFoundKey), "not found")
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "not found", st.Message())
+
+	err = apierror.GRPCError(fmt.Errorf("%w: dial tcp 10.0.0.1:5432", storeerror.ErrUnavailable), "storage is unavailable")
+	assert.Equal(t, "storage is unavailable", status.Convert(err).Message())
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "failed: invalid short link alias", apierror.Message(fmt.Errorf("failed: %w", shortner.ErrInvalidAlias)))
+	assert.Equal(t, "storage is unavailable",
+		apierror.Message(fmt.Errorf("%w: password authentication failed", storeerror.ErrUnavailable)))
+	assert.Equal(t, "internal server error", apierror.Message(errors.New("pq: relation does not exist")))
+	assert.True(t, apierror.IsInternal(storeerror.ErrTimeout))
+	assert.False(t, apierror.IsInternal(storeerror.ErrShortURLDeleted))
+}
@@ -12,4 +12,6 @@ type Config struct {
 	SecretKey     string `env:"SECRET_KEY"`
 	BaseURL       string `env:"BASE_URL"`
 	TrustedSubnet string `env:"TRUSTED_SUBNET"`
+	// TrustedProxies адреса и подсети прокси, заголовкам которых доверяется адрес клиента.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
 }
@@ -5,28 +5,33 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"strings"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/playmixer/short-link/internal/adapters/api/apierror"
 	pb "github.com/playmixer/short-link/internal/adapters/api/grpch/proto"
 	"github.com/playmixer/short-link/internal/adapters/models"
 )
 
 // Shortner интерфейс взаимодействия с сервисом сокращения ссылок.
 type Shortner interface {
-	Shorty(ctx context.Context, userID, link string) (string, error)
+	Shorty(ctx context.Context, userID string, link models.ShortLink) (string, error)
 	ShortyBatch(ctx context.Context, userID string, links []models.ShortenBatchRequest) (
 		[]models.ShortenBatchResponse,
 		error,
 	)
-	GetURL(ctx context.Context, short string) (string, error)
-	GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error)
+	GetLink(ctx context.Context, host, short string) (models.ShortLink, error)
+	GetUserURLs(ctx context.Context, userID, cursor string, limit int) ([]models.ShortenURL, string, error)
 	PingStore(ctx context.Context) error
 	DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) error
-	GetState(ctx context.Context) (models.ShortenStats, error)
+	GetUserURL(ctx context.Context, userID, domain, short string) (models.ShortLink, error)
+	DeleteUserURL(ctx context.Context, userID, domain, short string) error
+	GetState(ctx context.Context, days, top int) (models.ShortenStats, error)
+	Domains() []string
 }
 
 type AuthManager interface {
@@ -44,6 +49,8 @@ type Server struct {
 	auth          AuthManager
 	addr          string
 	trustedSubnet string
+	proxyList     []string
+	proxies       []netip.Prefix
 	secretKey     []byte
 }
 
@@ -78,6 +85,14 @@ func TrustedSubnet(subnet string) Option {
 	}
 }
 
+// TrustedProxies - адреса и подсети прокси, которым доверяются метаданные X-Forwarded-For и X-Real-IP.
+// Без доверенных прокси адрес клиента берется из соединения.
+func TrustedProxies(proxies []string) Option {
+	return func(s *Server) {
+		s.proxyList = proxies
+	}
+}
+
 // New создает Server.
 func New(short Shortner, auth AuthManager, options ...Option) (*Server, error) {
 	srv := &Server{
@@ -89,6 +104,7 @@ func New(short Shortner, auth AuthManager, options ...Option) (*Server, error) {
 	srv.s = grpc.NewServer(
 		grpc.ChainUnaryInterceptor(
 			srv.interceptorLogger,
+			srv.interceptorAuditClient,
 		),
 	)
 	srv.addr = "localhost:8081"
@@ -96,6 +112,13 @@ func New(short Shortner, auth AuthManager, options ...Option) (*Server, error) {
 	for _, opt := range options {
 		opt(srv)
 	}
+	for _, proxy := range srv.proxyList {
+		prefix, err := parseProxy(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed parse trusted proxy `%s`: %w", proxy, err)
+		}
+		srv.proxies = append(srv.proxies, prefix)
+	}
 
 	return srv, nil
 }
@@ -139,6 +162,40 @@ func (s *Server) getAuth(ctx context.Context) (string, error) {
 	return userID, nil
 }
 
+// statusError возвращает статус gRPC ошибки с сообщением для клиента msg, внутренние ошибки пишутся в журнал.
+func (s *Server) statusError(err error, msg, logMsg string) error {
+	if apierror.IsInternal(err) {
+		s.log.Error(logMsg, zap.Error(err))
+	}
+	return apierror.GRPCError(err, msg)
+}
+
+// parseProxy разбирает адрес или подсеть доверенного прокси.
+func parseProxy(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("failed parse subnet: %w", err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("failed parse address: %w", err)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// trustedProxy проверяет, что адрес принадлежит доверенному прокси.
+func (s *Server) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range s.proxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Server) getMetadata(ctx context.Context, name string) (string, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
 	"net/netip"
 	"net/url"
 	"strings"
@@ -13,6 +12,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/playmixer/short-link/internal/adapters/api/apierror"
 	pb "github.com/playmixer/short-link/internal/adapters/api/grpch/proto"
 	"github.com/playmixer/short-link/internal/adapters/models"
 	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
@@ -24,12 +24,13 @@ func (s *Server) Login(ctx context.Context, req *pb.LoginRequest) (response *pb.
 
 	id := req.GetId()
 	if id == "" {
-		return nil, errors.Join(status.Error(codes.NotFound, "id not found"), errors.New("id not found"))
+		return nil, status.Error(codes.NotFound, "id not found")
 	}
 
 	token, err := s.auth.CreateJWT(id)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed create jwt, error: %v", err)
+		s.log.Error("failed create jwt", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed create jwt")
 	}
 	response.AccessToken = token
 
@@ -42,24 +43,32 @@ func (s *Server) NewShort(ctx context.Context, req *pb.NewShortRequest) (respons
 
 	userID, err := s.getAuth(ctx)
 	if err != nil {
-		return response, errors.Join(status.Error(codes.Unauthenticated, err.Error()), err)
+		return response, status.Error(codes.Unauthenticated, err.Error())
 	}
 
 	link := strings.TrimSpace(req.GetOriginalUrl())
 	_, err = url.ParseRequestURI(link)
 	if err != nil {
 		response.Error = fmt.Sprintf("url invalid format `%s`", link)
-		return response, errors.Join(err, status.Errorf(codes.InvalidArgument, "url invalid format `%s`", link))
+		return response, status.Error(codes.InvalidArgument, response.Error)
 	}
-	sLink, err := s.short.Shorty(ctx, userID, req.GetOriginalUrl())
+	sLink, err := s.short.Shorty(ctx, userID, models.ShortLink{
+		ShortURL:     req.GetAlias(),
+		OriginalURL:  link,
+		Domain:       req.GetDomain(),
+		RedirectCode: int(req.GetRedirectCode()),
+	})
 	if err != nil {
 		if errors.Is(err, storeerror.ErrNotUnique) {
 			response.Short = sLink
 			response.Error = fmt.Sprintf("URI `%s` already shortened", req.GetOriginalUrl())
 			return response, nil
 		}
-		response.Error = fmt.Sprintf("failed create short url by original `%s`, error: %s", req.GetOriginalUrl(), err.Error())
-		return response, errors.Join(err, status.Error(codes.Aborted, err.Error()))
+		response.Error = apierror.Message(err)
+		if errors.Is(err, storeerror.ErrDuplicateShortURL) {
+			response.Error = fmt.Sprintf("alias `%s` already taken", req.GetAlias())
+		}
+		return response, s.statusError(err, response.Error, "failed create short url")
 	}
 	response.Short = sLink
 	return response, nil
@@ -71,22 +80,21 @@ func (s *Server) NewShorts(ctx context.Context, req *pb.NewShortsRequest) (*pb.N
 
 	userID, err := s.getAuth(ctx)
 	if err != nil {
-		return response, errors.Join(err, status.Error(codes.Unauthenticated, err.Error()))
+		return response, status.Error(codes.Unauthenticated, err.Error())
 	}
 
 	payload := []models.ShortenBatchRequest{}
 	for _, v := range req.GetOriginals() {
 		_, err = url.ParseRequestURI(v.GetOriginalUrl())
 		if err != nil {
-			response.Error = err.Error()
-			return response, errors.Join(
-				err,
-				status.Errorf(codes.Aborted, "url `%s` not valid, error: %s", v.GetOriginalUrl(), err.Error()),
-			)
+			response.Error = fmt.Sprintf("url `%s` not valid", v.GetOriginalUrl())
+			return response, status.Error(codes.InvalidArgument, response.Error)
 		}
 		payload = append(payload, models.ShortenBatchRequest{
 			CorrelationID: v.GetCorrelationId(),
 			OriginalURL:   v.GetOriginalUrl(),
+			Domain:        v.GetDomain(),
+			RedirectCode:  int(v.GetRedirectCode()),
 		})
 	}
 
@@ -96,14 +104,15 @@ func (s *Server) NewShorts(ctx context.Context, req *pb.NewShortsRequest) (*pb.N
 		response.Shorts = append(response.Shorts, &pb.ShortenBatchResponse{
 			CorrelationId: v.CorrelationID,
 			ShortUrl:      v.ShortURL,
+			Domain:        v.Domain,
 		})
 	}
 	if err != nil {
 		if errors.Is(err, storeerror.ErrNotUnique) {
-			return response, errors.Join(err, status.Error(codes.FailedPrecondition, "Conflict data"))
+			return response, apierror.GRPCError(err, "Conflict data")
 		}
-		response.Error = err.Error()
-		return response, errors.Join(err, status.Error(codes.Aborted, err.Error()))
+		response.Error = apierror.Message(err)
+		return response, s.statusError(err, response.Error, "failed create short urls")
 	}
 
 	return response, nil
@@ -113,17 +122,17 @@ func (s *Server) NewShorts(ctx context.Context, req *pb.NewShortsRequest) (*pb.N
 func (s *Server) GetURLByShort(ctx context.Context, req *pb.GetUrlByShortRequest) (*pb.GetURLByShortResponse, error) {
 	response := &pb.GetURLByShortResponse{}
 
-	link, err := s.short.GetURL(ctx, req.GetShortUrl())
+	link, err := s.short.GetLink(ctx, req.GetDomain(), req.GetShortUrl())
 	if err != nil {
+		response.Error = apierror.Message(err)
 		if errors.Is(err, storeerror.ErrShortURLDeleted) {
 			response.Error = "URL was deleted"
-			return response, errors.Join(err, status.Error(codes.NotFound, "URL was deleted"))
 		}
-		response.Error = err.Error()
-		return response, errors.Join(err, status.Error(codes.FailedPrecondition, err.Error()))
+		return response, s.statusError(err, response.Error, "failed get link")
 	}
 
-	response.OriginalUrl = link
+	response.OriginalUrl = link.OriginalURL
+	response.RedirectCode = int32(link.RedirectCode)
 	return response, nil
 }
 
@@ -133,22 +142,24 @@ func (s *Server) GetUserURLs(ctx context.Context, req *pb.GetUserURLsRequest) (*
 
 	userID, err := s.getAuth(ctx)
 	if err != nil {
-		return response, errors.Join(err, status.Error(codes.Unauthenticated, err.Error()))
+		return response, status.Error(codes.Unauthenticated, err.Error())
 	}
 
-	links, err := s.short.GetAllURL(ctx, userID)
+	links, next, err := s.short.GetUserURLs(ctx, userID, req.GetPageToken(), int(req.GetPageSize()))
 	if err != nil {
-		return response, errors.Join(err, status.Error(codes.Aborted, err.Error()))
+		return response, s.statusError(err, apierror.Message(err), "failed get user urls")
 	}
+	response.NextPageToken = next
 
 	for _, v := range links {
 		response.Urls = append(response.Urls, &pb.ShortenURLs{
 			ShortUrl:    v.ShortURL,
 			OriginalUrl: v.OriginalURL,
+			Domain:      v.Domain,
 		})
 	}
 	if len(links) == 0 {
-		return response, errors.Join(errors.New("failed get urls"), status.Error(codes.NotFound, "Not found"))
+		return response, status.Error(codes.NotFound, "Not found")
 	}
 
 	return response, nil
@@ -160,21 +171,67 @@ func (s *Server) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsReque
 
 	userID, err := s.getAuth(ctx)
 	if err != nil {
-		return response, errors.Join(err, status.Error(codes.Unauthenticated, err.Error()))
+		return response, status.Error(codes.Unauthenticated, err.Error())
 	}
 
 	data := []models.ShortLink{}
 	for _, short := range req.GetShortUrls() {
-		data = append(data, models.ShortLink{UserID: userID, ShortURL: short})
+		data = append(data, models.ShortLink{UserID: userID, ShortURL: short, Domain: req.GetDomain()})
 	}
 
 	err = s.short.DeleteShortURLs(ctx, data)
 	if err != nil {
-		return response, errors.Join(err, status.Error(codes.Aborted, err.Error()))
+		return response, s.statusError(err, apierror.Message(err), "failed delete user urls")
+	}
+	return response, nil
+}
+
+// GetUserURL получить ссылку пользователя, ссылка другого пользователя не найдена.
+func (s *Server) GetUserURL(ctx context.Context, req *pb.GetUserURLRequest) (*pb.GetUserURLResponse, error) {
+	response := &pb.GetUserURLResponse{}
+
+	userID, err := s.getAuth(ctx)
+	if err != nil {
+		return response, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	link, err := s.short.GetUserURL(ctx, userID, req.GetDomain(), req.GetShortUrl())
+	if err != nil {
+		return response, s.statusError(err, apierror.Message(err), "failed get user url")
+	}
+
+	response.ShortUrl = link.ShortURL
+	response.OriginalUrl = link.OriginalURL
+	response.Domain = link.Domain
+	response.RedirectCode = int32(link.RedirectCode)
+	if !link.CreatedAt.IsZero() {
+		response.CreatedAt = link.CreatedAt.Unix()
 	}
 	return response, nil
 }
 
+// DeleteUserURL удалить ссылку пользователя, ссылка другого пользователя не найдена.
+func (s *Server) DeleteUserURL(ctx context.Context, req *pb.DeleteUserURLRequest) (*pb.DeleteUserURLResponse, error) {
+	response := &pb.DeleteUserURLResponse{}
+
+	userID, err := s.getAuth(ctx)
+	if err != nil {
+		return response, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	err = s.short.DeleteUserURL(ctx, userID, req.GetDomain(), req.GetShortUrl())
+	if err != nil {
+		return response, s.statusError(err, apierror.Message(err), "failed delete user url")
+	}
+	return response, nil
+}
+
+// GetDomains список дополнительных доменов коротких ссылок.
+// Пустой домен в запросах соответствует домену по умолчанию.
+func (s *Server) GetDomains(ctx context.Context, req *pb.GetDomainsRequest) (*pb.GetDomainsResponse, error) {
+	return &pb.GetDomainsResponse{Domains: s.short.Domains()}, nil
+}
+
 // GetStatus статистика сохраненных ссылок.
 func (s *Server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.GetStatusResponse, error) {
 	response := &pb.GetStatusResponse{}
@@ -187,7 +244,7 @@ func (s *Server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.G
 	}
 	ipStr, err := s.getMetadata(ctx, "X-Real-IP")
 	if err != nil {
-		return response, status.Errorf(http.StatusForbidden, "not valid real ip `%s`", ipStr)
+		return response, apierror.GRPCError(apierror.ErrForbidden, "client address is not provided")
 	}
 	ip, err := netip.ParseAddr(ipStr)
 	if err != nil {
@@ -199,14 +256,22 @@ func (s *Server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.G
 	}
 
 	if !access {
-		return response, errors.Join(errors.New("forbidden"), status.Error(http.StatusForbidden, "forbidden"))
+		return response, apierror.GRPCError(apierror.ErrForbidden, "client address is not in trusted subnet")
 	}
 
-	stats, err := s.short.GetState(ctx)
+	stats, err := s.short.GetState(ctx, int(req.GetDays()), int(req.GetTop()))
 	if err != nil {
-		return response, errors.Join(err, status.Errorf(codes.Aborted, "failed to get state, error: %s", err.Error()))
+		return response, s.statusError(err, apierror.Message(err), "failed get state")
 	}
 	response.Urls = int32(stats.URLs)
 	response.Users = int32(stats.Users)
+	response.Deleted = int32(stats.Deleted)
+	response.StorageBytes = stats.StorageBytes
+	for _, day := range stats.CreatedPerDay {
+		response.CreatedPerDay = append(response.CreatedPerDay, &pb.DailyCount{Day: day.Day, Count: int32(day.Count)})
+	}
+	for _, user := range stats.TopUsers {
+		response.TopUsers = append(response.TopUsers, &pb.UserCount{UserId: user.UserID, Urls: int32(user.URLs)})
+	}
 	return response, nil
 }
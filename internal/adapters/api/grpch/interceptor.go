@@ -2,10 +2,17 @@ package grpch
 
 import (
 	"context"
+	"net"
+	"net/netip"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+
+	"github.com/playmixer/short-link/internal/adapters/audit"
+	"github.com/playmixer/short-link/internal/adapters/models"
 )
 
 func (s *Server) interceptorLogger(
@@ -22,3 +29,57 @@ func (s *Server) interceptorLogger(
 	}()
 	return handler(ctx, req)
 }
+
+// interceptorAuditClient сохраняет в контексте данные клиента для журнала аудита.
+func (s *Server) interceptorAuditClient(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (
+	interface{}, error,
+) {
+	return handler(audit.WithClient(ctx, models.TransportGRPC, s.clientIP(ctx)), req)
+}
+
+// clientIP возвращает адрес клиента из соединения. Метаданным X-Forwarded-For и X-Real-IP
+// доверяется, только если соединение установлено доверенным прокси.
+func (s *Server) clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !s.trustedProxy(addr) {
+		return ip
+	}
+	for _, name := range []string{"X-Forwarded-For", "X-Real-IP"} {
+		header, err := s.getMetadata(ctx, name)
+		if err != nil {
+			continue
+		}
+		if forwarded, ok := s.forwardedIP(header); ok {
+			return forwarded
+		}
+	}
+	return ip
+}
+
+// forwardedIP возвращает из списка адресов прокси ближайший справа адрес, не принадлежащий доверенному прокси.
+func (s *Server) forwardedIP(header string) (string, bool) {
+	items := strings.Split(header, ",")
+	for i := len(items) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(items[i]))
+		if err != nil {
+			return "", false
+		}
+		if i == 0 || !s.trustedProxy(addr) {
+			return addr.String(), true
+		}
+	}
+	return "", false
+}
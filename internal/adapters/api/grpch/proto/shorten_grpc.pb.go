@@ -26,6 +26,9 @@ const (
 	Shorten_GetURLByShort_FullMethodName  = "/grpch.proto.Shorten/GetURLByShort"
 	Shorten_GetUserURLs_FullMethodName    = "/grpch.proto.Shorten/GetUserURLs"
 	Shorten_DeleteUserURLs_FullMethodName = "/grpch.proto.Shorten/DeleteUserURLs"
+	Shorten_GetUserURL_FullMethodName     = "/grpch.proto.Shorten/GetUserURL"
+	Shorten_DeleteUserURL_FullMethodName  = "/grpch.proto.Shorten/DeleteUserURL"
+	Shorten_GetDomains_FullMethodName     = "/grpch.proto.Shorten/GetDomains"
 	Shorten_GetStatus_FullMethodName      = "/grpch.proto.Shorten/GetStatus"
 )
 
@@ -39,6 +42,9 @@ type ShortenClient interface {
 	GetURLByShort(ctx context.Context, in *GetUrlByShortRequest, opts ...grpc.CallOption) (*GetURLByShortResponse, error)
 	GetUserURLs(ctx context.Context, in *GetUserURLsRequest, opts ...grpc.CallOption) (*GetUserURLsResponse, error)
 	DeleteUserURLs(ctx context.Context, in *DeleteUserURLsRequest, opts ...grpc.CallOption) (*DeleteUserURLsRespons, error)
+	GetUserURL(ctx context.Context, in *GetUserURLRequest, opts ...grpc.CallOption) (*GetUserURLResponse, error)
+	DeleteUserURL(ctx context.Context, in *DeleteUserURLRequest, opts ...grpc.CallOption) (*DeleteUserURLResponse, error)
+	GetDomains(ctx context.Context, in *GetDomainsRequest, opts ...grpc.CallOption) (*GetDomainsResponse, error)
 	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
 }
 
@@ -110,6 +116,36 @@ func (c *shortenClient) DeleteUserURLs(ctx context.Context, in *DeleteUserURLsRe
 	return out, nil
 }
 
+func (c *shortenClient) GetUserURL(ctx context.Context, in *GetUserURLRequest, opts ...grpc.CallOption) (*GetUserURLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserURLResponse)
+	err := c.cc.Invoke(ctx, Shorten_GetUserURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) DeleteUserURL(ctx context.Context, in *DeleteUserURLRequest, opts ...grpc.CallOption) (*DeleteUserURLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteUserURLResponse)
+	err := c.cc.Invoke(ctx, Shorten_DeleteUserURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) GetDomains(ctx context.Context, in *GetDomainsRequest, opts ...grpc.CallOption) (*GetDomainsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDomainsResponse)
+	err := c.cc.Invoke(ctx, Shorten_GetDomains_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shortenClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetStatusResponse)
@@ -130,6 +166,9 @@ type ShortenServer interface {
 	GetURLByShort(context.Context, *GetUrlByShortRequest) (*GetURLByShortResponse, error)
 	GetUserURLs(context.Context, *GetUserURLsRequest) (*GetUserURLsResponse, error)
 	DeleteUserURLs(context.Context, *DeleteUserURLsRequest) (*DeleteUserURLsRespons, error)
+	GetUserURL(context.Context, *GetUserURLRequest) (*GetUserURLResponse, error)
+	DeleteUserURL(context.Context, *DeleteUserURLRequest) (*DeleteUserURLResponse, error)
+	GetDomains(context.Context, *GetDomainsRequest) (*GetDomainsResponse, error)
 	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
 	mustEmbedUnimplementedShortenServer()
 }
@@ -159,6 +198,15 @@ func (UnimplementedShortenServer) GetUserURLs(context.Context, *GetUserURLsReque
 func (UnimplementedShortenServer) DeleteUserURLs(context.Context, *DeleteUserURLsRequest) (*DeleteUserURLsRespons, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserURLs not implemented")
 }
+func (UnimplementedShortenServer) GetUserURL(context.Context, *GetUserURLRequest) (*GetUserURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserURL not implemented")
+}
+func (UnimplementedShortenServer) DeleteUserURL(context.Context, *DeleteUserURLRequest) (*DeleteUserURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserURL not implemented")
+}
+func (UnimplementedShortenServer) GetDomains(context.Context, *GetDomainsRequest) (*GetDomainsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDomains not implemented")
+}
 func (UnimplementedShortenServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
 }
@@ -291,6 +339,60 @@ func _Shorten_DeleteUserURLs_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shorten_GetUserURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).GetUserURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shorten_GetUserURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).GetUserURL(ctx, req.(*GetUserURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_DeleteUserURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).DeleteUserURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shorten_DeleteUserURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).DeleteUserURL(ctx, req.(*DeleteUserURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_GetDomains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDomainsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).GetDomains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shorten_GetDomains_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).GetDomains(ctx, req.(*GetDomainsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Shorten_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetStatusRequest)
 	if err := dec(in); err != nil {
@@ -340,6 +442,18 @@ var Shorten_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteUserURLs",
 			Handler:    _Shorten_DeleteUserURLs_Handler,
 		},
+		{
+			MethodName: "GetUserURL",
+			Handler:    _Shorten_GetUserURL_Handler,
+		},
+		{
+			MethodName: "DeleteUserURL",
+			Handler:    _Shorten_DeleteUserURL_Handler,
+		},
+		{
+			MethodName: "GetDomains",
+			Handler:    _Shorten_GetDomains_Handler,
+		},
 		{
 			MethodName: "GetStatus",
 			Handler:    _Shorten_GetStatus_Handler,
@@ -0,0 +1,33 @@
+package rest
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec - спецификация OpenAPI REST API, покрывает все маршруты SetupRouter, кроме debug/pprof.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// docsPage - страница документации, строится скриптом страницы по /api/openapi.json.
+// Страница не загружает ресурсы с других адресов, поэтому не зависит от внешних CDN.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// docsPolicy - политика безопасности страницы документации: только встроенные скрипты и стили и запросы к серверу.
+const docsPolicy = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'"
+
+// handlerOpenAPI - спецификация OpenAPI.
+func (s *Server) handlerOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, ApplicationJSON, openAPISpec)
+}
+
+// handlerDocs - страница интерактивной документации API.
+func (s *Server) handlerDocs(c *gin.Context) {
+	c.Header("Content-Security-Policy", docsPolicy)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
@@ -1,17 +1,21 @@
 package rest
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/playmixer/short-link/internal/adapters/api/apierror"
 	"github.com/playmixer/short-link/internal/adapters/models"
 	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
 )
@@ -25,7 +29,7 @@ func (s *Server) handlerMain(c *gin.Context) {
 	b, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		s.log.Error("can`t read body from request", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		writeProblem(c, http.StatusInternalServerError, apierror.CodeInternal, "failed read request body")
 		return
 	}
 	defer func() {
@@ -38,28 +42,43 @@ func (s *Server) handlerMain(c *gin.Context) {
 	link := strings.TrimSpace(string(b))
 	_, err = url.ParseRequestURI(link)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		writeProblem(c, http.StatusBadRequest, apierror.CodeInvalidURL, "request body must be an absolute URL")
 		return
 	}
 
 	userID, err := s.checkAuth(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		writeProblem(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "user is not authorized")
 		return
 	}
 
-	sLink, err := s.short.Shorty(ctx, userID, link)
+	var redirectCode int
+	if v := c.Query("redirect"); v != "" {
+		redirectCode, err = strconv.Atoi(v)
+		if err != nil || !models.IsValidRedirectCode(redirectCode) {
+			writeInvalidField(c, "redirect", apierror.CodeInvalidRedirectCode,
+				"redirect must be one of 301, 302, 307, 308")
+			return
+		}
+	}
+
+	domain := s.linkDomain(c.Query("domain"))
+	sLink, err := s.short.Shorty(ctx, userID, models.ShortLink{
+		ShortURL:     c.Query("alias"),
+		OriginalURL:  link,
+		Domain:       domain,
+		RedirectCode: redirectCode,
+	})
 	if err != nil {
 		if errors.Is(err, storeerror.ErrNotUnique) {
-			c.String(http.StatusConflict, s.baseLink(sLink))
+			c.String(http.StatusConflict, s.baseLink(domain, sLink))
 			return
 		}
-		s.log.Error("can't shorten URI", zap.String("URI", string(b)), zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, err, "can't shorten URI", zap.String("URI", string(b)))
 		return
 	}
 
-	c.String(http.StatusCreated, s.baseLink(sLink))
+	c.String(http.StatusCreated, s.baseLink(domain, sLink))
 }
 
 // handlerShort - перекидывает пользователя на оригинальную ссылку.
@@ -70,22 +89,23 @@ func (s *Server) handlerShort(c *gin.Context) {
 
 	id := c.Param("id")
 	if id == "" {
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		writeInvalidField(c, "id", apierror.CodeInvalidArgument, "short link code is required")
 		return
 	}
 
-	link, err := s.short.GetURL(ctx, id)
+	link, err := s.short.GetLink(ctx, c.Request.Host, id)
 	if err != nil {
-		if errors.Is(err, storeerror.ErrShortURLDeleted) {
-			c.Writer.WriteHeader(http.StatusGone)
-			return
-		}
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		s.writeError(c, err, "can't get link", zap.String("short", id))
 		return
 	}
 
-	c.Writer.Header().Add("Location", link)
-	c.Writer.WriteHeader(http.StatusTemporaryRedirect)
+	if models.IsPermanentRedirect(link.RedirectCode) {
+		c.Writer.Header().Set(CacheControl, fmt.Sprintf("public, max-age=%d", int(permanentRedirectMaxAge.Seconds())))
+	} else {
+		c.Writer.Header().Set(CacheControl, "private, no-cache")
+	}
+	c.Writer.Header().Add("Location", link.OriginalURL)
+	c.Writer.WriteHeader(link.RedirectCode)
 }
 
 // handlerAPIShorten - API метод, сокращает оригинальную ссылку.
@@ -94,58 +114,65 @@ func (s *Server) handlerAPIShorten(c *gin.Context) {
 	b, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		s.log.Error("can`t read body from request", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		writeProblem(c, http.StatusInternalServerError, apierror.CodeInternal, "failed read request body")
 		return
 	}
 	defer func() { _ = c.Request.Body.Close() }()
 
 	var req struct {
-		URL string `json:"url"`
+		URL          string `json:"url"`
+		Domain       string `json:"domain"`
+		Alias        string `json:"alias"`
+		RedirectCode int    `json:"redirect_code"`
 	}
 
 	err = json.Unmarshal(b, &req)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		writeProblem(c, http.StatusBadRequest, apierror.CodeInvalidBody, err.Error())
 		return
 	}
 
 	_, err = url.ParseRequestURI(req.URL)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		writeInvalidField(c, "url", apierror.CodeInvalidURL, "url must be an absolute URL")
 		return
 	}
 
 	userID, err := s.checkAuth(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		writeProblem(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "user is not authorized")
 		return
 	}
 
-	sLink, err := s.short.Shorty(ctx, userID, req.URL)
+	domain := s.linkDomain(req.Domain)
+	sLink, err := s.short.Shorty(ctx, userID, models.ShortLink{
+		ShortURL:     req.Alias,
+		OriginalURL:  req.URL,
+		Domain:       domain,
+		RedirectCode: req.RedirectCode,
+	})
 	if err != nil {
 		if errors.Is(err, storeerror.ErrNotUnique) {
 			c.Writer.Header().Add(ContentType, ApplicationJSON)
 			c.JSON(http.StatusConflict, gin.H{
-				"result": s.baseLink(sLink),
+				"result": s.baseLink(domain, sLink),
 			})
 			return
 		}
-		s.log.Error(fmt.Sprintf("can`t shorted URI `%s`", b), zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, err, "can`t shorted URI", zap.String("URI", string(b)))
 		return
 	}
 
 	c.Writer.Header().Add(ContentType, ApplicationJSON)
 	c.JSON(http.StatusCreated, gin.H{
-		"result": s.baseLink(sLink),
+		"result": s.baseLink(domain, sLink),
 	})
 }
 
 func (s *Server) handlerPing(c *gin.Context) {
 	err := s.short.PingStore(c.Request.Context())
 	if err != nil {
-		s.log.Info("failed ping database", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, err, "failed ping database")
 		return
 	}
 
@@ -157,7 +184,7 @@ func (s *Server) handlerAPIShortenBatch(c *gin.Context) {
 	b, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		s.log.Error("can`t read body from request", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		writeProblem(c, http.StatusInternalServerError, apierror.CodeInternal, "failed read request body")
 		return
 	}
 	defer func() { _ = c.Request.Body.Close() }()
@@ -165,26 +192,35 @@ func (s *Server) handlerAPIShortenBatch(c *gin.Context) {
 	var req []models.ShortenBatchRequest
 	err = json.Unmarshal(b, &req)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		writeProblem(c, http.StatusBadRequest, apierror.CodeInvalidBody, err.Error())
 		return
 	}
-	for _, v := range req {
+	var invalid []FieldError
+	for i, v := range req {
 		_, err = url.ParseRequestURI(v.OriginalURL)
 		if err != nil {
-			c.Writer.WriteHeader(http.StatusBadRequest)
-			return
+			invalid = append(invalid, FieldError{
+				Field:  fmt.Sprintf("[%d].original_url", i),
+				Code:   apierror.CodeInvalidURL,
+				Detail: "original_url must be an absolute URL",
+			})
 		}
+		req[i].Domain = s.linkDomain(v.Domain)
+	}
+	if len(invalid) > 0 {
+		writeProblem(c, http.StatusBadRequest, apierror.CodeInvalidURL, "batch contains invalid URLs", invalid...)
+		return
 	}
 
 	userID, err := s.checkAuth(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		writeProblem(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "user is not authorized")
 		return
 	}
 
 	sLink, err := s.short.ShortyBatch(ctx, userID, req)
 	for i, v := range sLink {
-		sLink[i].ShortURL = s.baseLink(v.ShortURL)
+		sLink[i].ShortURL = s.baseLink(v.Domain, v.ShortURL)
 	}
 	if err != nil {
 		if errors.Is(err, storeerror.ErrNotUnique) {
@@ -192,8 +228,7 @@ func (s *Server) handlerAPIShortenBatch(c *gin.Context) {
 			c.JSON(http.StatusConflict, sLink)
 			return
 		}
-		s.log.Error("can`t shorted URI", zap.String("URI", string(b)), zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, err, "can`t shorted URI", zap.String("URI", string(b)))
 		return
 	}
 
@@ -206,19 +241,29 @@ func (s *Server) handlerAPIGetUserURLs(c *gin.Context) {
 
 	userID, err := s.checkAuth(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		writeProblem(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "user is not authorized")
 		return
 	}
 
-	links, err := s.short.GetAllURL(ctx, userID)
+	var limit int
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			writeInvalidField(c, "limit", apierror.CodeInvalidPageLimit, "limit must be a positive integer")
+			return
+		}
+	}
+
+	links, next, err := s.short.GetUserURLs(ctx, userID, c.Query("cursor"), limit)
 	if err != nil {
-		s.log.Error("can`t getting URLs by user", zap.String(CookieNameUserID, userID), zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, err, "can`t getting URLs by user", zap.String(CookieNameUserID, userID))
 		return
 	}
 
 	for i := range links {
-		links[i].ShortURL = s.baseLink(links[i].ShortURL)
+		links[i].ShortURL = s.baseLink(links[i].Domain, links[i].ShortURL)
+	}
+	if next != "" {
+		c.Writer.Header().Add("Link", nextPageLink(c.Request.URL, next))
 	}
 	if len(links) == 0 {
 		c.Writer.WriteHeader(http.StatusNoContent)
@@ -229,19 +274,27 @@ func (s *Server) handlerAPIGetUserURLs(c *gin.Context) {
 	c.JSON(http.StatusOK, links)
 }
 
+// nextPageLink - заголовок Link со ссылкой на следующую страницу, остальные параметры запроса сохраняются.
+func nextPageLink(u *url.URL, cursor string) string {
+	query := u.Query()
+	query.Set("cursor", cursor)
+	next := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf("<%s>; rel=\"next\"", next.String())
+}
+
 func (s *Server) handlerAPIDeleteUserURLs(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	userID, err := s.checkAuth(c)
 	if err != nil {
-		c.Writer.WriteHeader(http.StatusUnauthorized)
+		writeProblem(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "user is not authorized")
 		return
 	}
 
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		s.log.Error("failed read body from request", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		writeProblem(c, http.StatusInternalServerError, apierror.CodeInternal, "failed read request body")
 		return
 	}
 
@@ -249,32 +302,216 @@ func (s *Server) handlerAPIDeleteUserURLs(c *gin.Context) {
 	err = json.Unmarshal(body, &jBody)
 	if err != nil {
 		s.log.Debug("invalid body", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusBadRequest)
+		writeProblem(c, http.StatusBadRequest, apierror.CodeInvalidBody, err.Error())
 		return
 	}
 
+	domain := s.linkDomain(c.Query("domain"))
 	data := []models.ShortLink{}
 	for _, short := range jBody {
-		data = append(data, models.ShortLink{UserID: userID, ShortURL: short})
+		data = append(data, models.ShortLink{UserID: userID, ShortURL: short, Domain: domain})
 	}
 
 	err = s.short.DeleteShortURLs(ctx, data)
 	if err != nil {
-		s.log.Error("delete short url error", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, err, "delete short url error")
 		return
 	}
 
 	c.Writer.WriteHeader(http.StatusAccepted)
 }
 
+// handlerAPIGetUserURL - данные ссылки пользователя.
+func (s *Server) handlerAPIGetUserURL(c *gin.Context) {
+	userID, err := s.checkAuth(c)
+	if err != nil {
+		writeProblem(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "user is not authorized")
+		return
+	}
+
+	short := c.Param("short")
+	link, err := s.short.GetUserURL(c.Request.Context(), userID, s.linkDomain(c.Query("domain")), short)
+	if err != nil {
+		s.writeError(c, err, "failed get user link", zap.String("short", short))
+		return
+	}
+
+	c.JSON(http.StatusOK, s.userLink(link))
+}
+
+// handlerAPIDeleteUserURL - удаление ссылки пользователя.
+func (s *Server) handlerAPIDeleteUserURL(c *gin.Context) {
+	userID, err := s.checkAuth(c)
+	if err != nil {
+		writeProblem(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "user is not authorized")
+		return
+	}
+
+	short := c.Param("short")
+	err = s.short.DeleteUserURL(c.Request.Context(), userID, s.linkDomain(c.Query("domain")), short)
+	if err != nil {
+		s.writeError(c, err, "failed delete user link", zap.String("short", short))
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// userLink - данные ссылки пользователя для ответа.
+func (s *Server) userLink(link models.ShortLink) models.UserLink {
+	result := models.UserLink{
+		ShortURL:     s.baseLink(link.Domain, link.ShortURL),
+		Code:         link.ShortURL,
+		OriginalURL:  link.OriginalURL,
+		Domain:       cmp.Or(link.Domain, s.defaultDomain()),
+		RedirectCode: link.RedirectCode,
+	}
+	if !link.CreatedAt.IsZero() {
+		createdAt := link.CreatedAt.UTC()
+		result.CreatedAt = &createdAt
+	}
+	return result
+}
+
 func (s *Server) handlerAPIInternalStats(c *gin.Context) {
-	stats, err := s.short.GetState(c.Request.Context())
+	var days, top int
+	var err error
+	if v := c.Query("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days <= 0 {
+			writeInvalidField(c, "days", apierror.CodeInvalidStatsDays, "days must be a positive integer")
+			return
+		}
+	}
+	if v := c.Query("top"); v != "" {
+		if top, err = strconv.Atoi(v); err != nil || top <= 0 {
+			writeInvalidField(c, "top", apierror.CodeInvalidStatsTop, "top must be a positive integer")
+			return
+		}
+	}
+
+	stats, err := s.short.GetState(c.Request.Context(), days, top)
 	if err != nil {
-		s.log.Error("failed get stats", zap.Error(err))
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		s.writeError(c, err, "failed get stats")
 		return
 	}
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// handlerAPIDomains - список доменов, доступных для сокращения ссылок.
+func (s *Server) handlerAPIDomains(c *gin.Context) {
+	domains := []models.ShortDomain{
+		{Domain: s.defaultDomain(), BaseURL: s.baseURL, Default: true},
+	}
+	for _, d := range s.short.Domains() {
+		domains = append(domains, models.ShortDomain{Domain: d, BaseURL: fmt.Sprintf("%s://%s", s.scheme(), d)})
+	}
+
+	c.Writer.Header().Add(ContentType, ApplicationJSON)
+	c.JSON(http.StatusOK, domains)
+}
+
+// handlerAPIInternalAudit - записи журнала аудита с фильтрацией.
+func (s *Server) handlerAPIInternalAudit(c *gin.Context) {
+	if s.audit == nil {
+		writeProblem(c, http.StatusNotFound, apierror.CodeNotFound, "audit log is not configured")
+		return
+	}
+
+	var err error
+	filter := models.AuditFilter{
+		UserID:    c.Query("user_id"),
+		Action:    c.Query("action"),
+		ShortURL:  c.Query("short_url"),
+		Transport: c.Query("transport"),
+	}
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			writeInvalidField(c, "from", apierror.CodeInvalidArgument, "from must be RFC 3339 time")
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			writeInvalidField(c, "to", apierror.CodeInvalidArgument, "to must be RFC 3339 time")
+			return
+		}
+	}
+	if v := c.Query("after_id"); v != "" {
+		if filter.AfterID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			writeInvalidField(c, "after_id", apierror.CodeInvalidArgument, "after_id must be an integer")
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeInvalidField(c, "limit", apierror.CodeInvalidArgument, "limit must be an integer")
+			return
+		}
+	}
+
+	events, err := s.audit.Find(c.Request.Context(), filter)
+	if err != nil {
+		s.writeError(c, err, "failed find audit events")
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// handlerAPIInternalAuditVerify - проверка целостности журнала аудита.
+func (s *Server) handlerAPIInternalAuditVerify(c *gin.Context) {
+	if s.audit == nil {
+		writeProblem(c, http.StatusNotFound, apierror.CodeNotFound, "audit log is not configured")
+		return
+	}
+
+	result, err := s.audit.Verify(c.Request.Context())
+	if err != nil {
+		s.writeError(c, err, "failed verify audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handlerAPIInternalBackup - создание резервной копии ссылок.
+func (s *Server) handlerAPIInternalBackup(c *gin.Context) {
+	if s.backup == nil {
+		writeProblem(c, http.StatusNotFound, apierror.CodeNotFound, "backup directory is not configured")
+		return
+	}
+
+	info, err := s.backup.Backup(c.Request.Context())
+	if err != nil {
+		s.writeError(c, err, "failed create backup")
+		return
+	}
+	s.log.Info("backup created", zap.String("file", info.File), zap.Int("links", info.Links))
+
+	c.JSON(http.StatusCreated, info)
+}
+
+// handlerNoRoute - ответ на запрос к несуществующему маршруту.
+func (s *Server) handlerNoRoute(c *gin.Context) {
+	writeProblem(c, http.StatusNotFound, apierror.CodeNotFound, "route not found")
+}
+
+// writeError отвечает описанием ошибки с кодом, соответствующим ей, ошибки сервера пишутся в журнал.
+// Текст ошибки возвращается клиенту только для ошибок параметров запроса.
+func (s *Server) writeError(c *gin.Context, err error, msg string, fields ...zap.Field) {
+	status := apierror.HTTPStatus(err)
+	if status >= http.StatusInternalServerError {
+		s.log.Error(msg, append(fields, zap.Error(err))...)
+	}
+	code := apierror.Code(err)
+	if !apierror.IsInvalidArgument(err) {
+		writeProblem(c, status, code, apierror.Message(err))
+		return
+	}
+	if field, ok := argumentFields[code]; ok {
+		writeInvalidField(c, field, code, err.Error())
+		return
+	}
+	writeProblem(c, status, code, err.Error())
+}
@@ -19,7 +19,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/playmixer/short-link/internal/adapters/api/rest"
+	"github.com/playmixer/short-link/internal/adapters/audit"
 	"github.com/playmixer/short-link/internal/adapters/auth"
+	"github.com/playmixer/short-link/internal/adapters/backup"
 	"github.com/playmixer/short-link/internal/adapters/config"
 	"github.com/playmixer/short-link/internal/adapters/models"
 	"github.com/playmixer/short-link/internal/adapters/storage"
@@ -68,7 +70,7 @@ func Test_mainHandle(t *testing.T) {
 				StatusCode:  http.StatusBadRequest,
 				Response:    "",
 				Request:     "",
-				ContentType: "text/plain",
+				ContentType: rest.ApplicationProblemJSON,
 			},
 		},
 		{
@@ -82,7 +84,7 @@ func Test_mainHandle(t *testing.T) {
 				StatusCode:  http.StatusBadRequest,
 				Response:    "",
 				Request:     "test?id=qweq",
-				ContentType: "text/plain",
+				ContentType: rest.ApplicationProblemJSON,
 			},
 		},
 		{
@@ -173,17 +175,17 @@ func Test_shortHandle(t *testing.T) {
 		}
 	}{
 		{
-			name: "bad request",
+			name: "not found",
 			want: struct {
 				StatusCode  int
 				Response    string
 				Request     string
 				ContentType string
 			}{
-				StatusCode:  http.StatusBadRequest,
+				StatusCode:  http.StatusNotFound,
 				Response:    "",
 				Request:     "",
-				ContentType: "text/plain",
+				ContentType: rest.ApplicationProblemJSON,
 			},
 		},
 	}
@@ -251,7 +253,7 @@ func Test_apiShorten(t *testing.T) {
 				StatusCode:  http.StatusBadRequest,
 				Response:    "",
 				Request:     tRequest{URL: ""},
-				ContentType: "",
+				ContentType: rest.ApplicationProblemJSON,
 			},
 		},
 		{
@@ -265,7 +267,7 @@ func Test_apiShorten(t *testing.T) {
 				StatusCode:  http.StatusBadRequest,
 				Response:    "",
 				Request:     tRequest{URL: "test?id=qweq"},
-				ContentType: "",
+				ContentType: rest.ApplicationProblemJSON,
 			},
 		},
 		{
@@ -464,7 +466,7 @@ func Test_apiShortenBatch(t *testing.T) {
 				Response: []models.ShortenBatchResponse{
 					{CorrelationID: "1"},
 				},
-				ContentType: "",
+				ContentType: rest.ApplicationProblemJSON,
 			},
 		},
 	}
@@ -770,3 +772,652 @@ func TestServer_handlerAPIGetUserURLs(t *testing.T) {
 		})
 	}
 }
+
+func Test_shortDomains(t *testing.T) {
+	initConfig(t)
+	type tWant struct {
+		StatusCode int
+		Location   string
+	}
+	tests := []struct {
+		name string
+		host string
+		want tWant
+	}{
+		{
+			name: "branded domain",
+			host: "go.brand.com",
+			want: tWant{StatusCode: http.StatusTemporaryRedirect, Location: "https://github.com/"},
+		},
+		{
+			name: "branded domain with port",
+			host: "go.brand.com:8080",
+			want: tWant{StatusCode: http.StatusTemporaryRedirect, Location: "https://github.com/"},
+		},
+		{
+			name: "default domain",
+			host: "localhost:8080",
+			want: tWant{StatusCode: http.StatusNotFound},
+		},
+	}
+
+	store, err := storage.NewStore(context.Background(), &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	s := shortner.New(context.Background(), store, shortner.SetDomains([]string{"go.brand.com"}))
+	srv := rest.New(s, authManager, rest.BaseURL("http://localhost:8080"))
+	router := srv.SetupRouter()
+
+	signedCookie, err := authManager.CreateJWT("1")
+	require.NoError(t, err)
+	cookie := &http.Cookie{Name: rest.CookieNameUserID, Value: signedCookie, Path: "/"}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url": "https://github.com/", "domain": "go.brand.com"}`))
+	r.AddCookie(cookie)
+	router.ServeHTTP(w, r)
+	result := w.Result()
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+	var res struct {
+		Result string `json:"result"`
+	}
+	err = json.NewDecoder(result.Body).Decode(&res)
+	require.NoError(t, err)
+	require.NoError(t, result.Body.Close())
+	shortURL, err := url.Parse(res.Result)
+	require.NoError(t, err)
+	require.Equal(t, "go.brand.com", shortURL.Host)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, shortURL.Path, http.NoBody)
+			r.Host = tt.host
+			r.AddCookie(cookie)
+
+			router.ServeHTTP(w, r)
+
+			result := w.Result()
+			assert.Equal(t, tt.want.StatusCode, result.StatusCode)
+			assert.Equal(t, tt.want.Location, result.Header.Get("Location"))
+			_ = result.Body.Close()
+		})
+	}
+
+	t.Run("unknown domain", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten",
+			strings.NewReader(`{"url": "https://github.com/", "domain": "evil.com"}`))
+		r.AddCookie(cookie)
+		router.ServeHTTP(w, r)
+		result := w.Result()
+		assert.Equal(t, http.StatusBadRequest, result.StatusCode)
+		_ = result.Body.Close()
+	})
+
+	t.Run("list domains", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/domains", http.NoBody)
+		r.AddCookie(cookie)
+		router.ServeHTTP(w, r)
+		result := w.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		var domains []models.ShortDomain
+		err := json.NewDecoder(result.Body).Decode(&domains)
+		require.NoError(t, err)
+		_ = result.Body.Close()
+		require.Equal(t, []models.ShortDomain{
+			{Domain: "localhost:8080", BaseURL: "http://localhost:8080", Default: true},
+			{Domain: "go.brand.com", BaseURL: "http://go.brand.com"},
+		}, domains)
+	})
+}
+
+func Test_redirectCodes(t *testing.T) {
+	initConfig(t)
+	type tWant struct {
+		CreateStatus   int
+		RedirectStatus int
+		CacheControl   string
+	}
+	tests := []struct {
+		name         string
+		url          string
+		redirectCode int
+		want         tWant
+	}{
+		{
+			name: "server default",
+			url:  "https://github.com/default",
+			want: tWant{
+				CreateStatus:   http.StatusCreated,
+				RedirectStatus: http.StatusFound,
+				CacheControl:   "private, no-cache",
+			},
+		},
+		{
+			name:         "moved permanently",
+			url:          "https://github.com/301",
+			redirectCode: http.StatusMovedPermanently,
+			want: tWant{
+				CreateStatus:   http.StatusCreated,
+				RedirectStatus: http.StatusMovedPermanently,
+				CacheControl:   "public, max-age=86400",
+			},
+		},
+		{
+			name:         "permanent redirect",
+			url:          "https://github.com/308",
+			redirectCode: http.StatusPermanentRedirect,
+			want: tWant{
+				CreateStatus:   http.StatusCreated,
+				RedirectStatus: http.StatusPermanentRedirect,
+				CacheControl:   "public, max-age=86400",
+			},
+		},
+		{
+			name:         "temporary redirect",
+			url:          "https://github.com/307",
+			redirectCode: http.StatusTemporaryRedirect,
+			want: tWant{
+				CreateStatus:   http.StatusCreated,
+				RedirectStatus: http.StatusTemporaryRedirect,
+				CacheControl:   "private, no-cache",
+			},
+		},
+		{
+			name:         "invalid code",
+			url:          "https://github.com/305",
+			redirectCode: http.StatusUseProxy,
+			want: tWant{
+				CreateStatus: http.StatusBadRequest,
+			},
+		},
+	}
+
+	store, err := storage.NewStore(context.Background(), &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	s := shortner.New(context.Background(), store, shortner.SetRedirectCode(http.StatusFound))
+	srv := rest.New(s, authManager, rest.BaseURL("http://localhost:8080"))
+	router := srv.SetupRouter()
+
+	signedCookie, err := authManager.CreateJWT("1")
+	require.NoError(t, err)
+	cookie := &http.Cookie{Name: rest.CookieNameUserID, Value: signedCookie, Path: "/"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody, err := json.Marshal(map[string]any{"url": tt.url, "redirect_code": tt.redirectCode})
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(reqBody))
+			r.AddCookie(cookie)
+			router.ServeHTTP(w, r)
+			result := w.Result()
+			require.Equal(t, tt.want.CreateStatus, result.StatusCode)
+			var res struct {
+				Result string `json:"result"`
+			}
+			_ = json.NewDecoder(result.Body).Decode(&res)
+			_ = result.Body.Close()
+			if tt.want.CreateStatus != http.StatusCreated {
+				return
+			}
+			shortURL, err := url.Parse(res.Result)
+			require.NoError(t, err)
+
+			w = httptest.NewRecorder()
+			r = httptest.NewRequest(http.MethodGet, shortURL.Path, http.NoBody)
+			r.AddCookie(cookie)
+			router.ServeHTTP(w, r)
+			result = w.Result()
+			assert.Equal(t, tt.want.RedirectStatus, result.StatusCode)
+			assert.Equal(t, tt.url, result.Header.Get("Location"))
+			assert.Equal(t, tt.want.CacheControl, result.Header.Get(rest.CacheControl))
+			_ = result.Body.Close()
+		})
+	}
+}
+
+func Test_internalAudit(t *testing.T) {
+	initConfig(t)
+	tests := []struct {
+		name       string
+		path       string
+		realIP     string
+		wantStatus int
+		wantEvents int
+	}{
+		{name: "all", path: "/api/internal/audit", realIP: "10.0.0.5", wantStatus: http.StatusOK, wantEvents: 2},
+		{
+			name:       "by action",
+			path:       "/api/internal/audit?action=delete",
+			realIP:     "10.0.0.5",
+			wantStatus: http.StatusOK,
+			wantEvents: 1,
+		},
+		{name: "bad filter", path: "/api/internal/audit?from=yesterday", realIP: "10.0.0.5", wantStatus: http.StatusBadRequest},
+		{name: "untrusted", path: "/api/internal/audit", realIP: "192.168.0.1", wantStatus: http.StatusForbidden},
+	}
+
+	auditLog, err := audit.New(context.Background(), &audit.Config{FilePath: t.TempDir() + "/audit.log"}, zap.NewNop())
+	require.NoError(t, err)
+	defer auditLog.Close()
+	store, err := storage.NewStore(context.Background(), &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	s := shortner.New(context.Background(), store, shortner.SetAuditor(auditLog))
+	srv := rest.New(s, authManager, rest.BaseURL(cfg.API.BaseURL), rest.TrastedSubnet("10.0.0.0/8"), rest.Audit(auditLog))
+	router := srv.SetupRouter()
+
+	signedCookie, err := authManager.CreateJWT("1")
+	require.NoError(t, err)
+	cookie := &http.Cookie{Name: rest.CookieNameUserID, Value: signedCookie, Path: "/"}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url": "https://github.com/", "alias": "QWE123"}`))
+	r.AddCookie(cookie)
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = httptest.NewRecorder()
+	// удаление отсутствующей ссылки не попадает в журнал.
+	r = httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["QWE123", "unknown"]`))
+	r.AddCookie(cookie)
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			r.Header.Set("X-Real-IP", tt.realIP)
+			router.ServeHTTP(w, r)
+
+			result := w.Result()
+			defer func() { _ = result.Body.Close() }()
+			require.Equal(t, tt.wantStatus, result.StatusCode)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var events []models.AuditEvent
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&events))
+			require.Len(t, events, tt.wantEvents)
+			for _, e := range events {
+				require.Equal(t, "1", e.UserID)
+				require.Equal(t, models.TransportREST, e.Transport)
+			}
+		})
+	}
+}
+
+func Test_auditClientIP(t *testing.T) {
+	initConfig(t)
+	tests := []struct {
+		name    string
+		proxies []string
+		wantIP  string
+	}{
+		{name: "forwarded header from client is ignored", wantIP: "192.0.2.1"},
+		{name: "forwarded header from trusted proxy", proxies: []string{"192.0.2.0/24"}, wantIP: "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog, err := audit.New(context.Background(), &audit.Config{FilePath: t.TempDir() + "/audit.log"}, zap.NewNop())
+			require.NoError(t, err)
+			defer auditLog.Close()
+			store, err := storage.NewStore(context.Background(), &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+			require.NoError(t, err)
+			authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+			require.NoError(t, err)
+			s := shortner.New(context.Background(), store, shortner.SetAuditor(auditLog))
+			srv := rest.New(s, authManager, rest.BaseURL(cfg.API.BaseURL), rest.TrustedProxies(tt.proxies), rest.Audit(auditLog))
+			router := srv.SetupRouter()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "https://github.com/"}`))
+			r.Header.Set("X-Forwarded-For", "203.0.113.7")
+			r.Header.Set("X-Real-IP", "203.0.113.7")
+			router.ServeHTTP(w, r)
+			require.Equal(t, http.StatusCreated, w.Code)
+
+			events, err := auditLog.Find(context.Background(), models.AuditFilter{Limit: 10})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, tt.wantIP, events[0].IP)
+		})
+	}
+}
+
+func Test_internalStats(t *testing.T) {
+	initConfig(t)
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantDays   int
+	}{
+		{name: "default", path: "/api/internal/stats", wantStatus: http.StatusOK, wantDays: 7},
+		{name: "window", path: "/api/internal/stats?days=30&top=1", wantStatus: http.StatusOK, wantDays: 30},
+		{name: "bad days", path: "/api/internal/stats?days=week", wantStatus: http.StatusBadRequest},
+		{name: "days out of range", path: "/api/internal/stats?days=1000", wantStatus: http.StatusBadRequest},
+		{name: "bad top", path: "/api/internal/stats?top=-1", wantStatus: http.StatusBadRequest},
+	}
+
+	store, err := storage.NewStore(context.Background(), &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	s := shortner.New(context.Background(), store)
+	srv := rest.New(s, authManager, rest.BaseURL(cfg.API.BaseURL), rest.TrastedSubnet("10.0.0.0/8"))
+	router := srv.SetupRouter()
+	_, err = s.Shorty(context.Background(), "1", models.ShortLink{OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			r.Header.Set("X-Real-IP", "10.0.0.5")
+			router.ServeHTTP(w, r)
+
+			result := w.Result()
+			defer func() { _ = result.Body.Close() }()
+			require.Equal(t, tt.wantStatus, result.StatusCode)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var stats models.ShortenStats
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&stats))
+			require.Equal(t, 1, stats.URLs)
+			require.Equal(t, 1, stats.Users)
+			require.Len(t, stats.CreatedPerDay, tt.wantDays)
+			require.Equal(t, 1, stats.CreatedPerDay[tt.wantDays-1].Count)
+			require.Equal(t, []models.UserCount{{UserID: "1", URLs: 1}}, stats.TopUsers)
+		})
+	}
+}
+
+func Test_internalBackup(t *testing.T) {
+	initConfig(t)
+	store, err := storage.NewStore(context.Background(), &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	s := shortner.New(context.Background(), store)
+	_, err = s.Shorty(context.Background(), "1", models.ShortLink{OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		options    []rest.Option
+		realIP     string
+		wantStatus int
+	}{
+		{name: "created", options: []rest.Option{rest.Backup(backup.NewService(store, dir))}, realIP: "10.0.0.5",
+			wantStatus: http.StatusCreated},
+		{name: "untrusted", options: []rest.Option{rest.Backup(backup.NewService(store, dir))}, realIP: "192.168.0.1",
+			wantStatus: http.StatusForbidden},
+		{name: "not configured", realIP: "10.0.0.5", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]rest.Option{rest.BaseURL(cfg.API.BaseURL), rest.TrastedSubnet("10.0.0.0/8")}, tt.options...)
+			router := rest.New(s, authManager, options...).SetupRouter()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/internal/backup", http.NoBody)
+			r.Header.Set("X-Real-IP", tt.realIP)
+			router.ServeHTTP(w, r)
+
+			result := w.Result()
+			defer func() { _ = result.Body.Close() }()
+			require.Equal(t, tt.wantStatus, result.StatusCode)
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			var info models.BackupInfo
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&info))
+			require.Equal(t, 1, info.Links)
+			require.FileExists(t, info.File)
+		})
+	}
+}
+
+func Test_reservedCodes(t *testing.T) {
+	initConfig(t)
+	tests := []struct {
+		name       string
+		alias      string
+		url        string
+		wantStatus int
+	}{
+		{name: "custom alias", alias: "github", url: "https://github.com/", wantStatus: http.StatusCreated},
+		{name: "alias taken", alias: "github", url: "https://gitlab.com/", wantStatus: http.StatusConflict},
+		{name: "route segment", alias: "ping", url: "https://gitlab.com/ping", wantStatus: http.StatusBadRequest},
+		{name: "api segment", alias: "api", url: "https://gitlab.com/api", wantStatus: http.StatusBadRequest},
+		{name: "configured", alias: "admin", url: "https://gitlab.com/admin", wantStatus: http.StatusBadRequest},
+		{name: "invalid alias", alias: "a b", url: "https://gitlab.com/space", wantStatus: http.StatusBadRequest},
+	}
+
+	store, err := storage.NewStore(context.Background(), &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	s := shortner.New(context.Background(), store, shortner.SetReservedCodes([]string{"admin"}))
+	srv := rest.New(s, authManager, rest.BaseURL("http://localhost:8080"))
+	router := srv.SetupRouter()
+
+	for _, code := range []string{"ping", "api", "debug"} {
+		assert.True(t, s.IsReserved(code), code)
+	}
+
+	signedCookie, err := authManager.CreateJWT("1")
+	require.NoError(t, err)
+	cookie := &http.Cookie{Name: rest.CookieNameUserID, Value: signedCookie, Path: "/"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody, err := json.Marshal(map[string]any{"url": tt.url, "alias": tt.alias})
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(reqBody))
+			r.AddCookie(cookie)
+			router.ServeHTTP(w, r)
+			result := w.Result()
+			assert.Equal(t, tt.wantStatus, result.StatusCode)
+			_ = result.Body.Close()
+		})
+	}
+}
+
+func TestServer_handlerAPIGetUserURLsPages(t *testing.T) {
+	initConfig(t)
+	ctx := context.Background()
+	store, err := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	for _, short := range []string{"a1", "a2", "a3"} {
+		_, err = store.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://" + short + ".ru/"})
+		require.NoError(t, err)
+	}
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	srv := rest.New(shortner.New(ctx, store), authManager, rest.Addr(cfg.API.Rest.Addr), rest.BaseURL(cfg.API.BaseURL))
+	router := srv.SetupRouter()
+	signedCookie, err := authManager.CreateJWT("1")
+	require.NoError(t, err)
+
+	get := func(target string) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		r.AddCookie(&http.Cookie{Name: rest.CookieNameUserID, Value: signedCookie, Path: "/"})
+		router.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	res := get("/api/user/urls?limit=2")
+	var links []models.ShortenURL
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, links, 2)
+	link := res.Header.Get("Link")
+	require.Regexp(t, `^</api/user/urls\?cursor=[\w-]+&limit=2>; rel="next"$`, link)
+
+	res = get(strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`))
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, links, 1)
+	require.Contains(t, links[0].ShortURL, "a3")
+	require.Empty(t, res.Header.Get("Link"))
+
+	for _, target := range []string{"/api/user/urls?limit=0", "/api/user/urls?limit=abc", "/api/user/urls?cursor=!"} {
+		res = get(target)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusBadRequest, res.StatusCode, target)
+	}
+}
+
+func Test_problemDetails(t *testing.T) {
+	initConfig(t)
+	store, err := storage.NewStore(context.Background(), &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	s := shortner.New(context.Background(), store)
+	router := rest.New(s, authManager, rest.BaseURL(cfg.API.BaseURL), rest.TrastedSubnet("10.0.0.0/8")).SetupRouter()
+	signedCookie, err := authManager.CreateJWT("1")
+	require.NoError(t, err)
+	cookie := &http.Cookie{Name: rest.CookieNameUserID, Value: signedCookie, Path: "/"}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		header     http.Header
+		noCookie   bool
+		wantStatus int
+		wantCode   string
+		wantFields []string
+	}{
+		{name: "invalid json", method: http.MethodPost, target: "/api/shorten", body: `{`,
+			wantStatus: http.StatusBadRequest, wantCode: "invalid_body"},
+		{name: "invalid url", method: http.MethodPost, target: "/api/shorten", body: `{"url": "github"}`,
+			wantStatus: http.StatusBadRequest, wantCode: "invalid_url", wantFields: []string{"url"}},
+		{name: "invalid alias", method: http.MethodPost, target: "/api/shorten", body: `{"url": "https://a.ru/", "alias": "!"}`,
+			wantStatus: http.StatusBadRequest, wantCode: "invalid_alias", wantFields: []string{"alias"}},
+		{name: "invalid redirect", method: http.MethodPost, target: "/?redirect=303", body: "https://a.ru/",
+			wantStatus: http.StatusBadRequest, wantCode: "invalid_redirect_code", wantFields: []string{"redirect"}},
+		{name: "invalid batch", method: http.MethodPost, target: "/api/shorten/batch",
+			body: `[{"correlation_id": "1", "original_url": "a"}, {"correlation_id": "2", "original_url": "https://a.ru/"},
+			{"correlation_id": "3", "original_url": "b"}]`,
+			wantStatus: http.StatusBadRequest, wantCode: "invalid_url", wantFields: []string{"[0].original_url", "[2].original_url"}},
+		{name: "invalid gzip", method: http.MethodPost, target: "/api/shorten", body: `{}`,
+			header: http.Header{"Content-Encoding": []string{"gzip"}}, wantStatus: http.StatusBadRequest, wantCode: "invalid_body"},
+		{name: "unauthorized", method: http.MethodGet, target: "/api/user/urls", noCookie: true,
+			wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+		{name: "forbidden", method: http.MethodGet, target: "/api/internal/stats",
+			wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "not found", method: http.MethodGet, target: "/unknown",
+			wantStatus: http.StatusNotFound, wantCode: "not_found"},
+		{name: "no route", method: http.MethodPut, target: "/api/unknown",
+			wantStatus: http.StatusNotFound, wantCode: "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			if !tt.noCookie {
+				r.AddCookie(cookie)
+			}
+			router.ServeHTTP(w, r)
+
+			result := w.Result()
+			defer func() { _ = result.Body.Close() }()
+			require.Equal(t, tt.wantStatus, result.StatusCode)
+			require.Equal(t, rest.ApplicationProblemJSON, result.Header.Get("Content-Type"))
+			var problem rest.Problem
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&problem))
+			require.Equal(t, tt.wantStatus, problem.Status)
+			require.Equal(t, tt.wantCode, problem.Code)
+			require.Equal(t, "urn:short-link:problem:"+tt.wantCode, problem.Type)
+			require.Equal(t, http.StatusText(tt.wantStatus), problem.Title)
+			require.NotEmpty(t, problem.Detail)
+			require.Equal(t, r.URL.Path, problem.Instance)
+			fields := []string{}
+			for _, e := range problem.Errors {
+				fields = append(fields, e.Field)
+				require.NotEmpty(t, e.Code)
+				require.NotEmpty(t, e.Detail)
+			}
+			require.ElementsMatch(t, tt.wantFields, fields)
+		})
+	}
+}
+
+func TestServer_handlerAPIUserURL(t *testing.T) {
+	initConfig(t)
+	ctx := context.Background()
+	store, err := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	_, err = store.Set(ctx, "1", models.ShortLink{ShortURL: "a1", OriginalURL: "https://a.ru/", RedirectCode: 301})
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	srv := rest.New(shortner.New(ctx, store), authManager, rest.BaseURL("http://localhost:8080"))
+	router := srv.SetupRouter()
+
+	do := func(method, target, userID string) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, http.NoBody)
+		signedCookie, err := authManager.CreateJWT(userID)
+		require.NoError(t, err)
+		r.AddCookie(&http.Cookie{Name: rest.CookieNameUserID, Value: signedCookie, Path: "/"})
+		router.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	res := do(http.MethodGet, "/api/user/urls/a1", "1")
+	var link models.UserLink
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&link))
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "http://localhost:8080/a1", link.ShortURL)
+	require.Equal(t, "a1", link.Code)
+	require.Equal(t, "https://a.ru/", link.OriginalURL)
+	require.Equal(t, "localhost:8080", link.Domain)
+	require.Equal(t, http.StatusMovedPermanently, link.RedirectCode)
+	require.NotNil(t, link.CreatedAt)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		userID     string
+		wantStatus int
+	}{
+		{"other user", http.MethodGet, "/api/user/urls/a1", "2", http.StatusNotFound},
+		{"other user delete", http.MethodDelete, "/api/user/urls/a1", "2", http.StatusNotFound},
+		{"not found", http.MethodGet, "/api/user/urls/a2", "1", http.StatusNotFound},
+		{"delete", http.MethodDelete, "/api/user/urls/a1?domain=localhost:8080", "1", http.StatusNoContent},
+		{"deleted", http.MethodGet, "/api/user/urls/a1", "1", http.StatusGone},
+		{"delete again", http.MethodDelete, "/api/user/urls/a1", "1", http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(tt.method, tt.target, tt.userID)
+			require.NoError(t, res.Body.Close())
+			require.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
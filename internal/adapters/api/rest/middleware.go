@@ -9,6 +9,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/api/apierror"
+	"github.com/playmixer/short-link/internal/adapters/audit"
+	"github.com/playmixer/short-link/internal/adapters/models"
 )
 
 // Logger middleware логирования.
@@ -27,14 +31,22 @@ func (s *Server) Logger() gin.HandlerFunc {
 	}
 }
 
+// AuditClient middleware сохраняет в контексте запроса данные клиента для журнала аудита.
+func (s *Server) AuditClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit.WithClient(c.Request.Context(), models.TransportREST, c.ClientIP())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
 // GzipDecompress middleware распаковка сжатых данных.
 func (s *Server) GzipDecompress() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if ok := strings.Contains(c.Request.Header.Get("Content-Encoding"), "gzip"); ok {
 			gr, err := NewGzipReader(c.Request.Body)
 			if err != nil {
-				c.Writer.WriteHeader(http.StatusBadRequest)
-				c.Abort()
+				writeProblem(c, http.StatusBadRequest, apierror.CodeInvalidBody, "request body is not valid gzip")
 				return
 			}
 			c.Request.Body = gr
@@ -81,8 +93,7 @@ func (s *Server) CheckCookies() gin.HandlerFunc {
 			signedCookie, err := s.auth.CreateJWT(uniqueID)
 			if err != nil {
 				s.log.Info("failed sign cookies", zap.Error(err))
-				c.Writer.WriteHeader(http.StatusInternalServerError)
-				c.Abort()
+				writeProblem(c, http.StatusInternalServerError, apierror.CodeInternal, "failed sign cookies")
 				return
 			}
 			userCookie = &http.Cookie{
@@ -103,8 +114,8 @@ func (s *Server) Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		_, err := s.checkAuth(c)
 		if err != nil {
-			c.Writer.WriteHeader(http.StatusUnauthorized)
-			c.Abort()
+			writeProblem(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "user is not authorized")
+			return
 		}
 
 		c.Next()
@@ -131,8 +142,8 @@ func (s *Server) TrustedSubnet() gin.HandlerFunc {
 		}
 
 		if !access {
-			c.Writer.WriteHeader(http.StatusForbidden)
-			c.Abort()
+			writeProblem(c, http.StatusForbidden, apierror.CodeForbidden, "client address is not in trusted subnet")
+			return
 		}
 		c.Next()
 	}
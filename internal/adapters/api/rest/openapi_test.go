@@ -0,0 +1,363 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/api/rest"
+	"github.com/playmixer/short-link/internal/adapters/audit"
+	"github.com/playmixer/short-link/internal/adapters/auth"
+	"github.com/playmixer/short-link/internal/adapters/backup"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/core/shortner"
+)
+
+// openAPI - часть документа OpenAPI, которую проверяет контрактный тест.
+type openAPI struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*jsonSchema     `json:"schemas"`
+		Responses map[string]openAPIResponse `json:"responses"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Responses map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIResponse struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *jsonSchema `json:"schema"`
+	} `json:"content"`
+}
+
+type jsonSchema struct {
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Items                *jsonSchema            `json:"items"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Format               string                 `json:"format"`
+	Enum                 []any                  `json:"enum"`
+	Required             []string               `json:"required"`
+}
+
+func (doc *openAPI) response(op openAPIOperation, code int) (openAPIResponse, bool) {
+	res, ok := op.Responses[strconv.Itoa(code)]
+	if ok && res.Ref != "" {
+		res, ok = doc.Components.Responses[strings.TrimPrefix(res.Ref, "#/components/responses/")]
+	}
+	return res, ok
+}
+
+// validate проверяет значение, полученное из JSON, на соответствие схеме.
+func (doc *openAPI) validate(value any, schema *jsonSchema, path string) error {
+	if schema.Ref != "" {
+		ref, ok := doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", path, schema.Ref)
+		}
+		schema = ref
+	}
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: want object, got %T", path, value)
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, v := range obj {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fmt.Errorf("%s: undocumented property %q", path, name)
+				}
+				continue
+			}
+			if err := doc.validate(v, prop, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: want array, got %T", path, value)
+		}
+		for i, v := range arr {
+			if err := doc.validate(v, schema.Items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: want integer, got %v", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: want boolean, got %T", path, value)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: want string, got %T", path, value)
+		}
+		return validateFormat(s, schema.Format, path)
+	}
+	return nil
+}
+
+func validateFormat(s, format, path string) error {
+	var err error
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, s)
+	case "date":
+		_, err = time.Parse(time.DateOnly, s)
+	case "uri":
+		_, err = url.ParseRequestURI(s)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: invalid %s %q: %w", path, format, s, err)
+	}
+	return nil
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// specPath - путь маршрута gin в записи OpenAPI.
+func specPath(route string) string {
+	return routeParam.ReplaceAllString(route, "{$1}")
+}
+
+// contract выполняет запросы к серверу и проверяет ответы по спецификации.
+type contract struct {
+	doc    *openAPI
+	router http.Handler
+	called map[string]bool
+}
+
+// do выполняет запрос к операции route спецификации и проверяет код, тип и тело ответа.
+func (c *contract) do(t *testing.T, route string, r *http.Request) (*http.Response, []byte) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c.router.ServeHTTP(w, r)
+	result := w.Result()
+	body, err := io.ReadAll(result.Body)
+	require.NoError(t, err)
+	require.NoError(t, result.Body.Close())
+
+	name := fmt.Sprintf("%s %s", r.Method, route)
+	c.called[name] = true
+	op, ok := c.doc.Paths[route][strings.ToLower(r.Method)]
+	require.True(t, ok, "operation %s is not documented", name)
+	res, ok := c.doc.response(op, result.StatusCode)
+	require.True(t, ok, "%s: response %d is not documented", name, result.StatusCode)
+
+	if len(res.Content) == 0 {
+		require.Empty(t, body, "%s: response %d must not have body", name, result.StatusCode)
+		return result, body
+	}
+	mediaType, _, err := mime.ParseMediaType(result.Header.Get(rest.ContentType))
+	require.NoError(t, err, name)
+	content, ok := res.Content[mediaType]
+	require.True(t, ok, "%s: response %d content type %s is not documented", name, result.StatusCode, mediaType)
+
+	var value any = string(body)
+	if mediaType == rest.ApplicationJSON || mediaType == rest.ApplicationProblemJSON {
+		require.NoError(t, json.Unmarshal(body, &value), name)
+	}
+	require.NoError(t, c.doc.validate(value, content.Schema, name), "%s: response %d", name, result.StatusCode)
+	return result, body
+}
+
+func newOpenAPIServer(t *testing.T) (*rest.Server, rest.AuthManager) {
+	t.Helper()
+	initConfig(t)
+	ctx := context.Background()
+	auditLog, err := audit.New(ctx, &audit.Config{FilePath: t.TempDir() + "/audit.log"}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(auditLog.Close)
+	store, err := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	require.NoError(t, err)
+	authManager, err := auth.New(auth.SetSecretKey([]byte("")))
+	require.NoError(t, err)
+	s := shortner.New(ctx, store, shortner.SetAuditor(auditLog))
+	srv := rest.New(s, authManager,
+		rest.BaseURL(cfg.API.BaseURL),
+		rest.TrastedSubnet("10.0.0.0/8"),
+		rest.Audit(auditLog),
+		rest.Backup(backup.NewService(store, t.TempDir())),
+	)
+	return srv, authManager
+}
+
+func loadOpenAPI(t *testing.T, router http.Handler) *openAPI {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc openAPI
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	return &doc
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	srv, _ := newOpenAPIServer(t)
+	router := srv.SetupRouter()
+	doc := loadOpenAPI(t, router)
+
+	routes := map[string]bool{}
+	for _, route := range router.Routes() {
+		if strings.HasPrefix(route.Path, "/debug/pprof") {
+			continue
+		}
+		name := fmt.Sprintf("%s %s", route.Method, specPath(route.Path))
+		routes[name] = true
+		_, ok := doc.Paths[specPath(route.Path)][strings.ToLower(route.Method)]
+		require.True(t, ok, "route %s is not documented", name)
+	}
+	for path, item := range doc.Paths {
+		for method := range item {
+			name := fmt.Sprintf("%s %s", strings.ToUpper(method), path)
+			require.True(t, routes[name], "documented operation %s has no route", name)
+		}
+	}
+}
+
+func TestOpenAPI_DocsPage(t *testing.T) {
+	srv, _ := newOpenAPIServer(t)
+	router := srv.SetupRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Header().Get("Content-Security-Policy"), "default-src 'none'")
+	require.NotRegexp(t, `(src|href)="(https?:)?//`, w.Body.String())
+	require.Contains(t, w.Body.String(), "/api/openapi.json")
+}
+
+func TestOpenAPI_Contract(t *testing.T) {
+	srv, authManager := newOpenAPIServer(t)
+	router := srv.SetupRouter()
+	c := &contract{doc: loadOpenAPI(t, router), router: router, called: map[string]bool{}}
+
+	cookie := func(userID string) *http.Cookie {
+		signed, err := authManager.CreateJWT(userID)
+		require.NoError(t, err)
+		return &http.Cookie{Name: rest.CookieNameUserID, Value: signed, Path: "/"}
+	}
+	request := func(method, target, body string, cookie *http.Cookie) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		r.Header.Set("X-Real-IP", "10.0.0.5")
+		return r
+	}
+	user := cookie("1")
+
+	tests := []struct {
+		name       string
+		route      string
+		request    *http.Request
+		wantStatus int
+	}{
+		{"main", "/", request(http.MethodPost, "/?alias=docs1", "https://practicum.yandex.ru/", user), http.StatusCreated},
+		{"main duplicate", "/", request(http.MethodPost, "/", "https://practicum.yandex.ru/", user), http.StatusConflict},
+		{"main invalid", "/", request(http.MethodPost, "/", "practicum", user), http.StatusBadRequest},
+		{"main bad alias", "/", request(http.MethodPost, "/?alias=!", "https://ya.ru/", user), http.StatusBadRequest},
+		{"redirect", "/{id}", request(http.MethodGet, "/docs1", "", nil), http.StatusTemporaryRedirect},
+		{"redirect not found", "/{id}", request(http.MethodGet, "/unknown", "", nil), http.StatusNotFound},
+		{"ping", "/ping", request(http.MethodGet, "/ping", "", nil), http.StatusOK},
+		{"shorten", "/api/shorten", request(http.MethodPost, "/api/shorten",
+			`{"url": "https://github.com/", "alias": "docs2", "redirect_code": 308}`, user), http.StatusCreated},
+		{"shorten duplicate", "/api/shorten", request(http.MethodPost, "/api/shorten",
+			`{"url": "https://github.com/"}`, user), http.StatusConflict},
+		{"shorten invalid", "/api/shorten", request(http.MethodPost, "/api/shorten", `{"url": 1}`, user),
+			http.StatusBadRequest},
+		{"batch", "/api/shorten/batch", request(http.MethodPost, "/api/shorten/batch",
+			`[{"correlation_id": "1", "original_url": "https://go.dev/"}]`, user), http.StatusCreated},
+		{"batch duplicate", "/api/shorten/batch", request(http.MethodPost, "/api/shorten/batch",
+			`[{"correlation_id": "1", "original_url": "https://go.dev/"}]`, user), http.StatusConflict},
+		{"domains", "/api/domains", request(http.MethodGet, "/api/domains", "", user), http.StatusOK},
+		{"user urls", "/api/user/urls", request(http.MethodGet, "/api/user/urls?limit=2", "", user), http.StatusOK},
+		{"user urls empty", "/api/user/urls", request(http.MethodGet, "/api/user/urls", "", cookie("2")),
+			http.StatusNoContent},
+		{"user urls unauthorized", "/api/user/urls", request(http.MethodGet, "/api/user/urls", "", nil),
+			http.StatusUnauthorized},
+		{"user urls bad limit", "/api/user/urls", request(http.MethodGet, "/api/user/urls?limit=0", "", user),
+			http.StatusBadRequest},
+		{"user url", "/api/user/urls/{short}", request(http.MethodGet, "/api/user/urls/docs1", "", user), http.StatusOK},
+		{"user url of other user", "/api/user/urls/{short}", request(http.MethodGet, "/api/user/urls/docs1", "", cookie("2")),
+			http.StatusNotFound},
+		{"delete", "/api/user/urls", request(http.MethodDelete, "/api/user/urls", `["docs1"]`, user),
+			http.StatusAccepted},
+		{"user url deleted", "/api/user/urls/{short}", request(http.MethodGet, "/api/user/urls/docs1", "", user),
+			http.StatusGone},
+		{"delete user url", "/api/user/urls/{short}", request(http.MethodDelete, "/api/user/urls/docs2", "", user),
+			http.StatusNoContent},
+		{"delete user url again", "/api/user/urls/{short}", request(http.MethodDelete, "/api/user/urls/docs2", "", user),
+			http.StatusGone},
+		{"delete user url not found", "/api/user/urls/{short}",
+			request(http.MethodDelete, "/api/user/urls/unknown", "", user), http.StatusNotFound},
+		{"delete invalid", "/api/user/urls", request(http.MethodDelete, "/api/user/urls", `{}`, user),
+			http.StatusBadRequest},
+		{"stats", "/api/internal/stats", request(http.MethodGet, "/api/internal/stats?days=2&top=1", "", nil),
+			http.StatusOK},
+		{"stats invalid", "/api/internal/stats", request(http.MethodGet, "/api/internal/stats?days=0", "", nil),
+			http.StatusBadRequest},
+		{"audit", "/api/internal/audit", request(http.MethodGet, "/api/internal/audit", "", nil), http.StatusOK},
+		{"audit invalid", "/api/internal/audit", request(http.MethodGet, "/api/internal/audit?to=now", "", nil),
+			http.StatusBadRequest},
+		{"audit verify", "/api/internal/audit/verify", request(http.MethodGet, "/api/internal/audit/verify", "", nil),
+			http.StatusOK},
+		{"backup", "/api/internal/backup", request(http.MethodPost, "/api/internal/backup", "", nil),
+			http.StatusCreated},
+		{"openapi", "/api/openapi.json", request(http.MethodGet, "/api/openapi.json", "", nil), http.StatusOK},
+		{"docs", "/api/docs", request(http.MethodGet, "/api/docs", "", nil), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _ := c.do(t, tt.route, tt.request)
+			require.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+
+	untrusted := request(http.MethodGet, "/api/internal/stats", "", nil)
+	untrusted.Header.Set("X-Real-IP", "192.168.0.1")
+	res, _ := c.do(t, "/api/internal/stats", untrusted)
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	for path, item := range c.doc.Paths {
+		for method := range item {
+			name := fmt.Sprintf("%s %s", strings.ToUpper(method), path)
+			require.True(t, c.called[name], "operation %s is not covered by contract test", name)
+		}
+	}
+}
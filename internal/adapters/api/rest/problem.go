@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/playmixer/short-link/internal/adapters/api/apierror"
+)
+
+// problemTypePrefix - префикс типа ошибки, тип ошибки - URN с кодом ошибки API.
+const problemTypePrefix = "urn:short-link:problem:"
+
+// Problem - описание ошибки по RFC 7807.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"` // код ошибки API.
+	Errors   []FieldError `json:"errors,omitempty"`
+	Status   int          `json:"status"`
+}
+
+// FieldError - ошибка значения поля или параметра запроса.
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// argumentFields - поля запроса, к которым относятся ошибки параметров сервиса.
+var argumentFields = map[string]string{
+	apierror.CodeUnknownDomain:       "domain",
+	apierror.CodeInvalidRedirectCode: "redirect_code",
+	apierror.CodeInvalidAlias:        "alias",
+	apierror.CodeReservedCode:        "alias",
+	apierror.CodeInvalidCursor:       "cursor",
+	apierror.CodeInvalidPageLimit:    "limit",
+	apierror.CodeInvalidStatsDays:    "days",
+	apierror.CodeInvalidStatsTop:     "top",
+}
+
+// writeProblem отвечает описанием ошибки и прерывает обработку запроса.
+func writeProblem(c *gin.Context, status int, code, detail string, errs ...FieldError) {
+	c.Header(ContentType, ApplicationProblemJSON)
+	c.AbortWithStatusJSON(status, Problem{
+		Type:     problemTypePrefix + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+		Errors:   errs,
+	})
+}
+
+// writeInvalidField отвечает ошибкой некорректного значения поля или параметра запроса.
+func writeInvalidField(c *gin.Context, field, code, detail string) {
+	writeProblem(c, http.StatusBadRequest, code, detail, FieldError{Field: field, Code: code, Detail: detail})
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/pprof"
@@ -21,6 +23,9 @@ const (
 	ContentLength   string = "Content-Length"   // заголовок длины конетента
 	ContentType     string = "Content-Type"     // заколовок типа контент
 	ApplicationJSON string = "application/json" // json контент
+	CacheControl    string = "Cache-Control"    // заголовок управления кэшированием
+
+	ApplicationProblemJSON string = "application/problem+json" // описание ошибки RFC 7807
 
 	CookieNameUserID string = "token" // поле хранения токента
 )
@@ -29,20 +34,38 @@ var (
 	errInvalidAuthCookie = errors.New("invalid authorization cookie")
 
 	shutdownDelay = time.Second * 5
+
+	permanentRedirectMaxAge = time.Hour * 24 // время кэширования постоянных перенаправлений.
 )
 
 // Shortner интерфейс взаимодействия с сервисом сокращения ссылок.
 type Shortner interface {
-	Shorty(ctx context.Context, userID, link string) (string, error)
+	Shorty(ctx context.Context, userID string, link models.ShortLink) (string, error)
 	ShortyBatch(ctx context.Context, userID string, links []models.ShortenBatchRequest) (
 		[]models.ShortenBatchResponse,
 		error,
 	)
-	GetURL(ctx context.Context, short string) (string, error)
-	GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error)
+	GetLink(ctx context.Context, host, short string) (models.ShortLink, error)
+	GetUserURLs(ctx context.Context, userID, cursor string, limit int) ([]models.ShortenURL, string, error)
+	GetUserURL(ctx context.Context, userID, domain, short string) (models.ShortLink, error)
+	DeleteUserURL(ctx context.Context, userID, domain, short string) error
 	PingStore(ctx context.Context) error
 	DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) error
-	GetState(ctx context.Context) (models.ShortenStats, error)
+	GetState(ctx context.Context, days, top int) (models.ShortenStats, error)
+	Domains() []string
+	ReserveCodes(codes ...string)
+	ShadowedLinks(ctx context.Context) ([]models.ShortLink, error)
+}
+
+// AuditLog интерфейс чтения журнала аудита.
+type AuditLog interface {
+	Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+	Verify(ctx context.Context) (models.AuditVerification, error)
+}
+
+// Backuper интерфейс создания резервных копий ссылок.
+type Backuper interface {
+	Backup(ctx context.Context) (models.BackupInfo, error)
 }
 
 type AuthManager interface {
@@ -55,8 +78,11 @@ type Server struct {
 	log           *zap.Logger
 	auth          AuthManager
 	short         Shortner
+	audit         AuditLog
+	backup        Backuper
 	baseURL       string
 	trustedSubnet string
+	proxies       []string
 	secretKey     []byte
 	s             http.Server
 	tlsEnable     bool
@@ -124,11 +150,38 @@ func TrastedSubnet(subnet string) Option {
 	}
 }
 
+// TrustedProxies - адреса и подсети прокси, которым доверяются заголовки X-Forwarded-For и X-Real-IP.
+// Без доверенных прокси адрес клиента берется из соединения.
+func TrustedProxies(proxies []string) Option {
+	return func(s *Server) {
+		s.proxies = proxies
+	}
+}
+
+// Audit - задает журнал аудита.
+func Audit(l AuditLog) Option {
+	return func(s *Server) {
+		s.audit = l
+	}
+}
+
+// Backup - задает создание резервных копий через внутренний API.
+func Backup(b Backuper) Option {
+	return func(s *Server) {
+		s.backup = b
+	}
+}
+
 // SetupRouter - создает маршруты.
 func (s *Server) SetupRouter() *gin.Engine {
 	r := gin.New()
+	if err := r.SetTrustedProxies(s.proxies); err != nil {
+		s.log.Error("trusted proxies are not valid, forwarded headers are ignored", zap.Error(err))
+		_ = r.SetTrustedProxies(nil)
+	}
 	r.Use(
 		s.Logger(),
+		s.AuditClient(),
 		s.GzipDecompress(),
 	)
 
@@ -144,6 +197,7 @@ func (s *Server) SetupRouter() *gin.Engine {
 		{
 			api.POST("/shorten", s.handlerAPIShorten)
 			api.POST("/shorten/batch", s.handlerAPIShortenBatch)
+			api.GET("/domains", s.handlerAPIDomains)
 		}
 	}
 
@@ -155,6 +209,8 @@ func (s *Server) SetupRouter() *gin.Engine {
 	{
 		userAPI.GET("/urls", s.handlerAPIGetUserURLs)
 		userAPI.DELETE("/urls", s.handlerAPIDeleteUserURLs)
+		userAPI.GET("/urls/:short", s.handlerAPIGetUserURL)
+		userAPI.DELETE("/urls/:short", s.handlerAPIDeleteUserURL)
 	}
 
 	interAPI := r.Group("/api/internal")
@@ -163,16 +219,58 @@ func (s *Server) SetupRouter() *gin.Engine {
 	)
 	{
 		interAPI.GET("/stats", s.handlerAPIInternalStats)
+		interAPI.GET("/audit", s.handlerAPIInternalAudit)
+		interAPI.GET("/audit/verify", s.handlerAPIInternalAuditVerify)
+		interAPI.POST("/backup", s.handlerAPIInternalBackup)
 	}
 
+	r.GET("/api/openapi.json", s.handlerOpenAPI)
+	r.GET("/api/docs", s.handlerDocs)
+	r.NoRoute(s.handlerNoRoute)
+
 	pprof.Register(r, "debug/pprof")
 
+	s.short.ReserveCodes(routeCodes(r.Routes())...)
+
 	return r
 }
 
+// routeCodes - статические первые сегменты маршрутов, которые перекрывают короткие ссылки.
+func routeCodes(routes gin.RoutesInfo) []string {
+	codes := []string{}
+	for _, route := range routes {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route.Path, "/"), "/")
+		if segment == "" || strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			continue
+		}
+		codes = append(codes, segment)
+	}
+	return codes
+}
+
+// checkShadowedLinks - сообщает о сохраненных ссылках, перекрытых маршрутами сервера.
+func (s *Server) checkShadowedLinks() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownDelay)
+	defer cancel()
+	links, err := s.short.ShadowedLinks(ctx)
+	if err != nil {
+		s.log.Error("failed check shadowed short links", zap.Error(err))
+		return
+	}
+	for _, link := range links {
+		s.log.Warn(
+			"short link is shadowed by server route",
+			zap.String("short", link.ShortURL),
+			zap.String("domain", link.Domain),
+			zap.String("original", link.OriginalURL),
+		)
+	}
+}
+
 // Run - запускает сервер.
 func (s *Server) Run() error {
 	s.s.Handler = s.SetupRouter().Handler()
+	s.checkShadowedLinks()
 	switch s.tlsEnable {
 	case false:
 		if err := s.s.ListenAndServe(); err != nil {
@@ -197,8 +295,41 @@ func (s *Server) Stop() {
 	s.log.Info("Server exiting")
 }
 
-func (s *Server) baseLink(short string) string {
-	return fmt.Sprintf("%s/%s", s.baseURL, short)
+func (s *Server) baseLink(domain, short string) string {
+	if domain == "" {
+		return fmt.Sprintf("%s/%s", s.baseURL, short)
+	}
+	return fmt.Sprintf("%s://%s/%s", s.scheme(), domain, short)
+}
+
+// scheme - схема коротких ссылок, берется из базового адреса.
+func (s *Server) scheme() string {
+	u, err := url.Parse(s.baseURL)
+	if err == nil && u.Scheme != "" {
+		return u.Scheme
+	}
+	if s.tlsEnable {
+		return "https"
+	}
+	return "http"
+}
+
+// defaultDomain - хост базового адреса коротких ссылок.
+func (s *Server) defaultDomain() string {
+	u, err := url.Parse(s.baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// linkDomain - домен ссылки из запроса, хост базового адреса соответствует домену по умолчанию.
+func (s *Server) linkDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if strings.EqualFold(domain, s.defaultDomain()) {
+		return ""
+	}
+	return domain
 }
 
 func (s *Server) checkAuth(c *gin.Context) (userID string, err error) {
@@ -0,0 +1,87 @@
+// Модуль archive переносит старые и неактивные ссылки из хранилища в архив.
+//
+// Archiver периодически выбирает в хранилище ссылки по политике архивирования, записывает их в архив
+// и удаляет из хранилища. Ссылка, не найденная в хранилище, ищется в архиве и при необходимости
+// возвращается в хранилище. Ссылки, пролежавшие в архиве дольше срока хранения, удаляются.
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+// ErrNotSupported - хранилище не поддерживает архивирование ссылок.
+var ErrNotSupported = errors.New("storage does not support archiving")
+
+// Source - хранилище, ссылки которого переносятся в архив.
+type Source interface {
+	// TouchLinks отмечает переходы по ссылкам в момент at.
+	TouchLinks(ctx context.Context, links []models.ShortLink, at time.Time) error
+	// ArchiveCandidates возвращает до limit неудаленных ссылок, по которым не переходили с inactiveSince
+	// или созданных раньше createdBefore. Нулевое время отключает условие.
+	ArchiveCandidates(ctx context.Context, inactiveSince, createdBefore time.Time, limit int) (
+		[]models.ArchivedLink, error)
+	// RemoveArchived удаляет перенесенные в архив ссылки, по которым не переходили после выбора,
+	// и возвращает удаленные.
+	RemoveArchived(ctx context.Context, links []models.ArchivedLink) ([]models.ArchivedLink, error)
+}
+
+// Store - хранилище ссылок, поддерживающее архивирование.
+type Store interface {
+	Source
+	// Set сохраняет ссылку, возвращенную из архива.
+	Set(ctx context.Context, userID string, link models.ShortLink) (string, error)
+}
+
+// Decrypter - хранилище, шифрующее оригинальные ссылки. Архив хранит ссылки в том виде, в котором их вернуло
+// хранилище, поэтому ссылка из архива расшифровывается хранилищем.
+type Decrypter interface {
+	DecryptArchived(link models.ArchivedLink) (models.ArchivedLink, error)
+}
+
+// Archive - хранилище архива.
+type Archive interface {
+	// Put сохраняет ссылки, заменяя ранее сохраненные с тем же доменом и коротким кодом.
+	Put(ctx context.Context, links []models.ArchivedLink) error
+	// Get возвращает ссылку из архива или storeerror.ErrNotFoundKey.
+	Get(ctx context.Context, domain, short string) (models.ArchivedLink, error)
+	// Delete удаляет ссылки из архива.
+	Delete(ctx context.Context, links []models.ArchivedLink) error
+	// Purge удаляет ссылки, перенесенные в архив раньше before, и возвращает их количество.
+	Purge(ctx context.Context, before time.Time) (int, error)
+	Close()
+}
+
+// New создает хранилище архива по конфигурации.
+func New(ctx context.Context, cfg *Config, log *zap.Logger) (Archive, error) {
+	switch cfg.Mode {
+	case ModeTable:
+		if cfg.DSN == "" {
+			return nil, errors.New("archive database dsn is not set")
+		}
+		a, err := newTable(ctx, cfg.DSN, !cfg.SkipMigrations)
+		if err != nil {
+			return nil, fmt.Errorf("failed initialize table archive: %w", err)
+		}
+		log.Info("table archive initialized")
+		return a, nil
+	case ModeFile:
+		if cfg.Dir == "" {
+			return nil, errors.New("archive dir is not set")
+		}
+		a, err := NewFiles(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed initialize file archive: %w", err)
+		}
+		log.Info("file archive initialized", zap.String("dir", cfg.Dir))
+		return a, nil
+	default:
+		return nil, fmt.Errorf("unknown archive mode `%s`", cfg.Mode)
+	}
+}
@@ -0,0 +1,175 @@
+package archive_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/archive"
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+func archived(short string, at time.Time) models.ArchivedLink {
+	return models.ArchivedLink{
+		ShortURL:    short,
+		OriginalURL: "https://" + short + ".ru/",
+		UserID:      "1",
+		CreatedAt:   at.Add(-time.Hour),
+		ArchivedAt:  at,
+	}
+}
+
+func TestFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	a, err := archive.NewFiles(dir)
+	require.NoError(t, err)
+	now := time.Now().UTC()
+
+	require.NoError(t, a.Put(ctx, []models.ArchivedLink{archived("a1", now), archived("a2", now)}))
+	require.NoError(t, a.Put(ctx, []models.ArchivedLink{archived("a3", now)}))
+	link, err := a.Get(ctx, "", "a2")
+	require.NoError(t, err)
+	require.Equal(t, "https://a2.ru/", link.OriginalURL)
+	require.True(t, now.Equal(link.ArchivedAt))
+	_, err = a.Get(ctx, "go.brand.com", "a2")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+
+	// повторная запись заменяет ссылку.
+	replaced := archived("a1", now)
+	replaced.OriginalURL = "https://new.ru/"
+	require.NoError(t, a.Put(ctx, []models.ArchivedLink{replaced}))
+	require.NoError(t, a.Delete(ctx, []models.ArchivedLink{archived("a2", now)}))
+
+	// индекс восстанавливается из файлов.
+	a, err = archive.NewFiles(dir)
+	require.NoError(t, err)
+	link, err = a.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://new.ru/", link.OriginalURL)
+	_, err = a.Get(ctx, "", "a2")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	n, err := a.Purge(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	n, err = a.Purge(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	_, err = a.Get(ctx, "", "a3")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func createStore(t *testing.T) *memory.Store {
+	t.Helper()
+	ctx := context.Background()
+	s, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	for _, short := range []string{"a1", "a2", "a3"} {
+		_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://" + short + ".ru/"})
+		require.NoError(t, err)
+	}
+	return s
+}
+
+func TestArchiver_ArchiveOnce(t *testing.T) {
+	ctx := context.Background()
+	store := createStore(t)
+	a, err := archive.NewFiles(t.TempDir())
+	require.NoError(t, err)
+	archiver := archive.NewArchiver(store, a, archive.SetInactivePeriod(time.Nanosecond), archive.SetBatchSize(1))
+
+	// без политики архивирования ссылки не переносятся.
+	n, err := archive.NewArchiver(store, a).ArchiveOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	time.Sleep(time.Millisecond)
+	archiver.Touch(models.ShortLink{ShortURL: "a2"})
+	n, err = archiver.ArchiveOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	_, err = store.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = store.Get(ctx, "", "a2")
+	require.NoError(t, err)
+	link, err := a.Get(ctx, "", "a3")
+	require.NoError(t, err)
+	require.Equal(t, "https://a3.ru/", link.OriginalURL)
+	require.False(t, link.ArchivedAt.IsZero())
+	_, err = a.Get(ctx, "", "a2")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+}
+
+func TestArchiver_Lookup(t *testing.T) {
+	ctx := context.Background()
+	store := createStore(t)
+	a, err := archive.NewFiles(t.TempDir())
+	require.NoError(t, err)
+	_, err = archive.NewArchiver(store, a, archive.SetMaxAge(time.Nanosecond)).ArchiveOnce(ctx)
+	require.NoError(t, err)
+
+	archiver := archive.NewArchiver(store, a)
+	ok, err := archiver.Contains(ctx, "", "a1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = archiver.Contains(ctx, "", "unknown")
+	require.NoError(t, err)
+	require.False(t, ok)
+	link, err := archiver.Lookup(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a1.ru/", link.OriginalURL)
+	require.Equal(t, "1", link.UserID)
+	_, err = store.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = archiver.Lookup(ctx, "", "unknown")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+
+	// возвращенная ссылка переносится из архива в хранилище.
+	archiver = archive.NewArchiver(store, a, archive.SetRehydrate(true))
+	link, err = archiver.Lookup(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a1.ru/", link.OriginalURL)
+	stored, err := store.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "1", stored.UserID)
+	_, err = a.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+
+	// ссылка, которую нельзя вернуть, остается в архиве.
+	_, err = store.Set(ctx, "1", models.ShortLink{ShortURL: "b2", OriginalURL: "https://a2.ru/"})
+	require.NoError(t, err)
+	link, err = archiver.Lookup(ctx, "", "a2")
+	require.NoError(t, err)
+	require.Equal(t, "https://a2.ru/", link.OriginalURL)
+	_, err = a.Get(ctx, "", "a2")
+	require.NoError(t, err)
+}
+
+func TestArchiver_PurgeOnce(t *testing.T) {
+	ctx := context.Background()
+	a, err := archive.NewFiles(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, a.Put(ctx, []models.ArchivedLink{archived("a1", time.Now())}))
+
+	n, err := archive.NewArchiver(createStore(t), a).PurgeOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	time.Sleep(time.Millisecond)
+	n, err = archive.NewArchiver(createStore(t), a, archive.SetRetention(time.Nanosecond)).PurgeOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
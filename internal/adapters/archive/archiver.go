@@ -0,0 +1,342 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+var (
+	defaultInterval      = time.Hour        // период архивирования по умолчанию.
+	defaultBatchSize     = 1000             // количество ссылок, переносимых за один раз, по умолчанию.
+	defaultFlushInterval = time.Second * 10 // период записи переходов в хранилище.
+)
+
+// Archiver переносит ссылки из хранилища в архив по политике архивирования.
+type Archiver struct {
+	store   Store
+	archive Archive
+	log     *zap.Logger
+	// inactive переносит ссылки без переходов за период, 0 - условие отключено.
+	inactive time.Duration
+	// maxAge переносит ссылки старше периода, 0 - условие отключено.
+	maxAge time.Duration
+	// retention срок хранения ссылок в архиве, 0 - хранятся бессрочно.
+	retention time.Duration
+	rehydrate bool
+	interval  time.Duration
+	batchSize int
+	mu        *sync.Mutex
+	touched   map[linkKey]models.ShortLink // переходы, ожидающие записи в хранилище.
+	stopped   bool                         // хранилище не поддерживает архивирование.
+}
+
+// Option интерфейс опции Archiver.
+type Option func(*Archiver)
+
+// SetLogger устанавливает логер.
+func SetLogger(log *zap.Logger) Option {
+	return func(a *Archiver) {
+		a.log = log
+	}
+}
+
+// SetInactivePeriod переносит в архив ссылки без переходов за период.
+func SetInactivePeriod(period time.Duration) Option {
+	return func(a *Archiver) {
+		a.inactive = max(period, 0)
+	}
+}
+
+// SetMaxAge переносит в архив ссылки старше периода.
+func SetMaxAge(age time.Duration) Option {
+	return func(a *Archiver) {
+		a.maxAge = max(age, 0)
+	}
+}
+
+// SetRetention устанавливает срок хранения ссылок в архиве.
+func SetRetention(retention time.Duration) Option {
+	return func(a *Archiver) {
+		a.retention = max(retention, 0)
+	}
+}
+
+// SetRehydrate включает возврат ссылки из архива в хранилище при переходе по ней.
+func SetRehydrate(rehydrate bool) Option {
+	return func(a *Archiver) {
+		a.rehydrate = rehydrate
+	}
+}
+
+// SetInterval устанавливает период архивирования.
+func SetInterval(interval time.Duration) Option {
+	return func(a *Archiver) {
+		if interval > 0 {
+			a.interval = interval
+		}
+	}
+}
+
+// SetBatchSize устанавливает количество ссылок, переносимых за один раз.
+func SetBatchSize(size int) Option {
+	return func(a *Archiver) {
+		if size > 0 {
+			a.batchSize = size
+		}
+	}
+}
+
+// NewArchiver создает Archiver.
+func NewArchiver(store Store, archive Archive, options ...Option) *Archiver {
+	a := &Archiver{
+		store:     store,
+		archive:   archive,
+		log:       zap.NewNop(),
+		interval:  defaultInterval,
+		batchSize: defaultBatchSize,
+		mu:        &sync.Mutex{},
+		touched:   make(map[linkKey]models.ShortLink),
+	}
+	for _, opt := range options {
+		opt(a)
+	}
+	return a
+}
+
+// NewArchiverFromConfig создает Archiver с политикой архивирования из конфигурации.
+func NewArchiverFromConfig(store Store, archive Archive, cfg *Config, log *zap.Logger) *Archiver {
+	return NewArchiver(store, archive,
+		SetLogger(log),
+		SetInactivePeriod(days(cfg.InactiveDays)),
+		SetMaxAge(days(cfg.MaxAgeDays)),
+		SetRetention(days(cfg.RetentionDays)),
+		SetRehydrate(cfg.Rehydrate),
+		SetInterval(cfg.Interval),
+		SetBatchSize(cfg.BatchSize),
+	)
+}
+
+// Touch отмечает переход по ссылке. Переходы записываются в хранилище порциями.
+func (a *Archiver) Touch(link models.ShortLink) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return
+	}
+	a.touched[linkKey{link.Domain, link.ShortURL}] = models.ShortLink{Domain: link.Domain, ShortURL: link.ShortURL}
+}
+
+// Flush записывает накопленные переходы в хранилище.
+func (a *Archiver) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	if len(a.touched) == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	links := make([]models.ShortLink, 0, len(a.touched))
+	for _, link := range a.touched {
+		links = append(links, link)
+	}
+	a.touched = make(map[linkKey]models.ShortLink)
+	a.mu.Unlock()
+
+	if err := a.store.TouchLinks(ctx, links, time.Now()); err != nil {
+		return fmt.Errorf("failed touch links: %w", err)
+	}
+	return nil
+}
+
+// ArchiveOnce переносит в архив все ссылки, подходящие под политику, и возвращает их количество.
+// Ссылка сначала записывается в архив, затем удаляется из хранилища, поэтому сбой не теряет ссылок:
+// оставшаяся в хранилище копия находится раньше архивной и переносится повторно.
+func (a *Archiver) ArchiveOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	var inactiveSince, createdBefore time.Time
+	if a.inactive > 0 {
+		inactiveSince = now.Add(-a.inactive)
+	}
+	if a.maxAge > 0 {
+		createdBefore = now.Add(-a.maxAge)
+	}
+	if inactiveSince.IsZero() && createdBefore.IsZero() {
+		return 0, nil
+	}
+	if err := a.Flush(ctx); err != nil {
+		return 0, err
+	}
+
+	var total int
+	for {
+		links, err := a.store.ArchiveCandidates(ctx, inactiveSince, createdBefore, a.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed select links for archive: %w", err)
+		}
+		if len(links) == 0 {
+			return total, nil
+		}
+		for i := range links {
+			links[i].ArchivedAt = now
+		}
+		if err = a.archive.Put(ctx, links); err != nil {
+			return total, fmt.Errorf("failed put links to archive: %w", err)
+		}
+		removed, err := a.store.RemoveArchived(ctx, links)
+		if err != nil {
+			return total, fmt.Errorf("failed remove archived links: %w", err)
+		}
+		total += len(removed)
+		// по ссылкам переходили после выбора, они остаются в хранилище.
+		if len(removed) < len(links) {
+			if err = a.archive.Delete(ctx, kept(links, removed)); err != nil {
+				return total, fmt.Errorf("failed delete active links from archive: %w", err)
+			}
+		}
+		if len(links) < a.batchSize || len(removed) == 0 {
+			return total, nil
+		}
+	}
+}
+
+// kept возвращает ссылки links, не вошедшие в removed.
+func kept(links, removed []models.ArchivedLink) []models.ArchivedLink {
+	gone := make(map[linkKey]struct{}, len(removed))
+	for _, link := range removed {
+		gone[linkKey{link.Domain, link.ShortURL}] = struct{}{}
+	}
+	result := make([]models.ArchivedLink, 0, len(links)-len(removed))
+	for _, link := range links {
+		if _, ok := gone[linkKey{link.Domain, link.ShortURL}]; !ok {
+			result = append(result, link)
+		}
+	}
+	return result
+}
+
+// PurgeOnce удаляет из архива ссылки старше срока хранения и возвращает их количество.
+func (a *Archiver) PurgeOnce(ctx context.Context) (int, error) {
+	if a.retention == 0 {
+		return 0, nil
+	}
+	n, err := a.archive.Purge(ctx, time.Now().Add(-a.retention))
+	if err != nil {
+		return n, fmt.Errorf("failed purge archive: %w", err)
+	}
+	return n, nil
+}
+
+// Lookup возвращает ссылку из архива или storeerror.ErrNotFoundKey.
+// При включенном возврате ссылка переносится из архива в хранилище; если сохранить ее не удалось,
+// ссылка остается в архиве и все равно возвращается.
+func (a *Archiver) Lookup(ctx context.Context, domain, short string) (models.ShortLink, error) {
+	archived, err := a.archive.Get(ctx, domain, short)
+	if err != nil {
+		return models.ShortLink{}, err
+	}
+	if d, ok := a.store.(Decrypter); ok {
+		if archived, err = d.DecryptArchived(archived); err != nil {
+			return models.ShortLink{}, fmt.Errorf("failed decrypt archived link: %w", err)
+		}
+	}
+	link := archived.Link()
+	if !a.rehydrate {
+		return link, nil
+	}
+	if _, err = a.store.Set(ctx, link.UserID, link); err != nil {
+		if !errors.Is(err, storeerror.ErrDuplicateShortURL) && !errors.Is(err, storeerror.ErrNotUnique) {
+			a.log.Warn("failed rehydrate archived link", zap.String("short", short), zap.Error(err))
+		}
+		return link, nil
+	}
+	if err = a.archive.Delete(ctx, []models.ArchivedLink{archived}); err != nil {
+		a.log.Warn("failed delete rehydrated link from archive", zap.String("short", short), zap.Error(err))
+	}
+	return link, nil
+}
+
+// Contains проверяет, есть ли ссылка в архиве, не возвращая ее в хранилище.
+func (a *Archiver) Contains(ctx context.Context, domain, short string) (bool, error) {
+	_, err := a.archive.Get(ctx, domain, short)
+	if errors.Is(err, storeerror.ErrNotFoundKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed get archived link: %w", err)
+	}
+	return true, nil
+}
+
+// Run архивирует ссылки и записывает переходы до отмены контекста.
+// Если хранилище не поддерживает архивирование, Run завершается.
+func (a *Archiver) Run(ctx context.Context) {
+	flush := time.NewTicker(min(defaultFlushInterval, a.interval))
+	defer flush.Stop()
+	tick := time.NewTicker(a.interval)
+	defer tick.Stop()
+
+	for {
+		if !a.runOnce(ctx) {
+			return
+		}
+		for loop := true; loop; {
+			select {
+			case <-ctx.Done():
+				// переходы последнего периода сохраняются и после отмены контекста.
+				if err := a.Flush(context.WithoutCancel(ctx)); err != nil {
+					a.log.Warn("failed flush link accesses", zap.Error(err))
+				}
+				return
+			case <-flush.C:
+				if err := a.Flush(ctx); err != nil && !a.stop(err) {
+					a.log.Warn("failed flush link accesses", zap.Error(err))
+				}
+			case <-tick.C:
+				loop = false
+			}
+		}
+	}
+}
+
+// runOnce архивирует ссылки и очищает архив, возвращает false, если хранилище не поддерживает архивирование.
+func (a *Archiver) runOnce(ctx context.Context) bool {
+	n, err := a.ArchiveOnce(ctx)
+	if err != nil {
+		if a.stop(err) {
+			return false
+		}
+		if ctx.Err() == nil {
+			a.log.Error("failed archive links", zap.Error(err))
+		}
+	}
+	if n > 0 {
+		a.log.Info("links archived", zap.Int("count", n))
+	}
+	n, err = a.PurgeOnce(ctx)
+	if err != nil && ctx.Err() == nil {
+		a.log.Error("failed purge archive", zap.Error(err))
+	}
+	if n > 0 {
+		a.log.Info("archived links purged", zap.Int("count", n))
+	}
+	return true
+}
+
+// stop останавливает учет переходов, если хранилище не поддерживает архивирование.
+func (a *Archiver) stop(err error) bool {
+	if !errors.Is(err, errors.ErrUnsupported) {
+		return false
+	}
+	a.log.Warn("storage does not support archiving, archiving stopped", zap.Error(err))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopped = true
+	a.touched = map[linkKey]models.ShortLink{}
+	return true
+}
@@ -0,0 +1,39 @@
+package archive
+
+import "time"
+
+// Хранилища архива.
+const (
+	ModeTable = "table" // таблица short_link_archive в PostgreSQL.
+	ModeFile  = "file"  // сжатые файлы JSONL в каталоге.
+)
+
+// Config конфигурация архивирования ссылок. Архивирование отключено, если хранилище архива не задано.
+type Config struct {
+	Mode string `env:"ARCHIVE_MODE"`
+	// DSN база архива, по умолчанию база хранилища ссылок.
+	DSN string `env:"ARCHIVE_DATABASE_DSN"`
+	Dir string `env:"ARCHIVE_DIR"`
+	// InactiveDays переносит в архив ссылки без переходов за указанное количество дней, 0 - условие отключено.
+	InactiveDays int `env:"ARCHIVE_INACTIVE_DAYS"`
+	// MaxAgeDays переносит в архив ссылки старше указанного количества дней, 0 - условие отключено.
+	MaxAgeDays int `env:"ARCHIVE_MAX_AGE_DAYS"`
+	// RetentionDays удаляет ссылки, пролежавшие в архиве указанное количество дней, 0 - хранятся бессрочно.
+	RetentionDays int `env:"ARCHIVE_RETENTION_DAYS"`
+	// Rehydrate возвращает ссылку из архива в хранилище при переходе по ней.
+	Rehydrate bool          `env:"ARCHIVE_REHYDRATE"`
+	Interval  time.Duration `env:"ARCHIVE_INTERVAL"`   // период архивирования.
+	BatchSize int           `env:"ARCHIVE_BATCH_SIZE"` // количество ссылок, переносимых за один раз.
+	// SkipMigrations отключает применение миграций базы данных при запуске.
+	SkipMigrations bool `env:"DATABASE_SKIP_MIGRATIONS"`
+}
+
+// Enabled - архивирование включено.
+func (c *Config) Enabled() bool {
+	return c.Mode != ""
+}
+
+// days переводит количество дней в продолжительность.
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
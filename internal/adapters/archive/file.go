@@ -0,0 +1,292 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+const (
+	filePrefix = "archive-"
+	fileSuffix = ".jsonl.gz"
+)
+
+// linkKey - ключ ссылки: короткий код уникален в пределах домена.
+type linkKey struct {
+	domain string
+	short  string
+}
+
+// Files архив в сжатых файлах JSONL.
+// Каждая запись в архив создает файл с временем архивирования в имени, поэтому срок хранения
+// проверяется по имени файла. В памяти хранится только индекс ссылок по файлам.
+type Files struct {
+	mu    *sync.RWMutex
+	dir   string
+	index map[linkKey]string              // файл ссылки.
+	files map[string]map[linkKey]struct{} // ссылки файла.
+	last  int64                           // время последнего файла, имена файлов не повторяются.
+}
+
+// NewFiles создает архив в каталоге dir и загружает индекс существующих файлов.
+func NewFiles(dir string) (*Files, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed create archive dir: %w", err)
+	}
+	a := &Files{
+		mu:    &sync.RWMutex{},
+		dir:   dir,
+		index: make(map[linkKey]string),
+		files: make(map[string]map[linkKey]struct{}),
+	}
+	names, err := a.list()
+	if err != nil {
+		return nil, err
+	}
+	// файлы читаются по времени создания, более поздняя запись ссылки заменяет раннюю.
+	for _, name := range names {
+		links, err := a.read(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, link := range links {
+			a.add(name, linkKey{link.Domain, link.ShortURL})
+		}
+		a.last = fileTime(name).UnixNano()
+	}
+	return a, nil
+}
+
+// Put записывает ссылки в новый файл архива.
+func (a *Files) Put(ctx context.Context, links []models.ArchivedLink) error {
+	if len(links) == 0 {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.drop(links); err != nil {
+		return err
+	}
+	a.last = max(time.Now().UnixNano(), a.last+1)
+	name := fmt.Sprintf("%s%020d%s", filePrefix, a.last, fileSuffix)
+	if err := a.write(name, links); err != nil {
+		return err
+	}
+	for _, link := range links {
+		a.add(name, linkKey{link.Domain, link.ShortURL})
+	}
+	return nil
+}
+
+// Get возвращает ссылку из архива.
+func (a *Files) Get(ctx context.Context, domain, short string) (models.ArchivedLink, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	name, ok := a.index[linkKey{domain, short}]
+	if !ok {
+		return models.ArchivedLink{}, storeerror.ErrNotFoundKey
+	}
+	links, err := a.read(name)
+	if err != nil {
+		return models.ArchivedLink{}, err
+	}
+	for _, link := range links {
+		if link.Domain == domain && link.ShortURL == short {
+			return link, nil
+		}
+	}
+	return models.ArchivedLink{}, storeerror.ErrNotFoundKey
+}
+
+// Delete удаляет ссылки из архива, перезаписывая содержащие их файлы.
+func (a *Files) Delete(ctx context.Context, links []models.ArchivedLink) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.drop(links)
+}
+
+// Purge удаляет файлы, записанные раньше before.
+func (a *Files) Purge(ctx context.Context, before time.Time) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	names, err := a.list()
+	if err != nil {
+		return 0, err
+	}
+	var purged int
+	for _, name := range names {
+		if !fileTime(name).Before(before) {
+			break
+		}
+		if err = os.Remove(filepath.Join(a.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return purged, fmt.Errorf("failed remove archive file: %w", err)
+		}
+		for key := range a.files[name] {
+			delete(a.index, key)
+		}
+		purged += len(a.files[name])
+		delete(a.files, name)
+	}
+	return purged, nil
+}
+
+// Close ничего не делает, файлы архива закрываются после каждой операции.
+func (a *Files) Close() {}
+
+// add добавляет ссылку файла в индекс.
+func (a *Files) add(name string, key linkKey) {
+	if prev, ok := a.index[key]; ok {
+		delete(a.files[prev], key)
+	}
+	a.index[key] = name
+	if _, ok := a.files[name]; !ok {
+		a.files[name] = make(map[linkKey]struct{})
+	}
+	a.files[name][key] = struct{}{}
+}
+
+// drop удаляет ссылки из файлов архива. Файл без ссылок удаляется.
+func (a *Files) drop(links []models.ArchivedLink) error {
+	dropped := make(map[linkKey]struct{}, len(links))
+	affected := make(map[string]struct{})
+	for _, link := range links {
+		key := linkKey{link.Domain, link.ShortURL}
+		dropped[key] = struct{}{}
+		if name, ok := a.index[key]; ok {
+			affected[name] = struct{}{}
+		}
+	}
+	for name := range affected {
+		stored, err := a.read(name)
+		if err != nil {
+			return err
+		}
+		kept := stored[:0]
+		for _, link := range stored {
+			key := linkKey{link.Domain, link.ShortURL}
+			if _, ok := dropped[key]; ok || a.index[key] != name {
+				continue
+			}
+			kept = append(kept, link)
+		}
+		if len(kept) == 0 {
+			if err = os.Remove(filepath.Join(a.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed remove archive file: %w", err)
+			}
+		} else if err = a.write(name, kept); err != nil {
+			return err
+		}
+		for key := range a.files[name] {
+			if _, ok := dropped[key]; ok {
+				delete(a.files[name], key)
+				delete(a.index, key)
+			}
+		}
+		if len(a.files[name]) == 0 {
+			delete(a.files, name)
+		}
+	}
+	return nil
+}
+
+// list возвращает файлы архива в порядке записи.
+func (a *Files) list() ([]string, error) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed read archive dir: %w", err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), filePrefix) && strings.HasSuffix(e.Name(), fileSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// read читает ссылки файла архива.
+func (a *Files) read(name string) ([]models.ArchivedLink, error) {
+	f, err := os.Open(filepath.Join(a.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed open archive file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed read archive file `%s`: %w", name, err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	links := []models.ArchivedLink{}
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var link models.ArchivedLink
+		if err = json.Unmarshal(scanner.Bytes(), &link); err != nil {
+			return nil, fmt.Errorf("failed parse archive file `%s`: %w", name, err)
+		}
+		links = append(links, link)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed read archive file `%s`: %w", name, err)
+	}
+	return links, nil
+}
+
+// write записывает файл архива через временный файл, поэтому сбой не оставляет файл частично записанным.
+func (a *Files) write(name string, links []models.ArchivedLink) error {
+	tmp, err := os.CreateTemp(a.dir, name+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed create archive file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	zw := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(zw)
+	for _, link := range links {
+		if err = enc.Encode(link); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("failed write archive file: %w", err)
+		}
+	}
+	if err = zw.Close(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed write archive file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed sync archive file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed close archive file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(a.dir, name)); err != nil {
+		return fmt.Errorf("failed rename archive file: %w", err)
+	}
+	return nil
+}
+
+// fileTime возвращает время записи файла архива по его имени.
+func fileTime(name string) time.Time {
+	ns, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/database"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// tableArchive архив в таблице short_link_archive.
+type tableArchive struct {
+	pool *pgxpool.Pool
+}
+
+func newTable(ctx context.Context, dsn string, migrate bool) (*tableArchive, error) {
+	if migrate {
+		if err := database.RunMigrations(dsn); err != nil {
+			return nil, fmt.Errorf("failed initialize tables: %w", err)
+		}
+	}
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed open database: %w", err)
+	}
+	return &tableArchive{pool: pool}, nil
+}
+
+// Put сохраняет ссылки в архив.
+func (a *tableArchive) Put(ctx context.Context, links []models.ArchivedLink) error {
+	batch := &pgx.Batch{}
+	for _, link := range links {
+		var accessedAt *time.Time
+		if !link.AccessedAt.IsZero() {
+			accessedAt = &link.AccessedAt
+		}
+		batch.Queue(`insert into short_link_archive
+(domain, short_url, original_url, user_id, redirect_code, created_at, accessed_at, archived_at)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+on conflict (domain, short_url) do update set original_url = excluded.original_url, user_id = excluded.user_id,
+redirect_code = excluded.redirect_code, created_at = excluded.created_at, accessed_at = excluded.accessed_at,
+archived_at = excluded.archived_at`,
+			link.Domain, link.ShortURL, link.OriginalURL, link.UserID, link.RedirectCode,
+			link.CreatedAt, accessedAt, link.ArchivedAt,
+		)
+	}
+	if err := a.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed insert archived links: %w", err)
+	}
+	return nil
+}
+
+// Get возвращает ссылку из архива.
+func (a *tableArchive) Get(ctx context.Context, domain, short string) (models.ArchivedLink, error) {
+	link := models.ArchivedLink{Domain: domain, ShortURL: short}
+	var accessedAt *time.Time
+	err := a.pool.QueryRow(ctx,
+		`select original_url, user_id, redirect_code, created_at, accessed_at, archived_at
+from short_link_archive where domain = $1 and short_url = $2`,
+		domain, short,
+	).Scan(&link.OriginalURL, &link.UserID, &link.RedirectCode, &link.CreatedAt, &accessedAt, &link.ArchivedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ArchivedLink{}, storeerror.ErrNotFoundKey
+		}
+		return models.ArchivedLink{}, fmt.Errorf("failed select archived link: %w", err)
+	}
+	if accessedAt != nil {
+		link.AccessedAt = *accessedAt
+	}
+	return link, nil
+}
+
+// Delete удаляет ссылки из архива.
+func (a *tableArchive) Delete(ctx context.Context, links []models.ArchivedLink) error {
+	domains := make([]string, 0, len(links))
+	shorts := make([]string, 0, len(links))
+	for _, link := range links {
+		domains = append(domains, link.Domain)
+		shorts = append(shorts, link.ShortURL)
+	}
+	_, err := a.pool.Exec(ctx, `delete from short_link_archive
+where (domain, short_url) in (select * from unnest($1::varchar[], $2::varchar[]))`,
+		domains, shorts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed delete archived links: %w", err)
+	}
+	return nil
+}
+
+// Purge удаляет ссылки, перенесенные в архив раньше before.
+func (a *tableArchive) Purge(ctx context.Context, before time.Time) (int, error) {
+	tag, err := a.pool.Exec(ctx, "delete from short_link_archive where archived_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed purge archive: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Close закрывает соединение с базой.
+func (a *tableArchive) Close() {
+	a.pool.Close()
+}
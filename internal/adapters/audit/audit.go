@@ -0,0 +1,152 @@
+// Модуль audit ведет журнал действий пользователей.
+// Записи журнала связаны в цепочку хешей, что позволяет обнаружить их изменение или удаление.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+// Log - интерфейс журнала аудита.
+type Log interface {
+	// Добавляет запись в журнал.
+	Record(ctx context.Context, event models.AuditEvent) error
+	// Возвращает записи журнала по фильтру.
+	Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+	// Проверяет целостность цепочки записей.
+	Verify(ctx context.Context) (models.AuditVerification, error)
+	Close()
+}
+
+const defaultLimit = 100 // количество записей по умолчанию при поиске.
+
+type ctxKey struct{}
+
+type client struct {
+	transport string
+	ip        string
+}
+
+// WithClient сохраняет в контексте транспорт и адрес клиента.
+func WithClient(ctx context.Context, transport, ip string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, client{transport: transport, ip: ip})
+}
+
+func clientFromContext(ctx context.Context) client {
+	c, ok := ctx.Value(ctxKey{}).(client)
+	if !ok {
+		return client{}
+	}
+	return c
+}
+
+// New создает журнал аудита.
+// Если хранилище журнала не задано, возвращается журнал, не сохраняющий записи.
+func New(ctx context.Context, cfg *Config, log *zap.Logger) (Log, error) {
+	if cfg.DSN != "" {
+		l, err := newDatabase(ctx, cfg.DSN, !cfg.SkipMigrations)
+		if err != nil {
+			return nil, fmt.Errorf("failed initialize database audit log: %w", err)
+		}
+		log.Info("database audit log initialized")
+		return l, nil
+	}
+
+	if cfg.FilePath != "" {
+		l, err := newFile(cfg.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed initialize file audit log: %w", err)
+		}
+		log.Info("file audit log initialized")
+		return l, nil
+	}
+
+	log.Info("audit log disabled")
+	return &nop{}, nil
+}
+
+// prepare заполняет служебные поля записи и вычисляет ее хеш.
+func prepare(ctx context.Context, event *models.AuditEvent, prev *models.AuditEvent) {
+	c := clientFromContext(ctx)
+	if event.Transport == "" {
+		event.Transport = c.transport
+	}
+	if event.IP == "" {
+		event.IP = c.ip
+	}
+	event.Time = time.Now().UTC().Truncate(time.Microsecond)
+	event.ID = 1
+	event.PrevHash = ""
+	if prev != nil {
+		event.ID = prev.ID + 1
+		event.PrevHash = prev.Hash
+	}
+	event.Hash = hash(event)
+}
+
+// hash вычисляет хеш записи с учетом хеша предыдущей записи.
+func hash(e *models.AuditEvent) string {
+	h := sha256.Sum256([]byte(strings.Join([]string{
+		strconv.FormatInt(e.ID, 10),
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.UserID,
+		e.Action,
+		e.Domain,
+		e.ShortURL,
+		e.Transport,
+		e.IP,
+		e.PrevHash,
+	}, "\x1f")))
+	return hex.EncodeToString(h[:])
+}
+
+// verifier проверяет цепочку записей по мере их чтения.
+type verifier struct {
+	prev   *models.AuditEvent
+	result models.AuditVerification
+}
+
+func newVerifier() *verifier {
+	return &verifier{result: models.AuditVerification{Valid: true}}
+}
+
+// next проверяет очередную запись, возвращает false при нарушении цепочки.
+func (v *verifier) next(e models.AuditEvent) bool {
+	v.result.Checked++
+	wantID, wantPrev := int64(1), ""
+	if v.prev != nil {
+		wantID, wantPrev = v.prev.ID+1, v.prev.Hash
+	}
+	if e.ID != wantID || e.PrevHash != wantPrev || e.Hash != hash(&e) {
+		v.result.Valid = false
+		v.result.BrokenAt = e.ID
+		return false
+	}
+	v.prev = &e
+	return true
+}
+
+type nop struct{}
+
+func (n *nop) Record(ctx context.Context, event models.AuditEvent) error {
+	return nil
+}
+
+func (n *nop) Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	return []models.AuditEvent{}, nil
+}
+
+func (n *nop) Verify(ctx context.Context) (models.AuditVerification, error) {
+	return models.AuditVerification{Valid: true}, nil
+}
+
+func (n *nop) Close() {}
@@ -0,0 +1,90 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/audit"
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+func createFileLog(t *testing.T, path string) audit.Log {
+	t.Helper()
+
+	l, err := audit.New(context.Background(), &audit.Config{FilePath: path}, zap.NewNop())
+	require.NoError(t, err)
+	return l
+}
+
+func TestFile_RecordAndFind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	ctx := audit.WithClient(context.Background(), models.TransportREST, "127.0.0.1")
+
+	l := createFileLog(t, path)
+	require.NoError(t, l.Record(ctx, models.AuditEvent{UserID: "1", Action: models.AuditActionCreate, ShortURL: "a"}))
+	require.NoError(t, l.Record(ctx, models.AuditEvent{UserID: "2", Action: models.AuditActionCreate, ShortURL: "b"}))
+	l.Close()
+
+	// цепочка продолжается после перезапуска.
+	l = createFileLog(t, path)
+	defer l.Close()
+	require.NoError(t, l.Record(ctx, models.AuditEvent{UserID: "1", Action: models.AuditActionDelete, ShortURL: "a"}))
+
+	tests := []struct {
+		name   string
+		filter models.AuditFilter
+		want   []int64
+	}{
+		{name: "all", filter: models.AuditFilter{}, want: []int64{1, 2, 3}},
+		{name: "by user", filter: models.AuditFilter{UserID: "1"}, want: []int64{1, 3}},
+		{name: "by action", filter: models.AuditFilter{Action: models.AuditActionDelete}, want: []int64{3}},
+		{name: "by transport", filter: models.AuditFilter{Transport: models.TransportGRPC}, want: []int64{}},
+		{name: "after id", filter: models.AuditFilter{AfterID: 1, Limit: 1}, want: []int64{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := l.Find(context.Background(), tt.filter)
+			require.NoError(t, err)
+			ids := []int64{}
+			for _, e := range events {
+				require.Equal(t, "127.0.0.1", e.IP)
+				ids = append(ids, e.ID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+
+	result, err := l.Verify(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, models.AuditVerification{Valid: true, Checked: 3}, result)
+}
+
+func TestFile_VerifyTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	ctx := context.Background()
+
+	l := createFileLog(t, path)
+	require.NoError(t, l.Record(ctx, models.AuditEvent{UserID: "1", Action: models.AuditActionCreate, ShortURL: "a"}))
+	require.NoError(t, l.Record(ctx, models.AuditEvent{UserID: "1", Action: models.AuditActionCreate, ShortURL: "b"}))
+	require.NoError(t, l.Record(ctx, models.AuditEvent{UserID: "1", Action: models.AuditActionDelete, ShortURL: "b"}))
+	l.Close()
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(string(b), "\n")
+	lines[1] = strings.Replace(lines[1], `"user_id":"1"`, `"user_id":"2"`, 1)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+
+	l = createFileLog(t, path)
+	defer l.Close()
+	result, err := l.Verify(ctx)
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.Equal(t, int64(2), result.BrokenAt)
+}
@@ -0,0 +1,10 @@
+package audit
+
+// Config конфигурация журнала аудита.
+// Журнал ведется в базе данных, если задан DSN, иначе в файле.
+type Config struct {
+	DSN      string `env:"AUDIT_DATABASE_DSN"`
+	FilePath string `env:"AUDIT_FILE_PATH"`
+	// SkipMigrations отключает применение миграций базы данных при запуске.
+	SkipMigrations bool `env:"DATABASE_SKIP_MIGRATIONS"`
+}
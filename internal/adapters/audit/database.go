@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/database"
+)
+
+// auditLockID ключ advisory блокировки, сериализующей запись в журнал между экземплярами сервиса.
+const auditLockID = 7_302_028
+
+type databaseLog struct {
+	pool *pgxpool.Pool
+}
+
+func newDatabase(ctx context.Context, dsn string, migrate bool) (*databaseLog, error) {
+	if migrate {
+		if err := database.RunMigrations(dsn); err != nil {
+			return nil, fmt.Errorf("failed initialize tables: %w", err)
+		}
+	}
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed open database: %w", err)
+	}
+	return &databaseLog{pool: pool}, nil
+}
+
+// Record добавляет запись в журнал.
+func (l *databaseLog) Record(ctx context.Context, event models.AuditEvent) error {
+	tx, err := l.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err = tx.Exec(ctx, "select pg_advisory_xact_lock($1)", auditLockID); err != nil {
+		return fmt.Errorf("failed lock audit log: %w", err)
+	}
+	var prev *models.AuditEvent
+	last := models.AuditEvent{}
+	err = tx.QueryRow(ctx, "select id, hash from audit_log order by id desc limit 1").Scan(&last.ID, &last.Hash)
+	switch {
+	case err == nil:
+		prev = &last
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("failed select last audit event: %w", err)
+	}
+
+	prepare(ctx, &event, prev)
+	_, err = tx.Exec(ctx,
+		`insert into audit_log (id, created_at, user_id, action, domain, short_url, transport, ip, prev_hash, hash)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		event.ID, event.Time, event.UserID, event.Action, event.Domain, event.ShortURL,
+		event.Transport, event.IP, event.PrevHash, event.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed insert audit event: %w", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Find возвращает записи журнала по фильтру.
+func (l *databaseLog) Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	where := []string{"true"}
+	args := []any{}
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if filter.AfterID != 0 {
+		add("id > ?", filter.AfterID)
+	}
+	if filter.UserID != "" {
+		add("user_id = ?", filter.UserID)
+	}
+	if filter.Action != "" {
+		add("action = ?", filter.Action)
+	}
+	if filter.ShortURL != "" {
+		add("short_url = ?", filter.ShortURL)
+	}
+	if filter.Transport != "" {
+		add("transport = ?", filter.Transport)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < ?", filter.To)
+	}
+	args = append(args, filter.Limit)
+	query := `select id, created_at, user_id, action, domain, short_url, transport, ip, prev_hash, hash
+from audit_log where ` + strings.Join(where, " and ") + " order by id limit $" + strconv.Itoa(len(args))
+
+	rows, err := l.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed select audit events: %w", err)
+	}
+	defer rows.Close()
+	result := []models.AuditEvent{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed read audit events: %w", err)
+	}
+	return result, nil
+}
+
+// Verify проверяет целостность цепочки записей.
+func (l *databaseLog) Verify(ctx context.Context) (models.AuditVerification, error) {
+	rows, err := l.pool.Query(ctx,
+		`select id, created_at, user_id, action, domain, short_url, transport, ip, prev_hash, hash
+from audit_log order by id`)
+	if err != nil {
+		return models.AuditVerification{}, fmt.Errorf("failed select audit events: %w", err)
+	}
+	defer rows.Close()
+	v := newVerifier()
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return models.AuditVerification{}, err
+		}
+		if !v.next(e) {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.AuditVerification{}, fmt.Errorf("failed read audit events: %w", err)
+	}
+	return v.result, nil
+}
+
+// Close закрывает соединение с базой данных.
+func (l *databaseLog) Close() {
+	l.pool.Close()
+}
+
+func scanEvent(rows pgx.Rows) (models.AuditEvent, error) {
+	e := models.AuditEvent{}
+	err := rows.Scan(&e.ID, &e.Time, &e.UserID, &e.Action, &e.Domain, &e.ShortURL,
+		&e.Transport, &e.IP, &e.PrevHash, &e.Hash)
+	if err != nil {
+		return e, fmt.Errorf("failed scan audit event: %w", err)
+	}
+	e.Time = e.Time.UTC()
+	return e, nil
+}
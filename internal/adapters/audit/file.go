@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+type file struct {
+	mu   *sync.Mutex
+	f    *os.File
+	last *models.AuditEvent
+	path string
+}
+
+func newFile(path string) (*file, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed create path of audit log: %w", err)
+	}
+	l := &file{
+		mu:   &sync.Mutex{},
+		path: path,
+	}
+	err := l.scan(func(e models.AuditEvent) bool {
+		l.last = &e
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed read audit log: %w", err)
+	}
+	l.f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed open audit log: %w", err)
+	}
+	return l, nil
+}
+
+// Record добавляет запись в журнал.
+func (l *file) Record(ctx context.Context, event models.AuditEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prepare(ctx, &event, l.last)
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed marshal audit event: %w", err)
+	}
+	if _, err = l.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed write audit event: %w", err)
+	}
+	if err = l.f.Sync(); err != nil {
+		return fmt.Errorf("failed sync audit log: %w", err)
+	}
+	l.last = &event
+	return nil
+}
+
+// Find возвращает записи журнала по фильтру.
+func (l *file) Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := []models.AuditEvent{}
+	err := l.scan(func(e models.AuditEvent) bool {
+		if filter.Match(&e) {
+			result = append(result, e)
+		}
+		return len(result) < filter.Limit
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed find audit events: %w", err)
+	}
+	return result, nil
+}
+
+// Verify проверяет целостность цепочки записей.
+func (l *file) Verify(ctx context.Context) (models.AuditVerification, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v := newVerifier()
+	if err := l.scan(v.next); err != nil {
+		return models.AuditVerification{}, fmt.Errorf("failed verify audit log: %w", err)
+	}
+	return v.result, nil
+}
+
+// Close закрывает файл журнала.
+func (l *file) Close() {
+	_ = l.f.Close()
+}
+
+// scan последовательно читает записи журнала, пока fn возвращает true.
+func (l *file) scan(fn func(e models.AuditEvent) bool) error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed open audit log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e models.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("failed unmarshal audit event: %w", err)
+		}
+		if !fn(e) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed scan audit log: %w", err)
+	}
+	return nil
+}
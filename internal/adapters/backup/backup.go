@@ -0,0 +1,206 @@
+// Модуль backup создает резервные копии ссылок хранилища и восстанавливает ссылки из них.
+//
+// Резервная копия - сжатый gzip файл JSONL: первая строка - заголовок с версией формата и моментом снимка,
+// остальные - ссылки, включая удаленные. Ссылки читаются из согласованного снимка хранилища,
+// поэтому копия соответствует состоянию хранилища на один момент времени независимо от хранилища.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+)
+
+const (
+	formatName    = "short-link-backup"
+	formatVersion = 1
+)
+
+var (
+	// ErrNotSupported - хранилище не поддерживает согласованные снимки.
+	ErrNotSupported = fmt.Errorf("storage does not support snapshots: %w", errors.ErrUnsupported)
+	// ErrInvalidFormat - файл не является резервной копией ссылок или записан неподдерживаемой версией.
+	ErrInvalidFormat = errors.New("invalid backup format")
+)
+
+// header - первая строка резервной копии.
+type header struct {
+	CreatedAt time.Time `json:"created_at"`
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+}
+
+// record - ссылка в резервной копии.
+type record struct {
+	Domain       string `json:"domain,omitempty"`
+	ShortURL     string `json:"short_url"`
+	OriginalURL  string `json:"original_url"`
+	UserID       string `json:"user_id"`
+	RedirectCode int    `json:"redirect_code,omitempty"`
+	IsDeleted    bool   `json:"is_deleted,omitempty"`
+	CreatedAt    int64  `json:"created_at,omitempty"` // время создания, Unix секунды, 0 - неизвестно.
+}
+
+func newRecord(link models.ShortLink) record {
+	r := record{
+		Domain:       link.Domain,
+		ShortURL:     link.ShortURL,
+		OriginalURL:  link.OriginalURL,
+		UserID:       link.UserID,
+		RedirectCode: link.RedirectCode,
+		IsDeleted:    link.IsDeleted,
+	}
+	if !link.CreatedAt.IsZero() {
+		r.CreatedAt = link.CreatedAt.Unix()
+	}
+	return r
+}
+
+func (r *record) link() models.ShortLink {
+	return models.ShortLink{
+		Domain:       r.Domain,
+		ShortURL:     r.ShortURL,
+		OriginalURL:  r.OriginalURL,
+		UserID:       r.UserID,
+		RedirectCode: r.RedirectCode,
+		IsDeleted:    r.IsDeleted,
+		CreatedAt:    models.UnixTime(r.CreatedAt),
+	}
+}
+
+// Write записывает в w резервную копию снимка ссылок store.
+func Write(ctx context.Context, store storage.Store, w io.Writer) (models.BackupInfo, error) {
+	sn, ok := store.(storage.Snapshotter)
+	if !ok {
+		return models.BackupInfo{}, ErrNotSupported
+	}
+	info := models.BackupInfo{CreatedAt: time.Now().UTC()}
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(header{Format: formatName, Version: formatVersion, CreatedAt: info.CreatedAt}); err != nil {
+		return models.BackupInfo{}, fmt.Errorf("failed write backup header: %w", err)
+	}
+	err := sn.Snapshot(ctx, func(link models.ShortLink) error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("backup interrupted: %w", err)
+		}
+		if err := enc.Encode(newRecord(link)); err != nil {
+			return fmt.Errorf("failed write link `%s`: %w", link.ShortURL, err)
+		}
+		info.Links++
+		if link.IsDeleted {
+			info.Deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return models.BackupInfo{}, ErrNotSupported
+		}
+		return models.BackupInfo{}, fmt.Errorf("failed snapshot storage: %w", err)
+	}
+	if err = zw.Close(); err != nil {
+		return models.BackupInfo{}, fmt.Errorf("failed write backup: %w", err)
+	}
+	return info, nil
+}
+
+// WriteFile записывает резервную копию в файл path через временный файл,
+// поэтому сбой не оставляет копию частично записанной.
+func WriteFile(ctx context.Context, store storage.Store, path string) (models.BackupInfo, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return models.BackupInfo{}, fmt.Errorf("failed create backup dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return models.BackupInfo{}, fmt.Errorf("failed create backup file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	info, err := Write(ctx, store, tmp)
+	if err != nil {
+		_ = tmp.Close()
+		return models.BackupInfo{}, err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return models.BackupInfo{}, fmt.Errorf("failed sync backup file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return models.BackupInfo{}, fmt.Errorf("failed close backup file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return models.BackupInfo{}, fmt.Errorf("failed rename backup file: %w", err)
+	}
+	info.File = path
+	return info, nil
+}
+
+// Read читает резервную копию из r и вызывает fn для каждой ссылки в порядке записи.
+func Read(r io.Reader, fn func(link models.ShortLink) error) (models.BackupInfo, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return models.BackupInfo{}, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		if err = scanner.Err(); err != nil {
+			return models.BackupInfo{}, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+		}
+		return models.BackupInfo{}, fmt.Errorf("%w: empty file", ErrInvalidFormat)
+	}
+	var h header
+	if err = json.Unmarshal(scanner.Bytes(), &h); err != nil || h.Format != formatName {
+		return models.BackupInfo{}, fmt.Errorf("%w: unknown header", ErrInvalidFormat)
+	}
+	if h.Version != formatVersion {
+		return models.BackupInfo{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, h.Version)
+	}
+
+	info := models.BackupInfo{CreatedAt: h.CreatedAt}
+	for scanner.Scan() {
+		var rec record
+		if err = json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.ShortURL == "" {
+			return info, fmt.Errorf("%w: broken link at line %d", ErrInvalidFormat, info.Links+2)
+		}
+		info.Links++
+		if rec.IsDeleted {
+			info.Deleted++
+		}
+		if err = fn(rec.link()); err != nil {
+			return info, err
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return info, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	return info, nil
+}
+
+// ReadFile читает резервную копию из файла path.
+func ReadFile(path string, fn func(link models.ShortLink) error) (models.BackupInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return models.BackupInfo{}, fmt.Errorf("failed open backup file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := Read(f, fn)
+	if err != nil {
+		return info, err
+	}
+	info.File = path
+	return info, nil
+}
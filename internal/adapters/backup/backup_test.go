@@ -0,0 +1,230 @@
+package backup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/backup"
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/sqlite"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+func createStore(t *testing.T, links ...models.ShortLink) *memory.Store {
+	t.Helper()
+	ctx := context.Background()
+	s, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	for _, link := range links {
+		_, err = s.Set(ctx, link.UserID, link)
+		require.NoError(t, err)
+		if link.IsDeleted {
+			_, err = s.DeleteShortURLs(ctx, []models.ShortLink{link})
+			require.NoError(t, err)
+		}
+	}
+	return s
+}
+
+var sample = []models.ShortLink{
+	{ShortURL: "a1", OriginalURL: "https://a.ru/1", UserID: "1", IsDeleted: true, CreatedAt: models.UnixTime(1700000000)},
+	{ShortURL: "a2", OriginalURL: "https://a.ru/2", UserID: "1", RedirectCode: 302, CreatedAt: models.UnixTime(1710000000)},
+	{ShortURL: "b1", OriginalURL: "https://b.ru/1", UserID: "2", Domain: "go.brand.com", CreatedAt: models.UnixTime(1720000000)},
+}
+
+func readAll(t *testing.T, path string) []models.ShortLink {
+	t.Helper()
+	links := []models.ShortLink{}
+	_, err := backup.ReadFile(path, func(link models.ShortLink) error {
+		links = append(links, link)
+		return nil
+	})
+	require.NoError(t, err)
+	return links
+}
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backup.jsonl.gz")
+	info, err := backup.WriteFile(context.Background(), createStore(t, sample...), path)
+	require.NoError(t, err)
+	require.Equal(t, 3, info.Links)
+	require.Equal(t, 1, info.Deleted)
+	require.Equal(t, path, info.File)
+	require.False(t, info.CreatedAt.IsZero())
+
+	require.Equal(t, sample, readAll(t, path))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestWrite_NotSupported(t *testing.T) {
+	store := struct{ storage.Store }{createStore(t)}
+	_, err := backup.Write(context.Background(), store, &bytes.Buffer{})
+	require.ErrorIs(t, err, backup.ErrNotSupported)
+	require.ErrorIs(t, err, errors.ErrUnsupported)
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestRead_InvalidFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not gzip", data: []byte("links")},
+		{name: "empty", data: gzipped(t, "")},
+		{name: "unknown header", data: gzipped(t, `{"format":"other","version":1}`+"\n")},
+		{name: "unsupported version", data: gzipped(t, `{"format":"short-link-backup","version":2}`+"\n")},
+		{name: "broken link", data: gzipped(t, `{"format":"short-link-backup","version":1}`+"\n{\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := backup.Read(bytes.NewReader(tt.data), func(models.ShortLink) error { return nil })
+			require.ErrorIs(t, err, backup.ErrInvalidFormat)
+		})
+	}
+}
+
+func TestRestore_Merge(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.jsonl.gz")
+	_, err := backup.WriteFile(ctx, createStore(t, sample...), path)
+	require.NoError(t, err)
+
+	target := createStore(t,
+		models.ShortLink{ShortURL: "a2", OriginalURL: "https://other.ru/", UserID: "3"},
+		models.ShortLink{ShortURL: "x1", OriginalURL: "https://b.ru/1", UserID: "2", Domain: "go.brand.com"},
+		models.ShortLink{ShortURL: "c1", OriginalURL: "https://c.ru/1", UserID: "3"},
+	)
+	res, err := backup.Restore(ctx, target, path, backup.ModeMerge)
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Restored)
+	require.Equal(t, 3, res.Info.Links)
+	require.Equal(t, []backup.Conflict{
+		{Link: sample[1], Reason: backup.ReasonShortTaken},
+		{Link: sample[2], Reason: backup.ReasonOriginalExists},
+	}, res.Conflicts)
+
+	_, err = target.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	link, err := target.Get(ctx, "", "c1")
+	require.NoError(t, err)
+	require.Equal(t, "https://c.ru/1", link.OriginalURL)
+
+	// повторное восстановление пропускает восстановленные ссылки.
+	res, err = backup.Restore(ctx, target, path, backup.ModeMerge)
+	require.NoError(t, err)
+	require.Equal(t, 0, res.Restored)
+	require.Equal(t, 1, res.Skipped)
+	require.Len(t, res.Conflicts, 2)
+}
+
+func TestRestore_Replace(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.jsonl.gz")
+	_, err := backup.WriteFile(ctx, createStore(t, sample...), path)
+	require.NoError(t, err)
+
+	target := createStore(t,
+		models.ShortLink{ShortURL: "a2", OriginalURL: "https://other.ru/", UserID: "3"},
+		models.ShortLink{ShortURL: "c1", OriginalURL: "https://c.ru/1", UserID: "3", IsDeleted: true},
+	)
+	res, err := backup.Restore(ctx, target, path, backup.ModeReplace)
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Removed)
+	require.Equal(t, 3, res.Restored)
+	require.Empty(t, res.Conflicts)
+
+	dir := t.TempDir()
+	_, err = backup.WriteFile(ctx, target, filepath.Join(dir, "restored.jsonl.gz"))
+	require.NoError(t, err)
+	require.Equal(t, sample, readAll(t, filepath.Join(dir, "restored.jsonl.gz")))
+}
+
+func TestRestore_DeletedAfterLive(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.jsonl.gz")
+	data := `{"format":"short-link-backup","version":1,"created_at":"2026-01-02T03:04:05Z"}
+{"short_url":"a2","original_url":"https://a.ru/","user_id":"1"}
+{"short_url":"a1","original_url":"https://a.ru/","user_id":"1","is_deleted":true}
+`
+	require.NoError(t, os.WriteFile(path, gzipped(t, data), 0o600))
+
+	target := createStore(t)
+	res, err := backup.Restore(ctx, target, path, backup.ModeMerge)
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Restored)
+	require.Empty(t, res.Conflicts)
+	_, err = target.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	_, err = target.Get(ctx, "", "a2")
+	require.NoError(t, err)
+}
+
+func TestRestore_CreatedAt(t *testing.T) {
+	ctx := context.Background()
+	source, err := sqlite.New(ctx, &sqlite.Config{DSN: sqlite.Scheme + filepath.Join(t.TempDir(), "source.db")})
+	require.NoError(t, err)
+	defer source.Close()
+	for _, link := range sample {
+		_, err = source.Set(ctx, link.UserID, link)
+		require.NoError(t, err)
+	}
+	path := filepath.Join(t.TempDir(), "backup.jsonl.gz")
+	_, err = backup.WriteFile(ctx, source, path)
+	require.NoError(t, err)
+
+	target, err := sqlite.New(ctx, &sqlite.Config{DSN: sqlite.Scheme + filepath.Join(t.TempDir(), "target.db")})
+	require.NoError(t, err)
+	defer target.Close()
+	_, err = backup.Restore(ctx, target, path, backup.ModeMerge)
+	require.NoError(t, err)
+	for _, want := range sample {
+		link, err := target.Get(ctx, want.Domain, want.ShortURL)
+		require.NoError(t, err)
+		require.Equal(t, want.CreatedAt, link.CreatedAt, want.ShortURL)
+	}
+}
+
+func TestRestore_Invalid(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.jsonl.gz")
+	require.NoError(t, os.WriteFile(path, gzipped(t, `{"format":"short-link-backup","version":1}`+"\n{\n"), 0o600))
+	target := createStore(t, sample[1])
+
+	_, err := backup.Restore(ctx, target, path, "overwrite")
+	require.ErrorIs(t, err, backup.ErrUnknownMode)
+	// поврежденная копия не изменяет хранилище.
+	_, err = backup.Restore(ctx, target, path, backup.ModeReplace)
+	require.ErrorIs(t, err, backup.ErrInvalidFormat)
+	_, err = target.Get(ctx, "", "a2")
+	require.NoError(t, err)
+}
+
+func TestService_Backup(t *testing.T) {
+	dir := t.TempDir()
+	svc := backup.NewService(createStore(t, sample...), dir)
+	info, err := svc.Backup(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, dir, filepath.Dir(info.File))
+	require.Regexp(t, `^backup-\d{8}T\d{6}\.\d{9}Z\.jsonl\.gz$`, filepath.Base(info.File))
+	require.Equal(t, sample, readAll(t, info.File))
+}
@@ -0,0 +1,12 @@
+package backup
+
+// Config конфигурация резервного копирования по запросу.
+type Config struct {
+	// Dir каталог резервных копий, создаваемых через внутренний API. Не задан - создание копий через API отключено.
+	Dir string `env:"BACKUP_DIR"`
+}
+
+// Enabled - создание резервных копий через API включено.
+func (c *Config) Enabled() bool {
+	return c.Dir != ""
+}
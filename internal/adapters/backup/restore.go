@@ -0,0 +1,168 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// Режимы восстановления.
+const (
+	ModeMerge   = "merge"   // добавить ссылки копии к ссылкам хранилища.
+	ModeReplace = "replace" // удалить ссылки хранилища и восстановить ссылки копии.
+)
+
+var (
+	// ErrUnknownMode - неизвестный режим восстановления.
+	ErrUnknownMode = errors.New("unknown restore mode")
+
+	clearBatchSize = 1000 // количество ссылок, удаляемых за один раз при замене.
+)
+
+// Причины конфликтов восстановления.
+const (
+	ReasonShortTaken     = "short code is taken by another link"
+	ReasonOriginalExists = "user already has a link with this original url"
+)
+
+// Conflict - ссылка копии, которую нельзя восстановить без потери данных хранилища.
+type Conflict struct {
+	Link   models.ShortLink
+	Reason string
+}
+
+// RestoreResult итог восстановления.
+type RestoreResult struct {
+	Conflicts []Conflict
+	Info      models.BackupInfo
+	Restored  int // восстановлено ссылок копии.
+	Skipped   int // ссылок копии, уже сохраненных в хранилище с теми же данными.
+	Removed   int // ссылок хранилища, удаленных при замене.
+}
+
+// Restore восстанавливает ссылки из файла резервной копии path в store.
+//
+// В режиме ModeMerge ссылки копии добавляются к ссылкам хранилища: совпадающие ссылки пропускаются,
+// ссылки, короткий код или оригинальная ссылка которых заняты другими ссылками хранилища, возвращаются
+// как конфликты. В режиме ModeReplace ссылки хранилища сначала удаляются, хранилище должно поддерживать
+// обход ссылок. Копия проверяется целиком до изменения хранилища.
+func Restore(ctx context.Context, store storage.Store, path, mode string) (RestoreResult, error) {
+	if mode != ModeMerge && mode != ModeReplace {
+		return RestoreResult{}, fmt.Errorf("%w `%s`", ErrUnknownMode, mode)
+	}
+	info, err := ReadFile(path, func(models.ShortLink) error { return ctx.Err() })
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("failed check backup: %w", err)
+	}
+	res := RestoreResult{Info: info, Conflicts: []Conflict{}}
+
+	if mode == ModeReplace {
+		if res.Removed, err = removeAll(ctx, store); err != nil {
+			return res, err
+		}
+	}
+
+	// удаленные ссылки восстанавливаются первыми: пока удаленная ссылка сохраняется, она занимает
+	// оригинальную ссылку пользователя, которую позже в копии может занимать неудаленная ссылка.
+	for _, deleted := range []bool{true, false} {
+		_, err = ReadFile(path, func(link models.ShortLink) error {
+			if link.IsDeleted != deleted {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("restore interrupted: %w", err)
+			}
+			restored, reason, err := restoreLink(ctx, store, link)
+			switch {
+			case err != nil:
+				return err
+			case reason != "":
+				res.Conflicts = append(res.Conflicts, Conflict{Link: link, Reason: reason})
+			case restored:
+				res.Restored++
+			default:
+				res.Skipped++
+			}
+			return nil
+		})
+		if err != nil {
+			return res, fmt.Errorf("failed restore links: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// restoreLink сохраняет ссылку копии, возвращает false, если ссылка уже сохранена,
+// или причину конфликта, если ссылку сохранить нельзя.
+func restoreLink(ctx context.Context, store storage.Store, link models.ShortLink) (bool, string, error) {
+	existing, err := store.Get(ctx, link.Domain, link.ShortURL)
+	if err == nil || errors.Is(err, storeerror.ErrShortURLDeleted) {
+		if existing.OriginalURL != link.OriginalURL || existing.UserID != link.UserID ||
+			existing.RedirectCode != link.RedirectCode {
+			return false, ReasonShortTaken, nil
+		}
+		if existing.IsDeleted || !link.IsDeleted {
+			return false, "", nil
+		}
+		return true, "", deleteLink(ctx, store, link)
+	}
+	if !errors.Is(err, storeerror.ErrNotFoundKey) {
+		return false, "", fmt.Errorf("failed check link `%s`: %w", link.ShortURL, err)
+	}
+
+	if _, err = store.Set(ctx, link.UserID, link); err != nil {
+		switch {
+		case errors.Is(err, storeerror.ErrNotUnique):
+			return false, ReasonOriginalExists, nil
+		case errors.Is(err, storeerror.ErrDuplicateShortURL):
+			return false, ReasonShortTaken, nil
+		}
+		return false, "", fmt.Errorf("failed set link `%s`: %w", link.ShortURL, err)
+	}
+	if link.IsDeleted {
+		return true, "", deleteLink(ctx, store, link)
+	}
+	return true, "", nil
+}
+
+func deleteLink(ctx context.Context, store storage.Store, link models.ShortLink) error {
+	if _, err := store.DeleteShortURLs(ctx, []models.ShortLink{link}); err != nil {
+		return fmt.Errorf("failed mark link `%s` deleted: %w", link.ShortURL, err)
+	}
+	return nil
+}
+
+// removeAll удаляет все ссылки хранилища и возвращает их количество.
+// Неудаленные ссылки помечаются удаленными, затем удаленные ссылки очищаются.
+func removeAll(ctx context.Context, store storage.Store) (int, error) {
+	walker, ok := store.(storage.Walker)
+	if !ok {
+		return 0, fmt.Errorf("replace mode: %w", errors.ErrUnsupported)
+	}
+	var total int
+	live := []models.ShortLink{}
+	err := walker.Walk(ctx, func(link models.ShortLink) error {
+		total++
+		if !link.IsDeleted {
+			live = append(live, models.ShortLink{Domain: link.Domain, ShortURL: link.ShortURL, UserID: link.UserID})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed walk storage: %w", err)
+	}
+	for start := 0; start < len(live); start += clearBatchSize {
+		batch := live[start:min(start+clearBatchSize, len(live))]
+		if _, err = store.DeleteShortURLs(ctx, batch); err != nil {
+			return 0, fmt.Errorf("failed delete links: %w", err)
+		}
+	}
+	if err = store.HardDeleteURLs(ctx); err != nil {
+		return 0, fmt.Errorf("failed purge links: %w", err)
+	}
+	return total, nil
+}
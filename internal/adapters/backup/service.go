@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+)
+
+// fileTimeLayout - время снимка в имени файла резервной копии.
+const fileTimeLayout = "20060102T150405.000000000Z"
+
+// Service создает резервные копии хранилища в каталоге по запросу.
+type Service struct {
+	store storage.Store
+	dir   string
+	mu    *sync.Mutex
+}
+
+// NewService создает Service, записывающий копии store в каталог dir.
+func NewService(store storage.Store, dir string) *Service {
+	return &Service{store: store, dir: dir, mu: &sync.Mutex{}}
+}
+
+// Backup создает резервную копию в файле backup-<время>.jsonl.gz каталога.
+// Копии создаются по одной, одновременный запрос ждет завершения предыдущей копии.
+func (s *Service) Backup(ctx context.Context) (models.BackupInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := "backup-" + time.Now().UTC().Format(fileTimeLayout) + ".jsonl.gz"
+	return WriteFile(ctx, s.store, filepath.Join(s.dir, name))
+}
@@ -15,10 +15,18 @@ import (
 	"github.com/playmixer/short-link/internal/adapters/api"
 	"github.com/playmixer/short-link/internal/adapters/api/grpch"
 	"github.com/playmixer/short-link/internal/adapters/api/rest"
+	"github.com/playmixer/short-link/internal/adapters/archive"
+	"github.com/playmixer/short-link/internal/adapters/audit"
+	"github.com/playmixer/short-link/internal/adapters/backup"
+	"github.com/playmixer/short-link/internal/adapters/outbox"
 	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/bolt"
+	"github.com/playmixer/short-link/internal/adapters/storage/cache"
+	"github.com/playmixer/short-link/internal/adapters/storage/crypt"
 	"github.com/playmixer/short-link/internal/adapters/storage/database"
 	"github.com/playmixer/short-link/internal/adapters/storage/file"
 	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/redis"
 	"github.com/playmixer/short-link/internal/core/shortner"
 )
 
@@ -27,24 +35,17 @@ type Config struct {
 	API        api.Config
 	Store      storage.Config
 	Shortner   shortner.Config
+	Audit      audit.Config
+	Outbox     outbox.Config
+	Archive    archive.Config
+	Backup     backup.Config
 	LogLevel   string `env:"LOG_LEVEL"`
 	ConfigPath string `env:"CONFIG"`
 }
 
 // Init инициализирует конфигурацию сервиса.
 func Init() (*Config, error) {
-	cfg := Config{
-		API: api.Config{
-			Rest: &rest.Config{},
-			GRPC: &grpch.Config{},
-		},
-		Store: storage.Config{
-			File:     &file.Config{},
-			Database: &database.Config{},
-			Memory:   &memory.Config{},
-		},
-	}
-	cfg.Store.Database.SetLogger(zap.NewNop())
+	cfg := newConfig()
 
 	flag.StringVar(&cfg.API.Rest.Addr, "a", "localhost:8080", "address listen")
 	flag.StringVar(&cfg.API.BaseURL, "b", "http://localhost:8080", "base url")
@@ -57,28 +58,97 @@ func Init() (*Config, error) {
 	flag.StringVar(&cfg.API.TrustedSubnet, "t", "", "trusted subnet")
 	flag.Parse()
 
+	if err := load(cfg); err != nil {
+		return nil, err
+	}
+	// хранилища записывают события изменения ссылок, только если их есть кому отправить.
+	cfg.Store.Database.Outbox = cfg.Outbox.Enabled()
+	cfg.Store.Memory.Outbox = cfg.Outbox.Enabled()
+	cfg.Store.File.Outbox = cfg.Outbox.Enabled()
+
+	return cfg, nil
+}
+
+// LoadStore загружает конфигурацию хранилищ из переменных окружения и файла конфигурации path
+// без разбора флагов командной строки. Используется утилитами обслуживания хранилищ.
+func LoadStore(path string) (*storage.Config, error) {
+	cfg := newConfig()
+	cfg.ConfigPath = path
+	if err := load(cfg); err != nil {
+		return nil, err
+	}
+	return &cfg.Store, nil
+}
+
+func newConfig() *Config {
+	cfg := &Config{
+		API: api.Config{
+			Rest: &rest.Config{},
+			GRPC: &grpch.Config{},
+		},
+		Store: storage.Config{
+			File:       &file.Config{},
+			Database:   &database.Config{},
+			Memory:     &memory.Config{},
+			Bolt:       &bolt.Config{},
+			Redis:      &redis.Config{},
+			Cache:      &cache.Config{},
+			Encryption: &crypt.Config{},
+		},
+	}
+	cfg.Store.Database.SetLogger(zap.NewNop())
+	return cfg
+}
+
+// load дополняет конфигурацию переменными окружения и файлом конфигурации.
+func load(cfg *Config) error {
 	_ = godotenv.Load(".env")
 
-	if err := env.Parse(&cfg); err != nil {
-		return nil, fmt.Errorf("error parse config %w", err)
+	if err := env.Parse(cfg); err != nil {
+		return fmt.Errorf("error parse config %w", err)
 	}
 	if cfg.ConfigPath != "" {
-		err := fromFile(cfg.ConfigPath, &cfg)
+		err := fromFile(cfg.ConfigPath, cfg)
 		if err != nil {
-			return nil, fmt.Errorf("failed load configure from file: %w", err)
+			return fmt.Errorf("failed load configure from file: %w", err)
 		}
 	}
-
-	return &cfg, nil
+	return nil
 }
 
 type configFile struct {
-	ServerAddress   *string `json:"server_address"`
-	BaseURL         *string `json:"base_url"`
-	FileStoragePath *string `json:"file_storage_path"`
-	DatabaseDSN     *string `json:"database_dsn"`
-	EnableHTTPS     *bool   `json:"enable_https"`
-	TrustedSubnet   *string `json:"trusted_subner"`
+	ServerAddress   *string  `json:"server_address"`
+	BaseURL         *string  `json:"base_url"`
+	StorageDSN      *string  `json:"storage_dsn"`
+	FileStoragePath *string  `json:"file_storage_path"`
+	DatabaseDSN     *string  `json:"database_dsn"`
+	SkipMigrations  *bool    `json:"skip_migrations"`
+	ReplicaDSNs     []string `json:"database_replica_dsns"`
+	CacheSize       *int     `json:"cache_size"`
+	BoltStoragePath *string  `json:"bolt_storage_path"`
+	RedisURL        *string  `json:"redis_url"`
+	EnableHTTPS     *bool    `json:"enable_https"`
+	TrustedSubnet   *string  `json:"trusted_subner"`
+	TrustedProxies  []string `json:"trusted_proxies"`
+	ShortDomains    []string `json:"short_domains"`
+	RedirectCode    *int     `json:"redirect_code"`
+	ReservedCodes   []string `json:"reserved_codes"`
+	AuditFilePath   *string  `json:"audit_file_path"`
+	AuditDSN        *string  `json:"audit_database_dsn"`
+	OutboxSink      *string  `json:"outbox_sink"`
+	OutboxFilePath  *string  `json:"outbox_file_path"`
+	OutboxURL       *string  `json:"outbox_http_url"`
+	EncryptionKeys  []string `json:"encryption_keys"`
+	ActiveKey       *string  `json:"encryption_active_key"`
+	IndexKey        *string  `json:"encryption_index_key"`
+	ArchiveMode     *string  `json:"archive_mode"`
+	ArchiveDSN      *string  `json:"archive_database_dsn"`
+	ArchiveDir      *string  `json:"archive_dir"`
+	InactiveDays    *int     `json:"archive_inactive_days"`
+	MaxAgeDays      *int     `json:"archive_max_age_days"`
+	RetentionDays   *int     `json:"archive_retention_days"`
+	Rehydrate       *bool    `json:"archive_rehydrate"`
+	BackupDir       *string  `json:"backup_dir"`
 }
 
 func fromFile(filepath string, cfg *Config) error {
@@ -107,9 +177,29 @@ func fromFile(filepath string, cfg *Config) error {
 	if configuration.BaseURL != nil && cfg.API.BaseURL == "" {
 		cfg.API.BaseURL = *configuration.BaseURL
 	}
+	if configuration.StorageDSN != nil && cfg.Store.DSN == "" {
+		cfg.Store.DSN = *configuration.StorageDSN
+	}
 	if configuration.DatabaseDSN != nil && cfg.Store.Database.DSN == "" {
 		cfg.Store.Database.DSN = *configuration.DatabaseDSN
 	}
+	if configuration.SkipMigrations != nil && !cfg.Store.Database.SkipMigrations {
+		cfg.Store.Database.SkipMigrations = *configuration.SkipMigrations
+		cfg.Audit.SkipMigrations = *configuration.SkipMigrations
+		cfg.Archive.SkipMigrations = *configuration.SkipMigrations
+	}
+	if len(configuration.ReplicaDSNs) > 0 && len(cfg.Store.Database.ReplicaDSNs) == 0 {
+		cfg.Store.Database.ReplicaDSNs = configuration.ReplicaDSNs
+	}
+	if configuration.CacheSize != nil && cfg.Store.Cache.Size == 0 {
+		cfg.Store.Cache.Size = *configuration.CacheSize
+	}
+	if configuration.BoltStoragePath != nil && cfg.Store.Bolt.Path == "" {
+		cfg.Store.Bolt.Path = *configuration.BoltStoragePath
+	}
+	if configuration.RedisURL != nil && cfg.Store.Redis.URL == "" {
+		cfg.Store.Redis.URL = *configuration.RedisURL
+	}
 	if configuration.EnableHTTPS != nil && !cfg.API.Rest.HTTPSEnable {
 		cfg.API.Rest.HTTPSEnable = *configuration.EnableHTTPS
 	}
@@ -122,6 +212,66 @@ func fromFile(filepath string, cfg *Config) error {
 	if configuration.TrustedSubnet != nil && cfg.API.TrustedSubnet == "" {
 		cfg.API.TrustedSubnet = *configuration.TrustedSubnet
 	}
+	if len(configuration.TrustedProxies) > 0 && len(cfg.API.TrustedProxies) == 0 {
+		cfg.API.TrustedProxies = configuration.TrustedProxies
+	}
+	if len(configuration.ShortDomains) > 0 && len(cfg.Shortner.Domains) == 0 {
+		cfg.Shortner.Domains = configuration.ShortDomains
+	}
+	if configuration.RedirectCode != nil && cfg.Shortner.RedirectCode == 0 {
+		cfg.Shortner.RedirectCode = *configuration.RedirectCode
+	}
+	if len(configuration.ReservedCodes) > 0 && len(cfg.Shortner.ReservedCodes) == 0 {
+		cfg.Shortner.ReservedCodes = configuration.ReservedCodes
+	}
+	if configuration.AuditFilePath != nil && cfg.Audit.FilePath == "" {
+		cfg.Audit.FilePath = *configuration.AuditFilePath
+	}
+	if configuration.AuditDSN != nil && cfg.Audit.DSN == "" {
+		cfg.Audit.DSN = *configuration.AuditDSN
+	}
+	if configuration.OutboxSink != nil && cfg.Outbox.Sink == "" {
+		cfg.Outbox.Sink = *configuration.OutboxSink
+	}
+	if configuration.OutboxFilePath != nil && cfg.Outbox.FilePath == "" {
+		cfg.Outbox.FilePath = *configuration.OutboxFilePath
+	}
+	if configuration.OutboxURL != nil && cfg.Outbox.URL == "" {
+		cfg.Outbox.URL = *configuration.OutboxURL
+	}
+	if len(configuration.EncryptionKeys) > 0 && len(cfg.Store.Encryption.Keys) == 0 {
+		cfg.Store.Encryption.Keys = configuration.EncryptionKeys
+	}
+	if configuration.ActiveKey != nil && cfg.Store.Encryption.ActiveKey == "" {
+		cfg.Store.Encryption.ActiveKey = *configuration.ActiveKey
+	}
+	if configuration.IndexKey != nil && cfg.Store.Encryption.IndexKey == "" {
+		cfg.Store.Encryption.IndexKey = *configuration.IndexKey
+	}
+	if configuration.ArchiveMode != nil && cfg.Archive.Mode == "" {
+		cfg.Archive.Mode = *configuration.ArchiveMode
+	}
+	if configuration.ArchiveDSN != nil && cfg.Archive.DSN == "" {
+		cfg.Archive.DSN = *configuration.ArchiveDSN
+	}
+	if configuration.ArchiveDir != nil && cfg.Archive.Dir == "" {
+		cfg.Archive.Dir = *configuration.ArchiveDir
+	}
+	if configuration.InactiveDays != nil && cfg.Archive.InactiveDays == 0 {
+		cfg.Archive.InactiveDays = *configuration.InactiveDays
+	}
+	if configuration.MaxAgeDays != nil && cfg.Archive.MaxAgeDays == 0 {
+		cfg.Archive.MaxAgeDays = *configuration.MaxAgeDays
+	}
+	if configuration.RetentionDays != nil && cfg.Archive.RetentionDays == 0 {
+		cfg.Archive.RetentionDays = *configuration.RetentionDays
+	}
+	if configuration.Rehydrate != nil && !cfg.Archive.Rehydrate {
+		cfg.Archive.Rehydrate = *configuration.Rehydrate
+	}
+	if configuration.BackupDir != nil && cfg.Backup.Dir == "" {
+		cfg.Backup.Dir = *configuration.BackupDir
+	}
 
 	return nil
 }
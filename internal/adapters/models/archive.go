@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ArchivedLink ссылка, перенесенная в архив.
+type ArchivedLink struct {
+	CreatedAt    time.Time `json:"created_at"`
+	AccessedAt   time.Time `json:"accessed_at"` // время последнего перехода, нулевое - переходов не было.
+	ArchivedAt   time.Time `json:"archived_at"`
+	ShortURL     string    `json:"short_url"`
+	OriginalURL  string    `json:"original_url"`
+	UserID       string    `json:"user_id"`
+	Domain       string    `json:"domain,omitempty"`
+	RedirectCode int       `json:"redirect_code,omitempty"`
+}
+
+// Link возвращает короткую ссылку архивной записи.
+func (a ArchivedLink) Link() ShortLink {
+	return ShortLink{
+		ShortURL:     a.ShortURL,
+		OriginalURL:  a.OriginalURL,
+		UserID:       a.UserID,
+		Domain:       a.Domain,
+		RedirectCode: a.RedirectCode,
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Действия пользователей, записываемые в журнал аудита.
+const (
+	AuditActionCreate = "create" // создание короткой ссылки.
+	AuditActionDelete = "delete" // удаление короткой ссылки.
+)
+
+// Транспорты, через которые выполняются действия.
+const (
+	TransportREST = "rest"
+	TransportGRPC = "grpc"
+)
+
+// AuditEvent запись журнала аудита.
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id"`
+	Action    string    `json:"action"`
+	Domain    string    `json:"domain,omitempty"`
+	ShortURL  string    `json:"short_url"`
+	Transport string    `json:"transport"`
+	IP        string    `json:"ip"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+	ID        int64     `json:"id"`
+}
+
+// AuditFilter фильтр записей журнала аудита.
+type AuditFilter struct {
+	From      time.Time
+	To        time.Time
+	UserID    string
+	Action    string
+	ShortURL  string
+	Transport string
+	AfterID   int64
+	Limit     int
+}
+
+// Match проверяет, подходит ли запись под фильтр.
+func (f *AuditFilter) Match(e *AuditEvent) bool {
+	switch {
+	case f.AfterID != 0 && e.ID <= f.AfterID:
+		return false
+	case f.UserID != "" && e.UserID != f.UserID:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.ShortURL != "" && e.ShortURL != f.ShortURL:
+		return false
+	case f.Transport != "" && e.Transport != f.Transport:
+		return false
+	case !f.From.IsZero() && e.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.Time.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// AuditVerification результат проверки целостности журнала аудита.
+type AuditVerification struct {
+	Valid    bool  `json:"valid"`
+	Checked  int   `json:"checked"`
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// BackupInfo сведения о резервной копии ссылок.
+type BackupInfo struct {
+	CreatedAt time.Time `json:"created_at"` // момент снимка хранилища.
+	File      string    `json:"file,omitempty"`
+	Links     int       `json:"links"`   // количество ссылок, включая удаленные.
+	Deleted   int       `json:"deleted"` // количество удаленных ссылок.
+}
@@ -1,9 +1,50 @@
 package models
 
+import (
+	"net/http"
+	"time"
+)
+
 // ShortLink модель хранения коротких ссылок.
 type ShortLink struct {
-	ShortURL    string
-	OriginalURL string
-	UserID      string
-	ID          int64
+	ShortURL     string
+	OriginalURL  string
+	OriginalHash string // слепой индекс оригинальной ссылки, пустой - ссылки сравниваются по OriginalURL.
+	UserID       string
+	Domain       string // домен короткой ссылки, пустое значение - домен по умолчанию.
+	ID           int64
+	RedirectCode int       // код перенаправления, 0 - код по умолчанию сервиса.
+	IsDeleted    bool      // ссылка помечена на удаление.
+	CreatedAt    time.Time // время создания, нулевое - неизвестно.
+}
+
+// CreationTime возвращает время создания ссылки или now, если оно неизвестно.
+// Хранилища сохраняют известное время создания, например, при восстановлении ссылки из резервной копии.
+func (l ShortLink) CreationTime(now time.Time) time.Time {
+	if l.CreatedAt.IsZero() {
+		return now
+	}
+	return l.CreatedAt
+}
+
+// IsValidRedirectCode проверяет, допустим ли код перенаправления короткой ссылки.
+func IsValidRedirectCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// UnixTime возвращает время по Unix секундам, 0 - нулевое время.
+func UnixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// IsPermanentRedirect возвращает true для постоянных перенаправлений.
+func IsPermanentRedirect(code int) bool {
+	return code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect
 }
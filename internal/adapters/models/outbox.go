@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Типы событий изменения ссылок.
+const (
+	LinkEventCreated = "link.created" // ссылка создана.
+	LinkEventDeleted = "link.deleted" // ссылка удалена пользователем.
+)
+
+// LinkEvent событие изменения ссылки, публикуемое через outbox.
+type LinkEvent struct {
+	CreatedAt   time.Time `json:"created_at"`
+	Type        string    `json:"type"`
+	UserID      string    `json:"user_id"`
+	Domain      string    `json:"domain,omitempty"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	ID          int64     `json:"id"`
+}
+
+// NewLinkEvent создает событие изменения ссылки пользователя.
+func NewLinkEvent(eventType, userID string, link ShortLink) LinkEvent {
+	return LinkEvent{
+		Type:        eventType,
+		UserID:      userID,
+		Domain:      link.Domain,
+		ShortURL:    link.ShortURL,
+		OriginalURL: link.OriginalURL,
+	}
+}
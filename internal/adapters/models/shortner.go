@@ -1,25 +1,49 @@
 package models
 
+import "time"
+
 // ShortenBatchRequest запрос по оригинальной ссылки.
 type ShortenBatchRequest struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
+	Domain        string `json:"domain,omitempty"`
+	RedirectCode  int    `json:"redirect_code,omitempty"`
 }
 
 // ShortenBatchResponse ответ с короткой ссылкой.
 type ShortenBatchResponse struct {
 	CorrelationID string `json:"correlation_id"`
 	ShortURL      string `json:"short_url"`
+	Domain        string `json:"-"`
 }
 
 // ShortenURL данные ссылки.
 type ShortenURL struct {
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
+	Domain      string `json:"-"`
+	ID          int64  `json:"-"` // позиция ссылки в порядке создания.
+}
+
+// UserLink полные данные ссылки пользователя.
+type UserLink struct {
+	CreatedAt    *time.Time `json:"created_at,omitempty"` // время создания, не задано - неизвестно.
+	ShortURL     string     `json:"short_url"`
+	Code         string     `json:"code"`
+	OriginalURL  string     `json:"original_url"`
+	Domain       string     `json:"domain"`
+	RedirectCode int        `json:"redirect_code"`
+}
+
+// Page страница ссылок пользователя в порядке создания.
+type Page struct {
+	After int64 // позиция последней ссылки предыдущей страницы, 0 - с начала.
+	Limit int   // максимальное количество ссылок на странице.
 }
 
-// ShortenStats статистика.
-type ShortenStats struct {
-	URLs  int `json:"urls"`
-	Users int `json:"users"`
+// ShortDomain домен коротких ссылок.
+type ShortDomain struct {
+	Domain  string `json:"domain"`
+	BaseURL string `json:"base_url"`
+	Default bool   `json:"default,omitempty"`
 }
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// StatsQuery параметры статистики.
+type StatsQuery struct {
+	Since time.Time // начало окна подсчета созданных ссылок по дням.
+	Top   int       // количество пользователей с наибольшим числом ссылок.
+}
+
+// ShortenStats статистика.
+type ShortenStats struct {
+	URLs          int          `json:"urls"`
+	Users         int          `json:"users"`
+	Deleted       int          `json:"deleted"` // удаленные ссылки, ожидающие очистки.
+	CreatedPerDay []DailyCount `json:"created_per_day"`
+	TopUsers      []UserCount  `json:"top_users"`
+	StorageBytes  int64        `json:"storage_bytes"` // размер хранилища, 0 - неизвестен.
+}
+
+// DailyCount количество ссылок, созданных за сутки.
+type DailyCount struct {
+	Day   string `json:"day"` // сутки UTC в формате YYYY-MM-DD.
+	Count int    `json:"count"`
+}
+
+// UserCount количество неудаленных ссылок пользователя.
+type UserCount struct {
+	UserID string `json:"user_id"`
+	URLs   int    `json:"urls"`
+}
+
+// DayLayout формат суток в статистике.
+const DayLayout = "2006-01-02"
+
+// DailyCounts возвращает количество ссылок по суткам в порядке возрастания суток.
+func DailyCounts(days map[string]int) []DailyCount {
+	result := make([]DailyCount, 0, len(days))
+	for day, count := range days {
+		result = append(result, DailyCount{Day: day, Count: count})
+	}
+	slices.SortFunc(result, func(a, b DailyCount) int {
+		return cmp.Compare(a.Day, b.Day)
+	})
+	return result
+}
+
+// TopUsers возвращает не больше top пользователей с наибольшим числом ссылок.
+// Пользователи с одинаковым числом ссылок упорядочены по идентификатору.
+func TopUsers(users map[string]int, top int) []UserCount {
+	result := make([]UserCount, 0, len(users))
+	for userID, count := range users {
+		result = append(result, UserCount{UserID: userID, URLs: count})
+	}
+	slices.SortFunc(result, func(a, b UserCount) int {
+		return cmp.Or(cmp.Compare(b.URLs, a.URLs), cmp.Compare(a.UserID, b.UserID))
+	})
+	return result[:min(max(top, 0), len(result))]
+}
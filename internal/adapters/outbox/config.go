@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Получатели событий.
+const (
+	SinkLog  = "log"  // журнал сервиса.
+	SinkFile = "file" // файл, по одному событию на строку.
+	SinkHTTP = "http" // POST запрос на OUTBOX_HTTP_URL.
+)
+
+// Config конфигурация публикации событий. Публикация отключена, если получатель не задан.
+type Config struct {
+	Sink        string        `env:"OUTBOX_SINK"`
+	FilePath    string        `env:"OUTBOX_FILE_PATH"`
+	URL         string        `env:"OUTBOX_HTTP_URL"`
+	HTTPTimeout time.Duration `env:"OUTBOX_HTTP_TIMEOUT"`
+	Interval    time.Duration `env:"OUTBOX_INTERVAL"`   // период опроса outbox.
+	BatchSize   int           `env:"OUTBOX_BATCH_SIZE"` // количество событий в одной отправке.
+}
+
+// Enabled - публикация событий включена.
+func (c *Config) Enabled() bool {
+	return c.Sink != ""
+}
+
+// NewSink создает получателя событий по конфигурации.
+func NewSink(cfg *Config, log *zap.Logger) (Sink, error) {
+	switch cfg.Sink {
+	case SinkLog:
+		return NewLogSink(log), nil
+	case SinkFile:
+		if cfg.FilePath == "" {
+			return nil, errors.New("outbox file path is not set")
+		}
+		return NewFileSink(cfg.FilePath)
+	case SinkHTTP:
+		if cfg.URL == "" {
+			return nil, errors.New("outbox http url is not set")
+		}
+		return NewHTTPSink(cfg.URL, cfg.HTTPTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox sink `%s`", cfg.Sink)
+	}
+}
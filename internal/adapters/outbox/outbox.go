@@ -0,0 +1,107 @@
+// Модуль outbox публикует события изменения ссылок по схеме transactional outbox.
+//
+// Хранилище записывает события вместе с изменением ссылок, фоновый Relay передает
+// неотправленные события в Sink и отмечает их отправленными.
+// Доставка выполняется не менее одного раза: при сбое после публикации события будут отправлены повторно,
+// получатель различает повторы по идентификатору события.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+// ErrNotSupported - хранилище не записывает события изменения ссылок.
+var ErrNotSupported = errors.New("storage does not support outbox")
+
+// Source - хранилище событий, ожидающих публикации.
+type Source interface {
+	// RelayOutbox передает fn до limit неотправленных событий в порядке записи
+	// и отмечает их отправленными, если fn завершилась без ошибки. Возвращает количество отправленных событий.
+	RelayOutbox(ctx context.Context, limit int, fn func(events []models.LinkEvent) error) (int, error)
+}
+
+// Queue - очередь событий в памяти процесса для хранилищ без транзакций.
+// События хранятся до отправки и теряются при остановке процесса.
+type Queue struct {
+	mu      *sync.Mutex
+	relayMu *sync.Mutex
+	events  []models.LinkEvent
+	nextID  int64
+}
+
+// NewQueue создает Queue.
+func NewQueue() *Queue {
+	return &Queue{
+		mu:      &sync.Mutex{},
+		relayMu: &sync.Mutex{},
+		events:  []models.LinkEvent{},
+	}
+}
+
+// Append добавляет события в очередь, назначая им идентификаторы и время.
+func (q *Queue) Append(events ...models.LinkEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now().UTC()
+	for _, e := range events {
+		q.nextID++
+		e.ID = q.nextID
+		e.CreatedAt = now
+		q.events = append(q.events, e)
+	}
+}
+
+// Drop удаляет из очереди неотправленные события, для которых match возвращает true.
+func (q *Queue) Drop(match func(e models.LinkEvent) bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	events := q.events[:0]
+	for _, e := range q.events {
+		if !match(e) {
+			events = append(events, e)
+		}
+	}
+	q.events = events
+}
+
+// Len возвращает количество неотправленных событий.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.events)
+}
+
+// RelayOutbox передает fn до limit неотправленных событий и удаляет их из очереди после успешной отправки.
+func (q *Queue) RelayOutbox(ctx context.Context, limit int, fn func(events []models.LinkEvent) error) (int, error) {
+	q.relayMu.Lock()
+	defer q.relayMu.Unlock()
+
+	q.mu.Lock()
+	n := min(limit, len(q.events))
+	events := make([]models.LinkEvent, n)
+	copy(events, q.events)
+	q.mu.Unlock()
+	if n == 0 {
+		return 0, nil
+	}
+
+	if err := fn(events); err != nil {
+		return 0, err
+	}
+
+	// события могли быть удалены из очереди во время отправки, удаляем только отправленные.
+	sent := make(map[int64]struct{}, n)
+	for _, e := range events {
+		sent[e.ID] = struct{}{}
+	}
+	q.Drop(func(e models.LinkEvent) bool {
+		_, ok := sent[e.ID]
+		return ok
+	})
+	return n, nil
+}
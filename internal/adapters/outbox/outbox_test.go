@@ -0,0 +1,121 @@
+package outbox_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/outbox"
+)
+
+func event(short string) models.LinkEvent {
+	return models.NewLinkEvent(models.LinkEventCreated, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://" + short})
+}
+
+func TestQueue_RelayOutbox(t *testing.T) {
+	ctx := context.Background()
+	q := outbox.NewQueue()
+	q.Append(event("a"), event("b"), event("c"))
+
+	errSink := errors.New("sink failed")
+	_, err := q.RelayOutbox(ctx, 2, func(events []models.LinkEvent) error { return errSink })
+	require.ErrorIs(t, err, errSink)
+	require.Equal(t, 3, q.Len(), "events are kept after failure")
+
+	got := []models.LinkEvent{}
+	n, err := q.RelayOutbox(ctx, 2, func(events []models.LinkEvent) error {
+		got = append(got, events...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 1, q.Len())
+	require.Equal(t, int64(1), got[0].ID)
+	require.Equal(t, "b", got[1].ShortURL)
+	require.False(t, got[0].CreatedAt.IsZero())
+
+	q.Drop(func(e models.LinkEvent) bool { return e.ShortURL == "c" })
+	n, err = q.RelayOutbox(ctx, 2, func(events []models.LinkEvent) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestRelay_FileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "outbox.jsonl")
+	sink, err := outbox.NewFileSink(path)
+	require.NoError(t, err)
+
+	q := outbox.NewQueue()
+	q.Append(event("a"), event("b"), event("c"))
+	relay := outbox.NewRelay(q, sink, outbox.SetBatchSize(2))
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	shorts := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e models.LinkEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		shorts = append(shorts, e.ShortURL)
+	}
+	require.Equal(t, []string{"a", "b", "c"}, shorts)
+}
+
+func TestRelay_HTTPSink(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	received := []models.LinkEvent{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status == http.StatusOK {
+			var events []models.LinkEvent
+			if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			received = append(received, events...)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	q := outbox.NewQueue()
+	q.Append(event("a"))
+	relay := outbox.NewRelay(q, outbox.NewHTTPSink(srv.URL, 0))
+
+	_, err := relay.RelayOnce(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 1, q.Len())
+
+	status = http.StatusOK
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 0, q.Len())
+	require.Len(t, received, 1)
+	require.Equal(t, "a", received[0].ShortURL)
+}
+
+func TestNewSink(t *testing.T) {
+	_, err := outbox.NewSink(&outbox.Config{Sink: "kafka"}, nil)
+	require.Error(t, err)
+	_, err = outbox.NewSink(&outbox.Config{Sink: outbox.SinkHTTP}, nil)
+	require.Error(t, err)
+	sink, err := outbox.NewSink(&outbox.Config{Sink: outbox.SinkFile, FilePath: filepath.Join(t.TempDir(), "e.jsonl")}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &outbox.FileSink{}, sink)
+}
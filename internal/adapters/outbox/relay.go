@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+var (
+	defaultInterval  = time.Second // период опроса outbox по умолчанию.
+	defaultBatchSize = 100         // количество событий в одной отправке по умолчанию.
+	maxRetryDelay    = time.Minute // максимальная задержка повтора после ошибки.
+)
+
+// Relay переносит события из хранилища в получателя.
+type Relay struct {
+	source    Source
+	sink      Sink
+	log       *zap.Logger
+	interval  time.Duration
+	batchSize int
+}
+
+// Option интерфейс опции Relay.
+type Option func(*Relay)
+
+// SetLogger устанавливает логер.
+func SetLogger(log *zap.Logger) Option {
+	return func(r *Relay) {
+		r.log = log
+	}
+}
+
+// SetInterval устанавливает период опроса outbox.
+func SetInterval(interval time.Duration) Option {
+	return func(r *Relay) {
+		if interval > 0 {
+			r.interval = interval
+		}
+	}
+}
+
+// SetBatchSize устанавливает количество событий в одной отправке.
+func SetBatchSize(size int) Option {
+	return func(r *Relay) {
+		if size > 0 {
+			r.batchSize = size
+		}
+	}
+}
+
+// NewRelay создает Relay.
+func NewRelay(source Source, sink Sink, options ...Option) *Relay {
+	r := &Relay{
+		source:    source,
+		sink:      sink,
+		log:       zap.NewNop(),
+		interval:  defaultInterval,
+		batchSize: defaultBatchSize,
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	return r
+}
+
+// Run переносит события до отмены контекста.
+// Полные порции отправляются сразу одна за другой, после ошибки задержка повтора растет до maxRetryDelay.
+func (r *Relay) Run(ctx context.Context) {
+	delay := r.interval
+	for {
+		n, err := r.RelayOnce(ctx)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			r.log.Warn("failed relay outbox events", zap.Error(err), zap.Duration("retry", delay))
+		case n == r.batchSize:
+			delay = r.interval
+			continue
+		default:
+			delay = r.interval
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if err != nil {
+			delay = min(delay*2, maxRetryDelay)
+		}
+	}
+}
+
+// RelayOnce отправляет одну порцию событий и возвращает их количество.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	return r.source.RelayOutbox(ctx, r.batchSize, func(events []models.LinkEvent) error {
+		return r.sink.Publish(ctx, events)
+	})
+}
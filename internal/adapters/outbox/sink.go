@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+// Sink - получатель событий.
+type Sink interface {
+	// Publish публикует события. Ошибка означает, что события нужно отправить повторно.
+	Publish(ctx context.Context, events []models.LinkEvent) error
+}
+
+// LogSink пишет события в журнал сервиса.
+type LogSink struct {
+	log *zap.Logger
+}
+
+// NewLogSink создает LogSink.
+func NewLogSink(log *zap.Logger) *LogSink {
+	return &LogSink{log: log}
+}
+
+// Publish пишет события в журнал.
+func (s *LogSink) Publish(ctx context.Context, events []models.LinkEvent) error {
+	for _, e := range events {
+		s.log.Info("link event",
+			zap.Int64("id", e.ID),
+			zap.String("type", e.Type),
+			zap.String("user_id", e.UserID),
+			zap.String("domain", e.Domain),
+			zap.String("short_url", e.ShortURL),
+			zap.String("original_url", e.OriginalURL),
+			zap.Time("created_at", e.CreatedAt),
+		)
+	}
+	return nil
+}
+
+// FileSink дописывает события в файл, по одному JSON на строку.
+type FileSink struct {
+	mu   *sync.Mutex
+	path string
+}
+
+// NewFileSink создает FileSink.
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed create path of outbox file: %w", err)
+	}
+	return &FileSink{mu: &sync.Mutex{}, path: path}, nil
+}
+
+// Publish дописывает события в файл и сбрасывает его на диск.
+func (s *FileSink) Publish(ctx context.Context, events []models.LinkEvent) error {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed marshal event: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed open outbox file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed write events: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("failed sync outbox file: %w", err)
+	}
+	return nil
+}
+
+var defaultHTTPTimeout = time.Second * 10 // время ожидания ответа получателя по умолчанию.
+
+// HTTPSink отправляет события POST запросом с JSON массивом событий.
+type HTTPSink struct {
+	client *http.Client
+	url    string
+}
+
+// NewHTTPSink создает HTTPSink.
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &HTTPSink{client: &http.Client{Timeout: timeout}, url: url}
+}
+
+// Publish отправляет события, успешным считается ответ с кодом 2xx.
+func (s *HTTPSink) Publish(ctx context.Context, events []models.LinkEvent) error {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed marshal events: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed send events: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("events rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,511 @@
+// Модуль bolt реализует хранилище ссылок во встроенной key-value базе bbolt.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+var (
+	bucketLinks     = []byte("links")     // ссылки по (домен, короткий код).
+	bucketUsers     = []byte("users")     // ссылки пользователя в порядке добавления.
+	bucketOriginals = []byte("originals") // короткий код по (пользователь, домен, оригинальная ссылка).
+
+	openTimeout = time.Second // ожидание блокировки файла базы.
+)
+
+const sep = 0 // разделитель частей составного ключа.
+
+// item - ссылка в бакете links.
+type item struct {
+	UserID       string `json:"user_id"`
+	ShortURL     string `json:"short_url"`
+	OriginalURL  string `json:"original_url"`
+	OriginalHash string `json:"original_hash,omitempty"`
+	Domain       string `json:"domain,omitempty"`
+	RedirectCode int    `json:"redirect_code,omitempty"`
+	IsDeleted    bool   `json:"is_deleted,omitempty"`
+	Seq          uint64 `json:"seq"`
+	CreatedAt    int64  `json:"created_at,omitempty"` // время создания, Unix секунды.
+}
+
+func (i *item) toLink() models.ShortLink {
+	return models.ShortLink{
+		ShortURL:     i.ShortURL,
+		OriginalURL:  i.OriginalURL,
+		OriginalHash: i.OriginalHash,
+		UserID:       i.UserID,
+		Domain:       i.Domain,
+		RedirectCode: i.RedirectCode,
+		IsDeleted:    i.IsDeleted,
+		CreatedAt:    models.UnixTime(i.CreatedAt),
+	}
+}
+
+// Store имплементация хранилища bbolt.
+type Store struct {
+	db *bbolt.DB
+}
+
+// New создает Store.
+func New(cfg *Config) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed create path of bolt storage: %w", err)
+	}
+	db, err := bbolt.Open(cfg.Path, 0o600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed open bolt storage: %w", classify(err))
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{bucketLinks, bucketUsers, bucketOriginals} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("failed create bucket %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed initialize bolt storage: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func joinKey(parts ...string) []byte {
+	var b bytes.Buffer
+	for i, p := range parts {
+		if i > 0 {
+			b.WriteByte(sep)
+		}
+		b.WriteString(p)
+	}
+	return b.Bytes()
+}
+
+func linkKey(domain, short string) []byte {
+	return joinKey(domain, short)
+}
+
+// originalKey - ключ индекса оригинальных ссылок. Ссылка со слепым индексом hash индексируется по нему.
+func originalKey(userID, domain, original, hash string) []byte {
+	if hash != "" {
+		return joinKey(userID, domain, "", hash)
+	}
+	return joinKey(userID, domain, original)
+}
+
+// userPrefix - префикс ключей ссылок пользователя.
+func userPrefix(userID string) []byte {
+	return append([]byte(userID), sep)
+}
+
+// userKey - ключ ссылки пользователя, порядковый номер сохраняет порядок добавления.
+func userKey(userID string, seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(userPrefix(userID), seq)
+}
+
+func getItem(tx *bbolt.Tx, key []byte) (*item, error) {
+	v := tx.Bucket(bucketLinks).Get(key)
+	if v == nil {
+		return nil, storeerror.ErrNotFoundKey
+	}
+	var it item
+	if err := json.Unmarshal(v, &it); err != nil {
+		return nil, fmt.Errorf("failed unmarshal link: %w", err)
+	}
+	return &it, nil
+}
+
+func putItem(tx *bbolt.Tx, it *item) error {
+	b, err := json.Marshal(it)
+	if err != nil {
+		return fmt.Errorf("failed marshal link: %w", err)
+	}
+	if err = tx.Bucket(bucketLinks).Put(linkKey(it.Domain, it.ShortURL), b); err != nil {
+		return fmt.Errorf("failed put link: %w", err)
+	}
+	return nil
+}
+
+// insert сохраняет ссылку и индексы в транзакции.
+func insert(tx *bbolt.Tx, userID string, link models.ShortLink) error {
+	orig := originalKey(userID, link.Domain, link.OriginalURL, link.OriginalHash)
+	if short := tx.Bucket(bucketOriginals).Get(orig); short != nil {
+		return &notUniqueError{short: string(short)}
+	}
+	key := linkKey(link.Domain, link.ShortURL)
+	if tx.Bucket(bucketLinks).Get(key) != nil {
+		return storeerror.ErrDuplicateShortURL
+	}
+
+	links := tx.Bucket(bucketLinks)
+	seq, err := links.NextSequence()
+	if err != nil {
+		return fmt.Errorf("failed get sequence: %w", err)
+	}
+	it := &item{
+		UserID:       userID,
+		ShortURL:     link.ShortURL,
+		OriginalURL:  link.OriginalURL,
+		OriginalHash: link.OriginalHash,
+		Domain:       link.Domain,
+		RedirectCode: link.RedirectCode,
+		Seq:          seq,
+		CreatedAt:    link.CreationTime(time.Now()).Unix(),
+	}
+	if err = putItem(tx, it); err != nil {
+		return err
+	}
+	if err = tx.Bucket(bucketUsers).Put(userKey(userID, seq), key); err != nil {
+		return fmt.Errorf("failed put user index: %w", err)
+	}
+	err = tx.Bucket(bucketOriginals).Put(orig, []byte(link.ShortURL))
+	if err != nil {
+		return fmt.Errorf("failed put original index: %w", err)
+	}
+	return nil
+}
+
+// unindexOriginal удаляет ссылку из индекса оригинальных ссылок, если индекс указывает на нее.
+func unindexOriginal(tx *bbolt.Tx, it *item) error {
+	originals := tx.Bucket(bucketOriginals)
+	key := originalKey(it.UserID, it.Domain, it.OriginalURL, it.OriginalHash)
+	if !bytes.Equal(originals.Get(key), []byte(it.ShortURL)) {
+		return nil
+	}
+	if err := originals.Delete(key); err != nil {
+		return fmt.Errorf("failed delete original index: %w", err)
+	}
+	return nil
+}
+
+// notUniqueError - оригинальная ссылка уже сокращена пользователем.
+type notUniqueError struct {
+	short string
+}
+
+func (e *notUniqueError) Error() string { return storeerror.ErrNotUnique.Error() }
+func (e *notUniqueError) Unwrap() error { return storeerror.ErrNotUnique }
+
+// Get Возвращает ссылку.
+func (s *Store) Get(ctx context.Context, domain, short string) (models.ShortLink, error) {
+	var link models.ShortLink
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		it, err := getItem(tx, linkKey(domain, short))
+		if err != nil {
+			return err
+		}
+		link = it.toLink()
+		return nil
+	})
+	if err != nil {
+		return models.ShortLink{}, fmt.Errorf("failed get link: %w", classify(err))
+	}
+	if link.IsDeleted {
+		return link, storeerror.ErrShortURLDeleted
+	}
+	return link, nil
+}
+
+// GetAllURL Возвращает все ссылки пользователя.
+func (s *Store) GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error) {
+	result := []models.ShortenURL{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := userPrefix(userID)
+		c := tx.Bucket(bucketUsers).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			it, err := getItem(tx, v)
+			if err != nil {
+				return err
+			}
+			if it.IsDeleted {
+				continue
+			}
+			result = append(result, models.ShortenURL{ShortURL: it.ShortURL, OriginalURL: it.OriginalURL, Domain: it.Domain})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed get user links: %w", classify(err))
+	}
+	return result, nil
+}
+
+// GetUserURLs Возвращает страницу ссылок пользователя.
+// Позиция ссылки - порядковый номер из ключа индекса пользователя.
+func (s *Store) GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error) {
+	result := []models.ShortenURL{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := userPrefix(userID)
+		c := tx.Bucket(bucketUsers).Cursor()
+		k, v := c.Seek(userKey(userID, uint64(page.After)+1))
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(result) < page.Limit; k, v = c.Next() {
+			it, err := getItem(tx, v)
+			if err != nil {
+				return err
+			}
+			if it.IsDeleted {
+				continue
+			}
+			result = append(result, models.ShortenURL{
+				ShortURL:    it.ShortURL,
+				OriginalURL: it.OriginalURL,
+				Domain:      it.Domain,
+				ID:          int64(it.Seq),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed get user links: %w", classify(err))
+	}
+	return result, nil
+}
+
+// Set Сохраняет ссылку.
+func (s *Store) Set(ctx context.Context, userID string, link models.ShortLink) (string, error) {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return insert(tx, userID, link)
+	})
+	if err != nil {
+		var nu *notUniqueError
+		if errors.As(err, &nu) {
+			return nu.short, fmt.Errorf("url `%s` is not unique: %w", link.OriginalURL, err)
+		}
+		return "", fmt.Errorf("failed setting short url: %w", classify(err))
+	}
+	return link.ShortURL, nil
+}
+
+// SetBatch Сохраняет список ссылок в одной транзакции.
+func (s *Store) SetBatch(ctx context.Context, userID string, batch []models.ShortLink) (
+	output []models.ShortLink,
+	err error,
+) {
+	var conflict models.ShortLink
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		for _, link := range batch {
+			if err := insert(tx, userID, link); err != nil {
+				conflict = link
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var nu *notUniqueError
+		if errors.As(err, &nu) {
+			return []models.ShortLink{{ShortURL: nu.short, OriginalURL: conflict.OriginalURL, Domain: conflict.Domain}},
+				fmt.Errorf("URL `%s` is not unique: %w", conflict.OriginalURL, err)
+		}
+		return []models.ShortLink{}, fmt.Errorf("set link `%s` failed: %w", conflict.OriginalURL, classify(err))
+	}
+	return batch, nil
+}
+
+// Ping Проверка соединения с хранилищем.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.db.View(func(*bbolt.Tx) error { return nil }); err != nil {
+		return fmt.Errorf("failed ping bolt storage: %w", classify(err))
+	}
+	return nil
+}
+
+// DeleteShortURLs Мягкое удаляет ссылки.
+// Удаленная ссылка не мешает сократить оригинальную ссылку повторно.
+func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
+	var deleted []models.ShortLink
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		deleted = make([]models.ShortLink, 0, len(shorts))
+		for _, short := range shorts {
+			it, err := getItem(tx, linkKey(short.Domain, short.ShortURL))
+			if errors.Is(err, storeerror.ErrNotFoundKey) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if it.UserID != short.UserID || it.IsDeleted {
+				continue
+			}
+			it.IsDeleted = true
+			if err = putItem(tx, it); err != nil {
+				return err
+			}
+			if err = unindexOriginal(tx, it); err != nil {
+				return err
+			}
+			deleted = append(deleted, short)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed deleting shorts: %w", classify(err))
+	}
+	return deleted, nil
+}
+
+// GetState Получение статисики.
+// Агрегаты считаются за один проход по бакету ссылок, размер хранилища - размер файла базы.
+func (s *Store) GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error) {
+	stats := models.ShortenStats{}
+	userCount := make(map[string]int)
+	dayCount := make(map[string]int)
+	since := query.Since.Unix()
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		stats.StorageBytes = tx.Size()
+		return tx.Bucket(bucketLinks).ForEach(func(_, v []byte) error {
+			var it item
+			if err := json.Unmarshal(v, &it); err != nil {
+				return fmt.Errorf("failed unmarshal link: %w", err)
+			}
+			if it.IsDeleted {
+				stats.Deleted++
+				return nil
+			}
+			stats.URLs++
+			userCount[it.UserID]++
+			if it.CreatedAt >= since {
+				dayCount[time.Unix(it.CreatedAt, 0).UTC().Format(models.DayLayout)]++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return models.ShortenStats{}, fmt.Errorf("failed get state: %w", classify(err))
+	}
+	stats.Users = len(userCount)
+	stats.CreatedPerDay = models.DailyCounts(dayCount)
+	stats.TopUsers = models.TopUsers(userCount, query.Top)
+	return stats, nil
+}
+
+// HardDeleteURLs Хард удаление ссылок.
+func (s *Store) HardDeleteURLs(ctx context.Context) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		deleted := []item{}
+		err := tx.Bucket(bucketLinks).ForEach(func(_, v []byte) error {
+			var it item
+			if err := json.Unmarshal(v, &it); err != nil {
+				return fmt.Errorf("failed unmarshal link: %w", err)
+			}
+			if it.IsDeleted {
+				deleted = append(deleted, it)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, it := range deleted {
+			if err = tx.Bucket(bucketLinks).Delete(linkKey(it.Domain, it.ShortURL)); err != nil {
+				return fmt.Errorf("failed delete link: %w", err)
+			}
+			if err = tx.Bucket(bucketUsers).Delete(userKey(it.UserID, it.Seq)); err != nil {
+				return fmt.Errorf("failed delete user index: %w", err)
+			}
+			if err = unindexOriginal(tx, &it); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed hard deleting URLs: %w", classify(err))
+	}
+	return nil
+}
+
+// FindOriginal возвращает короткий код неудаленной ссылки пользователя без слепого индекса
+// с оригинальной ссылкой original.
+func (s *Store) FindOriginal(ctx context.Context, userID, domain, original string) (string, error) {
+	var short string
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(bucketOriginals).Get(originalKey(userID, domain, original, "")); v != nil {
+			short = string(v)
+			return nil
+		}
+		return storeerror.ErrNotFoundKey
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed find original: %w", classify(err))
+	}
+	return short, nil
+}
+
+// RewriteOriginalURL заменяет оригинальную ссылку и ее слепой индекс, если ссылка не изменилась с момента чтения link.
+func (s *Store) RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error) {
+	var rewritten bool
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		it, err := getItem(tx, linkKey(link.Domain, link.ShortURL))
+		if errors.Is(err, storeerror.ErrNotFoundKey) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if it.OriginalURL != link.OriginalURL || it.OriginalURL == original {
+			return nil
+		}
+		if !it.IsDeleted {
+			originals := tx.Bucket(bucketOriginals)
+			key := originalKey(it.UserID, it.Domain, original, hash)
+			if short := originals.Get(key); short != nil && string(short) != it.ShortURL {
+				return storeerror.ErrNotUnique
+			}
+			if err = unindexOriginal(tx, it); err != nil {
+				return err
+			}
+			if err = originals.Put(key, []byte(it.ShortURL)); err != nil {
+				return fmt.Errorf("failed put original index: %w", err)
+			}
+		}
+		it.OriginalURL, it.OriginalHash = original, hash
+		rewritten = true
+		return putItem(tx, it)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed rewrite original url: %w", classify(err))
+	}
+	return rewritten, nil
+}
+
+// Walk обходит все ссылки в порядке ключей.
+func (s *Store) Walk(ctx context.Context, fn func(link models.ShortLink) error) error {
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketLinks).ForEach(func(_, v []byte) error {
+			var it item
+			if err := json.Unmarshal(v, &it); err != nil {
+				return fmt.Errorf("failed unmarshal link: %w", err)
+			}
+			return fn(it.toLink())
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed walk links: %w", classify(err))
+	}
+	return nil
+}
+
+// Snapshot обходит все ссылки. Обход выполняется в одной транзакции чтения,
+// которая видит состояние базы на момент своего начала.
+func (s *Store) Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error {
+	return s.Walk(ctx, fn)
+}
+
+// Close - закрыть соединение.
+func (s *Store) Close() {
+	_ = s.db.Close()
+}
@@ -0,0 +1,162 @@
+package bolt_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/bolt"
+	"github.com/playmixer/short-link/internal/adapters/storage/storagetest"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+func createBoltStorage(t *testing.T) (*bolt.Store, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "data.db")
+	s, err := bolt.New(&bolt.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s, path
+}
+
+func TestStore_SetGet(t *testing.T) {
+	ctx := context.Background()
+	s, _ := createBoltStorage(t)
+
+	_, err := s.Get(ctx, "", "WQEAWE")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+
+	short, err := s.Set(ctx, "1", models.ShortLink{ShortURL: "abc", OriginalURL: "https://github.com/", RedirectCode: 301})
+	require.NoError(t, err)
+	require.Equal(t, "abc", short)
+
+	link, err := s.Get(ctx, "", "abc")
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/", link.OriginalURL)
+	require.Equal(t, "1", link.UserID)
+	require.Equal(t, 301, link.RedirectCode)
+
+	short, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "def", OriginalURL: "https://github.com/"})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "abc", short)
+
+	_, err = s.Set(ctx, "2", models.ShortLink{ShortURL: "abc", OriginalURL: "https://gitlab.com/"})
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+
+	_, err = s.Set(ctx, "2", models.ShortLink{ShortURL: "abc", OriginalURL: "https://gitlab.com/", Domain: "go.dev"})
+	require.NoError(t, err)
+}
+
+func TestStore_SetBatch(t *testing.T) {
+	ctx := context.Background()
+	s, _ := createBoltStorage(t)
+	_, err := s.Set(ctx, "1", models.ShortLink{ShortURL: "abc", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		batch   []models.ShortLink
+		wantErr error
+	}{
+		{
+			name: "duplicate short",
+			batch: []models.ShortLink{
+				{ShortURL: "q1", OriginalURL: "https://go.dev/"},
+				{ShortURL: "abc", OriginalURL: "https://gitlab.com/"},
+			},
+			wantErr: storeerror.ErrDuplicateShortURL,
+		},
+		{
+			name: "not unique original",
+			batch: []models.ShortLink{
+				{ShortURL: "q1", OriginalURL: "https://go.dev/"},
+				{ShortURL: "q2", OriginalURL: "https://github.com/"},
+			},
+			wantErr: storeerror.ErrNotUnique,
+		},
+		{
+			name: "saved",
+			batch: []models.ShortLink{
+				{ShortURL: "q1", OriginalURL: "https://go.dev/"},
+				{ShortURL: "q2", OriginalURL: "https://gitlab.com/"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.SetBatch(ctx, "1", tt.batch)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				// транзакция откатывается целиком.
+				_, err = s.Get(ctx, "", "q1")
+				require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, res, len(tt.batch))
+		})
+	}
+
+	urls, err := s.GetAllURL(ctx, "1")
+	require.NoError(t, err)
+	require.Equal(t, []models.ShortenURL{
+		{ShortURL: "abc", OriginalURL: "https://github.com/"},
+		{ShortURL: "q1", OriginalURL: "https://go.dev/"},
+		{ShortURL: "q2", OriginalURL: "https://gitlab.com/"},
+	}, urls)
+}
+
+func TestStore_Delete(t *testing.T) {
+	ctx := context.Background()
+	s, path := createBoltStorage(t)
+	for _, short := range []string{"a", "b", "c"} {
+		_, err := s.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://github.com/" + short})
+		require.NoError(t, err)
+	}
+	_, err := s.Set(ctx, "2", models.ShortLink{ShortURL: "d", OriginalURL: "https://github.com/d"})
+	require.NoError(t, err)
+
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{
+		{ShortURL: "a", UserID: "1"},
+		{ShortURL: "d", UserID: "1"},
+	})
+	require.NoError(t, err)
+	_, err = s.Get(ctx, "", "a")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	_, err = s.Get(ctx, "", "d")
+	require.NoError(t, err)
+
+	stats, err := s.GetState(ctx, models.StatsQuery{})
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.URLs)
+	require.Equal(t, 2, stats.Users)
+
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	_, err = s.Get(ctx, "", "a")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "e", OriginalURL: "https://github.com/a"})
+	require.NoError(t, err)
+
+	s.Close()
+	s, err = bolt.New(&bolt.Config{Path: path})
+	require.NoError(t, err)
+	defer s.Close()
+	urlsList, err := s.GetAllURL(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, urlsList, 3)
+	require.NoError(t, s.Ping(ctx))
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		s, _ := createBoltStorage(t)
+		return s
+	})
+}
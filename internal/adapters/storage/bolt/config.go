@@ -0,0 +1,6 @@
+package bolt
+
+// Config настройка хранилища bbolt.
+type Config struct {
+	Path string `env:"BOLT_STORAGE_PATH"` // путь к файлу базы.
+}
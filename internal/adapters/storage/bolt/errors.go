@@ -0,0 +1,24 @@
+package bolt
+
+import (
+	"errors"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// classify переводит ошибки bolt в ошибки хранилища.
+func classify(err error) error {
+	if err == nil || storeerror.KindOf(err) != storeerror.KindUnknown {
+		return err
+	}
+	switch {
+	case errors.Is(err, bbolt.ErrTimeout):
+		return fmt.Errorf("%w: %w", storeerror.ErrTimeout, err)
+	case errors.Is(err, bbolt.ErrDatabaseNotOpen), errors.Is(err, bbolt.ErrDatabaseReadOnly):
+		return fmt.Errorf("%w: %w", storeerror.ErrUnavailable, err)
+	}
+	return storeerror.Classify(err)
+}
@@ -0,0 +1,300 @@
+// Модуль cache кэширует переходы по коротким ссылкам в памяти экземпляра сервиса.
+//
+// Изменения ссылок на других экземплярах доставляются через шину инвалидации.
+// После переподключения шины кэш очищается полностью, поэтому пропущенные события не оставляют устаревших записей.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/outbox"
+	"github.com/playmixer/short-link/internal/adapters/storage/database"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+var defaultTTL = time.Minute // время жизни ссылки в кэше по умолчанию.
+
+// ErrNotWalkable - хранилище не поддерживает обход ссылок.
+var ErrNotWalkable = errors.New("storage does not support walking links")
+
+// Store - интерфейс кэшируемого хранилища ссылок.
+type Store interface {
+	Get(ctx context.Context, domain, short string) (models.ShortLink, error)
+	GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error)
+	GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error)
+	Set(ctx context.Context, userID string, link models.ShortLink) (string, error)
+	SetBatch(ctx context.Context, userID string, batch []models.ShortLink) ([]models.ShortLink, error)
+	Ping(ctx context.Context) error
+	DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error)
+	GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error)
+	HardDeleteURLs(ctx context.Context) error
+	Close()
+}
+
+// Bus - шина событий инвалидации между экземплярами сервиса.
+type Bus interface {
+	// Listen доставляет события в кэш до отмены контекста.
+	Listen(ctx context.Context, inv database.Invalidator)
+}
+
+type key struct {
+	domain string
+	short  string
+}
+
+type entry struct {
+	key     key
+	link    models.ShortLink
+	expires time.Time
+}
+
+// Cache - хранилище с кэшем переходов по ссылкам.
+// Кэшируются найденные и удаленные ссылки, отсутствующие ссылки не кэшируются:
+// ссылка может быть создана на другом экземпляре.
+type Cache struct {
+	Store
+	mu    *sync.Mutex
+	items map[key]*list.Element
+	lru   *list.List
+	size  int
+	ttl   time.Duration
+	// gen увеличивается при каждой инвалидации, чтение из хранилища, начатое до нее, не кэшируется.
+	gen    uint64
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+}
+
+// New создает Cache. При bus == nil кэш инвалидируется только изменениями этого экземпляра.
+func New(store Store, cfg *Config, bus Bus) *Cache {
+	c := &Cache{
+		Store:  store,
+		mu:     &sync.Mutex{},
+		items:  make(map[key]*list.Element),
+		lru:    list.New(),
+		size:   cfg.Size,
+		ttl:    cfg.TTL,
+		cancel: func() {},
+		wg:     &sync.WaitGroup{},
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultTTL
+	}
+	if bus != nil {
+		var ctx context.Context
+		ctx, c.cancel = context.WithCancel(context.Background())
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			bus.Listen(ctx, c)
+		}()
+	}
+	return c
+}
+
+// Close останавливает подписку на шину и закрывает хранилище.
+func (c *Cache) Close() {
+	c.cancel()
+	c.wg.Wait()
+	c.Store.Close()
+}
+
+// Get Возвращает ссылку из кэша или хранилища.
+func (c *Cache) Get(ctx context.Context, domain, short string) (models.ShortLink, error) {
+	k := key{domain, short}
+	c.mu.Lock()
+	if el, ok := c.items[k]; ok {
+		e, _ := el.Value.(*entry)
+		if time.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			link := e.link
+			c.mu.Unlock()
+			if link.IsDeleted {
+				return link, storeerror.ErrShortURLDeleted
+			}
+			return link, nil
+		}
+		c.remove(el)
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	link, err := c.Store.Get(ctx, domain, short)
+	if err != nil && !errors.Is(err, storeerror.ErrShortURLDeleted) {
+		return link, err
+	}
+	c.mu.Lock()
+	if c.gen == gen {
+		c.put(k, link)
+	}
+	c.mu.Unlock()
+	return link, err
+}
+
+// put добавляет ссылку в кэш, вызывается под mu.
+func (c *Cache) put(k key, link models.ShortLink) {
+	if el, ok := c.items[k]; ok {
+		c.remove(el)
+	}
+	c.items[k] = c.lru.PushFront(&entry{key: k, link: link, expires: time.Now().Add(c.ttl)})
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove удаляет элемент кэша, вызывается под mu.
+func (c *Cache) remove(el *list.Element) {
+	e, _ := c.lru.Remove(el).(*entry)
+	delete(c.items, e.key)
+}
+
+// Invalidate удаляет ссылки из кэша.
+func (c *Cache) Invalidate(links ...models.ShortLink) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, link := range links {
+		if el, ok := c.items[key{link.Domain, link.ShortURL}]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// Flush очищает кэш.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.items = make(map[key]*list.Element)
+	c.lru.Init()
+}
+
+// Len возвращает количество ссылок в кэше.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// DeleteShortURLs Мягкое удаляет ссылки.
+func (c *Cache) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
+	deleted, err := c.Store.DeleteShortURLs(ctx, shorts)
+	c.Invalidate(shorts...)
+	return deleted, err
+}
+
+// HardDeleteURLs Хард удаление ссылок.
+func (c *Cache) HardDeleteURLs(ctx context.Context) error {
+	err := c.Store.HardDeleteURLs(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if e, _ := el.Value.(*entry); e.link.IsDeleted {
+			c.remove(el)
+		}
+		el = next
+	}
+	return err
+}
+
+// RelayOutbox передает события изменения ссылок хранилища.
+func (c *Cache) RelayOutbox(ctx context.Context, limit int, fn func(events []models.LinkEvent) error) (int, error) {
+	source, ok := c.Store.(outbox.Source)
+	if !ok {
+		return 0, outbox.ErrNotSupported
+	}
+	return source.RelayOutbox(ctx, limit, fn)
+}
+
+// Walk обходит все ссылки хранилища.
+func (c *Cache) Walk(ctx context.Context, fn func(link models.ShortLink) error) error {
+	w, ok := c.Store.(interface {
+		Walk(ctx context.Context, fn func(link models.ShortLink) error) error
+	})
+	if !ok {
+		return ErrNotWalkable
+	}
+	return w.Walk(ctx, fn)
+}
+
+// Snapshot обходит снимок ссылок хранилища.
+func (c *Cache) Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error {
+	sn, ok := c.Store.(interface {
+		Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error
+	})
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return sn.Snapshot(ctx, fn)
+}
+
+// FindOriginal ищет ссылку пользователя по оригинальной ссылке в хранилище.
+func (c *Cache) FindOriginal(ctx context.Context, userID, domain, original string) (string, error) {
+	f, ok := c.Store.(interface {
+		FindOriginal(ctx context.Context, userID, domain, original string) (string, error)
+	})
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	return f.FindOriginal(ctx, userID, domain, original)
+}
+
+// RewriteOriginalURL заменяет оригинальную ссылку в хранилище и удаляет ссылку из кэша.
+func (c *Cache) RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error) {
+	r, ok := c.Store.(interface {
+		RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error)
+	})
+	if !ok {
+		return false, errors.ErrUnsupported
+	}
+	rewritten, err := r.RewriteOriginalURL(ctx, link, original, hash)
+	c.Invalidate(link)
+	return rewritten, err
+}
+
+// TouchLinks отмечает переходы по ссылкам в хранилище.
+func (c *Cache) TouchLinks(ctx context.Context, links []models.ShortLink, at time.Time) error {
+	t, ok := c.Store.(interface {
+		TouchLinks(ctx context.Context, links []models.ShortLink, at time.Time) error
+	})
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return t.TouchLinks(ctx, links, at)
+}
+
+// ArchiveCandidates возвращает ссылки хранилища для переноса в архив.
+func (c *Cache) ArchiveCandidates(ctx context.Context, inactiveSince, createdBefore time.Time, limit int) (
+	[]models.ArchivedLink,
+	error,
+) {
+	a, ok := c.Store.(interface {
+		ArchiveCandidates(ctx context.Context, inactiveSince, createdBefore time.Time, limit int) (
+			[]models.ArchivedLink, error)
+	})
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return a.ArchiveCandidates(ctx, inactiveSince, createdBefore, limit)
+}
+
+// RemoveArchived удаляет перенесенные в архив ссылки из хранилища и кэша.
+func (c *Cache) RemoveArchived(ctx context.Context, links []models.ArchivedLink) ([]models.ArchivedLink, error) {
+	r, ok := c.Store.(interface {
+		RemoveArchived(ctx context.Context, links []models.ArchivedLink) ([]models.ArchivedLink, error)
+	})
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	removed, err := r.RemoveArchived(ctx, links)
+	for _, link := range removed {
+		c.Invalidate(link.Link())
+	}
+	return removed, err
+}
@@ -0,0 +1,128 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/cache"
+	"github.com/playmixer/short-link/internal/adapters/storage/database"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/storagetest"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// testBus передает в кэш события теста.
+type testBus struct {
+	inv chan database.Invalidator
+}
+
+func (b *testBus) Listen(ctx context.Context, inv database.Invalidator) {
+	inv.Flush()
+	b.inv <- inv
+	<-ctx.Done()
+}
+
+func createStore(t *testing.T) *memory.Store {
+	t.Helper()
+	ctx := context.Background()
+	s, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	for _, short := range []string{"a1", "a2", "a3"} {
+		_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://" + short + ".ru/"})
+		require.NoError(t, err)
+	}
+	return s
+}
+
+func TestCache_Get(t *testing.T) {
+	ctx := context.Background()
+	store := createStore(t)
+	c := cache.New(store, &cache.Config{Size: 2}, nil)
+	defer c.Close()
+
+	_, err := c.Get(ctx, "", "unknown")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	require.Equal(t, 0, c.Len())
+
+	link, err := c.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a1.ru/", link.OriginalURL)
+
+	// изменение в обход кэша не видно до инвалидации.
+	_, err = store.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a1", UserID: "1"}})
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	c.Invalidate(models.ShortLink{ShortURL: "a1"})
+	_, err = c.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	_, err = c.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+
+	// вытесняется давно не используемая ссылка.
+	_, err = c.Get(ctx, "", "a2")
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "", "a3")
+	require.NoError(t, err)
+	require.Equal(t, 2, c.Len())
+
+	_, err = c.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a2", UserID: "1"}})
+
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "", "a2")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+
+	require.NoError(t, c.HardDeleteURLs(ctx))
+	_, err = c.Get(ctx, "", "a2")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+}
+
+func TestCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	store := createStore(t)
+	c := cache.New(store, &cache.Config{Size: 10, TTL: time.Millisecond}, nil)
+	defer c.Close()
+
+	_, err := c.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	_, err = store.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a1", UserID: "1"}})
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 2)
+	_, err = c.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+}
+
+func TestCache_Bus(t *testing.T) {
+	ctx := context.Background()
+	store := createStore(t)
+	bus := &testBus{inv: make(chan database.Invalidator)}
+	c := cache.New(store, &cache.Config{Size: 10}, bus)
+	inv := <-bus.inv
+
+	for _, short := range []string{"a1", "a2"} {
+		_, err := c.Get(ctx, "", short)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, c.Len())
+
+	inv.Invalidate(models.ShortLink{ShortURL: "a1"})
+	require.Equal(t, 1, c.Len())
+	inv.Flush()
+	require.Equal(t, 0, c.Len())
+
+	// Close дожидается остановки подписки.
+	c.Close()
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		s, err := memory.New(&memory.Config{})
+		require.NoError(t, err)
+		return cache.New(s, &cache.Config{Size: 100}, nil)
+	})
+}
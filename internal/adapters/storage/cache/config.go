@@ -0,0 +1,9 @@
+package cache
+
+import "time"
+
+// Config настройка кэша переходов по ссылкам.
+type Config struct {
+	Size int           `env:"CACHE_SIZE"` // максимальное количество ссылок в кэше, 0 - кэш отключен.
+	TTL  time.Duration `env:"CACHE_TTL"`  // время жизни ссылки в кэше, по умолчанию 1m.
+}
@@ -0,0 +1,20 @@
+package crypt
+
+import "time"
+
+// Config настройка шифрования оригинальных ссылок. Шифрование отключено, если ключи не заданы.
+type Config struct {
+	// Keys ключи шифрования в виде id:base64, ключ длиной 16, 24 или 32 байта.
+	Keys []string `env:"ENCRYPTION_KEYS" envSeparator:","`
+	// ActiveKey идентификатор ключа для шифрования новых ссылок, по умолчанию последний ключ.
+	ActiveKey string `env:"ENCRYPTION_ACTIVE_KEY"`
+	// IndexKey ключ слепого индекса в base64, не меняется при ротации ключей шифрования.
+	IndexKey string `env:"ENCRYPTION_INDEX_KEY"`
+	// ReencryptInterval период перешифрования ссылок активным ключом, по умолчанию 1h, отрицательный - отключено.
+	ReencryptInterval time.Duration `env:"ENCRYPTION_REENCRYPT_INTERVAL"`
+}
+
+// Enabled - шифрование включено.
+func (c *Config) Enabled() bool {
+	return len(c.Keys) > 0
+}
@@ -0,0 +1,334 @@
+// Модуль crypt шифрует оригинальные ссылки в хранилище.
+//
+// Crypt оборачивает любое хранилище: оригинальная ссылка сохраняется зашифрованной AES-GCM
+// с идентификатором ключа, при чтении расшифровывается. Равенство ссылок проверяется по слепому индексу (HMAC),
+// который хранится рядом со ссылкой, поэтому повторное сокращение ссылки распознается и после смены ключа шифрования.
+package crypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/outbox"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// ErrNotWalkable - хранилище не поддерживает обход ссылок.
+var ErrNotWalkable = errors.New("storage does not support walking links")
+
+// Store - интерфейс шифруемого хранилища ссылок.
+type Store interface {
+	Get(ctx context.Context, domain, short string) (models.ShortLink, error)
+	GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error)
+	GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error)
+	Set(ctx context.Context, userID string, link models.ShortLink) (string, error)
+	SetBatch(ctx context.Context, userID string, batch []models.ShortLink) ([]models.ShortLink, error)
+	Ping(ctx context.Context) error
+	DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error)
+	GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error)
+	HardDeleteURLs(ctx context.Context) error
+	Close()
+}
+
+// Finder - хранилище, поддерживающее поиск ссылки, сохраненной до включения шифрования.
+type Finder interface {
+	// FindOriginal возвращает короткий код неудаленной ссылки пользователя в домене без слепого индекса,
+	// оригинальная ссылка которой равна original, или storeerror.ErrNotFoundKey.
+	FindOriginal(ctx context.Context, userID, domain, original string) (string, error)
+}
+
+// Rewriter - хранилище, поддерживающее замену оригинальной ссылки.
+type Rewriter interface {
+	// RewriteOriginalURL заменяет оригинальную ссылку на original и ее слепой индекс на hash,
+	// если оригинальная ссылка равна link.OriginalURL.
+	// Возвращает false, если ссылки нет или ее оригинальная ссылка изменилась.
+	// Для неудаленной ссылки возвращает storeerror.ErrNotUnique, если у пользователя есть другая ссылка с hash.
+	RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error)
+}
+
+// walker - хранилище, поддерживающее обход всех ссылок.
+type walker interface {
+	Walk(ctx context.Context, fn func(link models.ShortLink) error) error
+}
+
+// Crypt - хранилище с шифрованием оригинальных ссылок.
+type Crypt struct {
+	Store
+	keys *Keyring
+	log  *zap.Logger
+	// interval период перешифрования, 0 - перешифрование не запускается.
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       *sync.WaitGroup
+}
+
+// Option интерфейс опции Crypt.
+type Option func(*Crypt)
+
+// SetLogger устанавливает логер.
+func SetLogger(log *zap.Logger) Option {
+	return func(c *Crypt) {
+		c.log = log
+	}
+}
+
+// SetReencryptInterval включает перешифрование ссылок активным ключом с периодом interval.
+// Нулевой период заменяется периодом по умолчанию, отрицательный отключает перешифрование.
+func SetReencryptInterval(interval time.Duration) Option {
+	return func(c *Crypt) {
+		switch {
+		case interval == 0:
+			c.interval = defaultReencryptInterval
+		case interval > 0:
+			c.interval = interval
+		default:
+			c.interval = 0
+		}
+	}
+}
+
+// New создает Crypt.
+func New(store Store, keys *Keyring, options ...Option) *Crypt {
+	c := &Crypt{
+		Store:  store,
+		keys:   keys,
+		log:    zap.NewNop(),
+		cancel: func() {},
+		wg:     &sync.WaitGroup{},
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	if c.interval > 0 {
+		var ctx context.Context
+		ctx, c.cancel = context.WithCancel(context.Background())
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.runReencrypt(ctx)
+		}()
+	}
+	return c
+}
+
+// Close останавливает перешифрование и закрывает хранилище.
+func (c *Crypt) Close() {
+	c.cancel()
+	c.wg.Wait()
+	c.Store.Close()
+}
+
+// Get Возвращает ссылку с расшифрованной оригинальной ссылкой.
+func (c *Crypt) Get(ctx context.Context, domain, short string) (models.ShortLink, error) {
+	link, err := c.Store.Get(ctx, domain, short)
+	if link.OriginalURL == "" {
+		return link, err
+	}
+	plain, decErr := c.keys.Decrypt(link.OriginalURL)
+	if decErr != nil {
+		return models.ShortLink{}, fmt.Errorf("failed decrypt link `%s`: %w", short, decErr)
+	}
+	link.OriginalURL, link.OriginalHash = plain, ""
+	return link, err
+}
+
+// GetAllURL Возвращает все ссылки пользователя.
+func (c *Crypt) GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error) {
+	links, err := c.Store.GetAllURL(ctx, userID)
+	if err != nil {
+		return links, err
+	}
+	return c.decryptURLs(links)
+}
+
+// GetUserURLs Возвращает страницу ссылок пользователя.
+func (c *Crypt) GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error) {
+	links, err := c.Store.GetUserURLs(ctx, userID, page)
+	if err != nil {
+		return links, err
+	}
+	return c.decryptURLs(links)
+}
+
+func (c *Crypt) decryptURLs(links []models.ShortenURL) ([]models.ShortenURL, error) {
+	for i := range links {
+		plain, err := c.keys.Decrypt(links[i].OriginalURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed decrypt link `%s`: %w", links[i].ShortURL, err)
+		}
+		links[i].OriginalURL = plain
+	}
+	return links, nil
+}
+
+// Set Сохраняет ссылку, зашифровав оригинальную ссылку активным ключом.
+func (c *Crypt) Set(ctx context.Context, userID string, link models.ShortLink) (string, error) {
+	short, err := c.find(ctx, userID, link)
+	if err != nil {
+		return short, err
+	}
+	if link, err = c.encrypt(link); err != nil {
+		return "", err
+	}
+	return c.Store.Set(ctx, userID, link)
+}
+
+// SetBatch Сохраняет список ссылок, зашифровав оригинальные ссылки активным ключом.
+func (c *Crypt) SetBatch(ctx context.Context, userID string, batch []models.ShortLink) ([]models.ShortLink, error) {
+	encrypted := make([]models.ShortLink, 0, len(batch))
+	for _, link := range batch {
+		if short, err := c.find(ctx, userID, link); err != nil {
+			if errors.Is(err, storeerror.ErrNotUnique) {
+				return []models.ShortLink{{ShortURL: short, OriginalURL: link.OriginalURL, Domain: link.Domain}}, err
+			}
+			return []models.ShortLink{}, err
+		}
+		link, err := c.encrypt(link)
+		if err != nil {
+			return []models.ShortLink{}, err
+		}
+		encrypted = append(encrypted, link)
+	}
+	output, err := c.Store.SetBatch(ctx, userID, encrypted)
+	for i := range output {
+		if plain, decErr := c.keys.Decrypt(output[i].OriginalURL); decErr == nil {
+			output[i].OriginalURL, output[i].OriginalHash = plain, ""
+		}
+	}
+	return output, err
+}
+
+// encrypt шифрует оригинальную ссылку активным ключом и заполняет ее слепой индекс,
+// по которому хранилище проверяет уникальность ссылки.
+func (c *Crypt) encrypt(link models.ShortLink) (models.ShortLink, error) {
+	encrypted, err := c.keys.Encrypt(link.OriginalURL)
+	if err != nil {
+		return link, fmt.Errorf("failed encrypt url: %w", err)
+	}
+	link.OriginalURL, link.OriginalHash = encrypted, c.keys.Hash(link.OriginalURL)
+	return link, nil
+}
+
+// find ищет ссылку пользователя, сохраненную открытой до включения шифрования.
+// Зашифрованные ссылки хранилище сравнивает по слепому индексу при сохранении.
+func (c *Crypt) find(ctx context.Context, userID string, link models.ShortLink) (string, error) {
+	finder, ok := c.Store.(Finder)
+	if !ok {
+		return "", nil
+	}
+	short, err := finder.FindOriginal(ctx, userID, link.Domain, link.OriginalURL)
+	switch {
+	case err == nil:
+		return short, fmt.Errorf("url is not unique: %w", storeerror.ErrNotUnique)
+	case errors.Is(err, storeerror.ErrNotFoundKey), errors.Is(err, errors.ErrUnsupported):
+		return "", nil
+	default:
+		return "", fmt.Errorf("failed find original url: %w", err)
+	}
+}
+
+// Walk обходит все ссылки хранилища с расшифрованными оригинальными ссылками.
+func (c *Crypt) Walk(ctx context.Context, fn func(link models.ShortLink) error) error {
+	w, ok := c.Store.(walker)
+	if !ok {
+		return ErrNotWalkable
+	}
+	return w.Walk(ctx, func(link models.ShortLink) error {
+		plain, err := c.keys.Decrypt(link.OriginalURL)
+		if err != nil {
+			return fmt.Errorf("failed decrypt link `%s`: %w", link.ShortURL, err)
+		}
+		link.OriginalURL, link.OriginalHash = plain, ""
+		return fn(link)
+	})
+}
+
+// Snapshot обходит снимок ссылок хранилища с расшифрованными оригинальными ссылками.
+func (c *Crypt) Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error {
+	sn, ok := c.Store.(interface {
+		Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error
+	})
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return sn.Snapshot(ctx, func(link models.ShortLink) error {
+		plain, err := c.keys.Decrypt(link.OriginalURL)
+		if err != nil {
+			return fmt.Errorf("failed decrypt link `%s`: %w", link.ShortURL, err)
+		}
+		link.OriginalURL, link.OriginalHash = plain, ""
+		return fn(link)
+	})
+}
+
+// RelayOutbox передает события изменения ссылок хранилища с расшифрованными оригинальными ссылками.
+func (c *Crypt) RelayOutbox(ctx context.Context, limit int, fn func(events []models.LinkEvent) error) (int, error) {
+	source, ok := c.Store.(outbox.Source)
+	if !ok {
+		return 0, outbox.ErrNotSupported
+	}
+	return source.RelayOutbox(ctx, limit, func(events []models.LinkEvent) error {
+		for i := range events {
+			plain, err := c.keys.Decrypt(events[i].OriginalURL)
+			if err != nil {
+				return fmt.Errorf("failed decrypt event %d: %w", events[i].ID, err)
+			}
+			events[i].OriginalURL = plain
+		}
+		return fn(events)
+	})
+}
+
+// TouchLinks отмечает переходы по ссылкам в хранилище.
+func (c *Crypt) TouchLinks(ctx context.Context, links []models.ShortLink, at time.Time) error {
+	t, ok := c.Store.(interface {
+		TouchLinks(ctx context.Context, links []models.ShortLink, at time.Time) error
+	})
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return t.TouchLinks(ctx, links, at)
+}
+
+// ArchiveCandidates возвращает ссылки хранилища для переноса в архив.
+// Оригинальные ссылки не расшифровываются: архив хранит их зашифрованными, как и хранилище.
+func (c *Crypt) ArchiveCandidates(ctx context.Context, inactiveSince, createdBefore time.Time, limit int) (
+	[]models.ArchivedLink,
+	error,
+) {
+	a, ok := c.Store.(interface {
+		ArchiveCandidates(ctx context.Context, inactiveSince, createdBefore time.Time, limit int) (
+			[]models.ArchivedLink, error)
+	})
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return a.ArchiveCandidates(ctx, inactiveSince, createdBefore, limit)
+}
+
+// DecryptArchived расшифровывает оригинальную ссылку, полученную из архива.
+func (c *Crypt) DecryptArchived(link models.ArchivedLink) (models.ArchivedLink, error) {
+	plain, err := c.keys.Decrypt(link.OriginalURL)
+	if err != nil {
+		return models.ArchivedLink{}, fmt.Errorf("failed decrypt archived link `%s`: %w", link.ShortURL, err)
+	}
+	link.OriginalURL = plain
+	return link, nil
+}
+
+// RemoveArchived удаляет перенесенные в архив ссылки из хранилища.
+func (c *Crypt) RemoveArchived(ctx context.Context, links []models.ArchivedLink) ([]models.ArchivedLink, error) {
+	r, ok := c.Store.(interface {
+		RemoveArchived(ctx context.Context, links []models.ArchivedLink) ([]models.ArchivedLink, error)
+	})
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return r.RemoveArchived(ctx, links)
+}
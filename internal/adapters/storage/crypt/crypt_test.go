@@ -0,0 +1,258 @@
+package crypt_test
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/archive"
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/crypt"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/storagetest"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+func key(id string, b byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+var indexKey = base64.StdEncoding.EncodeToString([]byte("index-key-0123456789"))
+
+func keyring(t *testing.T, active string, keys ...string) *crypt.Keyring {
+	t.Helper()
+	k, err := crypt.NewKeyring(&crypt.Config{Keys: keys, ActiveKey: active, IndexKey: indexKey})
+	require.NoError(t, err)
+	return k
+}
+
+func newCrypt(t *testing.T, s crypt.Store, k *crypt.Keyring) *crypt.Crypt {
+	t.Helper()
+	return crypt.New(s, k, crypt.SetReencryptInterval(-1))
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  crypt.Config
+		ok   bool
+	}{
+		{"ok", crypt.Config{Keys: []string{key("k1", 'a')}, IndexKey: indexKey}, true},
+		{"no keys", crypt.Config{IndexKey: indexKey}, false},
+		{"invalid entry", crypt.Config{Keys: []string{"secret"}, IndexKey: indexKey}, false},
+		{"invalid base64", crypt.Config{Keys: []string{"k1:***"}, IndexKey: indexKey}, false},
+		{"invalid size", crypt.Config{Keys: []string{"k1:" + base64.StdEncoding.EncodeToString([]byte("short"))}, IndexKey: indexKey}, false},
+		{"duplicate", crypt.Config{Keys: []string{key("k1", 'a'), key("k1", 'b')}, IndexKey: indexKey}, false},
+		{"unknown active", crypt.Config{Keys: []string{key("k1", 'a')}, ActiveKey: "k2", IndexKey: indexKey}, false},
+		{"no index key", crypt.Config{Keys: []string{key("k1", 'a')}}, false},
+		{"short index key", crypt.Config{Keys: []string{key("k1", 'a')}, IndexKey: "c2hvcnQ="}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := crypt.NewKeyring(&tt.cfg)
+			if tt.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	k := keyring(t, "", key("k1", 'a'), key("k2", 'b'))
+	require.Equal(t, "k2", k.ActiveKey())
+
+	value, err := k.Encrypt("https://a.ru/?token=secret")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(value, "enc:v1:k2:"))
+	require.NotContains(t, value, "secret")
+	require.True(t, k.IsCurrent(value))
+
+	// шифртексты равных ссылок различаются, слепой индекс совпадает при любом ключе шифрования.
+	again, err := k.Encrypt("https://a.ru/?token=secret")
+	require.NoError(t, err)
+	require.NotEqual(t, value, again)
+	other := keyring(t, "", key("k3", 'c'))
+	require.Equal(t, k.Hash("https://a.ru/?token=secret"), other.Hash("https://a.ru/?token=secret"))
+	require.NotEqual(t, k.Hash("https://a.ru/?token=secret"), k.Hash("https://a.ru/?token=other"))
+	require.NotContains(t, k.Hash("https://a.ru/?token=secret"), "secret")
+
+	plain, err := k.Decrypt(value)
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/?token=secret", plain)
+
+	// ссылки, сохраненные до включения шифрования, не меняются.
+	plain, err = k.Decrypt("https://a.ru/")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/", plain)
+	require.False(t, k.IsCurrent("https://a.ru/"))
+
+	_, err = keyring(t, "", key("k3", 'c')).Decrypt(value)
+	require.ErrorIs(t, err, crypt.ErrUnknownKey)
+	_, err = k.Decrypt(value[:len(value)-2] + "AA")
+	require.Error(t, err)
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		s, err := memory.New(&memory.Config{})
+		require.NoError(t, err)
+		return newCrypt(t, s, keyring(t, "", key("k1", 'a')))
+	})
+}
+
+func TestCrypt_AtRest(t *testing.T) {
+	ctx := context.Background()
+	inner, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	c := newCrypt(t, inner, keyring(t, "", key("k1", 'a')))
+
+	_, err = c.Set(ctx, "1", models.ShortLink{ShortURL: "a1", OriginalURL: "https://a.ru/?token=secret"})
+	require.NoError(t, err)
+
+	stored, err := inner.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(stored.OriginalURL, "enc:v1:k1:"))
+	require.NotEmpty(t, stored.OriginalHash)
+
+	link, err := c.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/?token=secret", link.OriginalURL)
+	require.Empty(t, link.OriginalHash)
+	urls, err := c.GetAllURL(ctx, "1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/?token=secret", urls[0].OriginalURL)
+}
+
+func TestCrypt_DedupAfterRotation(t *testing.T) {
+	ctx := context.Background()
+	inner, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	_, err = inner.Set(ctx, "1", models.ShortLink{ShortURL: "plain", OriginalURL: "https://a.ru/0"})
+	require.NoError(t, err)
+
+	old := newCrypt(t, inner, keyring(t, "", key("k1", 'a')))
+	_, err = old.Set(ctx, "1", models.ShortLink{ShortURL: "a1", OriginalURL: "https://a.ru/1"})
+	require.NoError(t, err)
+
+	c := newCrypt(t, inner, keyring(t, "", key("k1", 'a'), key("k2", 'b')))
+	short, err := c.Set(ctx, "1", models.ShortLink{ShortURL: "b1", OriginalURL: "https://a.ru/1"})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "a1", short)
+
+	short, err = c.Set(ctx, "1", models.ShortLink{ShortURL: "b0", OriginalURL: "https://a.ru/0"})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "plain", short)
+
+	output, err := c.SetBatch(ctx, "1", []models.ShortLink{
+		{ShortURL: "b2", OriginalURL: "https://a.ru/2"},
+		{ShortURL: "b3", OriginalURL: "https://a.ru/1"},
+	})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, []models.ShortLink{{ShortURL: "a1", OriginalURL: "https://a.ru/1"}}, output)
+
+	// другой пользователь сокращает ту же ссылку.
+	_, err = c.Set(ctx, "2", models.ShortLink{ShortURL: "c1", OriginalURL: "https://a.ru/1"})
+	require.NoError(t, err)
+}
+
+func TestCrypt_Reencrypt(t *testing.T) {
+	ctx := context.Background()
+	inner, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	_, err = inner.Set(ctx, "1", models.ShortLink{ShortURL: "plain", OriginalURL: "https://a.ru/0"})
+	require.NoError(t, err)
+	old := newCrypt(t, inner, keyring(t, "", key("k1", 'a')))
+	for i, short := range []string{"a1", "a2", "a3"} {
+		_, err = old.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://a.ru/" + string(rune('1'+i))})
+		require.NoError(t, err)
+	}
+
+	k := keyring(t, "k2", key("k1", 'a'), key("k2", 'b'))
+	c := newCrypt(t, inner, k)
+	n, err := c.Reencrypt(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	for _, item := range inner.GetAll() {
+		require.True(t, k.IsCurrent(item.OriginalURL), item.ShortURL)
+	}
+	link, err := c.Get(ctx, "", "a2")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/2", link.OriginalURL)
+	link, err = c.Get(ctx, "", "plain")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/0", link.OriginalURL)
+
+	// после перешифрования старый ключ можно удалить из связки.
+	c = newCrypt(t, inner, keyring(t, "", key("k2", 'b')))
+	link, err = c.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/1", link.OriginalURL)
+
+	// перешифрованная открытая ссылка сравнивается по слепому индексу.
+	short, err := c.Set(ctx, "1", models.ShortLink{ShortURL: "b0", OriginalURL: "https://a.ru/0"})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "plain", short)
+
+	n, err = c.Reencrypt(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestCrypt_RelayOutbox(t *testing.T) {
+	ctx := context.Background()
+	inner, err := memory.New(&memory.Config{Outbox: true})
+	require.NoError(t, err)
+	c := newCrypt(t, inner, keyring(t, "", key("k1", 'a')))
+
+	_, err = c.Set(ctx, "1", models.ShortLink{ShortURL: "a1", OriginalURL: "https://a.ru/?token=secret"})
+	require.NoError(t, err)
+
+	events := []models.LinkEvent{}
+	_, err = c.RelayOutbox(ctx, 10, func(batch []models.LinkEvent) error {
+		events = append(events, batch...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "https://a.ru/?token=secret", events[0].OriginalURL)
+}
+
+func TestCrypt_Archive(t *testing.T) {
+	ctx := context.Background()
+	inner, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	c := newCrypt(t, inner, keyring(t, "", key("k1", 'a')))
+	_, err = c.Set(ctx, "1", models.ShortLink{ShortURL: "a1", OriginalURL: "https://a.ru/?token=secret"})
+	require.NoError(t, err)
+
+	a, err := archive.NewFiles(t.TempDir())
+	require.NoError(t, err)
+	n, err := archive.NewArchiver(c, a, archive.SetMaxAge(time.Nanosecond)).ArchiveOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	// архив хранит ссылку зашифрованной.
+	row, err := a.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(row.OriginalURL, "enc:v1:k1:"))
+	require.NotContains(t, row.OriginalURL, "secret")
+
+	// ссылка из архива расшифровывается и возвращается в хранилище зашифрованной.
+	link, err := archive.NewArchiver(c, a, archive.SetRehydrate(true)).Lookup(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/?token=secret", link.OriginalURL)
+	stored, err := inner.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(stored.OriginalURL, "enc:v1:k1:"))
+	link, err = c.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/?token=secret", link.OriginalURL)
+}
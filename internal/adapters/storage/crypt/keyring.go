@@ -0,0 +1,141 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix - признак зашифрованной ссылки и версия формата: enc:v1:<id ключа>:<base64(nonce || шифртекст)>.
+const prefix = "enc:v1:"
+
+const minIndexKeySize = 16 // минимальная длина ключа слепого индекса.
+
+// ErrUnknownKey - ссылка зашифрована ключом, которого нет в связке.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Keyring связка ключей шифрования.
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+	index  []byte
+}
+
+// NewKeyring создает связку ключей по конфигурации.
+func NewKeyring(cfg *Config) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(cfg.Keys))}
+	for _, v := range cfg.Keys {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(v), ":")
+		if !ok || id == "" {
+			return nil, errors.New("invalid encryption key, expected id:base64")
+		}
+		if _, exists := k.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate encryption key `%s`", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed decode encryption key `%s`: %w", id, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key `%s`: %w", id, err)
+		}
+		k.keys[id], err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed create cipher for key `%s`: %w", id, err)
+		}
+		k.active = id
+	}
+	if len(k.keys) == 0 {
+		return nil, errors.New("encryption keys are not set")
+	}
+	if cfg.ActiveKey != "" {
+		if _, ok := k.keys[cfg.ActiveKey]; !ok {
+			return nil, fmt.Errorf("active encryption key `%s` is not in keyring", cfg.ActiveKey)
+		}
+		k.active = cfg.ActiveKey
+	}
+
+	var err error
+	k.index, err = base64.StdEncoding.DecodeString(cfg.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed decode index key: %w", err)
+	}
+	if len(k.index) < minIndexKeySize {
+		return nil, fmt.Errorf("index key must be at least %d bytes", minIndexKeySize)
+	}
+	return k, nil
+}
+
+// ActiveKey возвращает идентификатор ключа шифрования новых ссылок.
+func (k *Keyring) ActiveKey() string {
+	return k.active
+}
+
+// BlindIndex возвращает слепой индекс ссылки - HMAC-SHA256 на ключе индекса.
+// Равные ссылки имеют равный индекс при любом ключе шифрования.
+func (k *Keyring) BlindIndex(plain string) []byte {
+	mac := hmac.New(sha256.New, k.index)
+	mac.Write([]byte(plain))
+	return mac.Sum(nil)
+}
+
+// Hash возвращает слепой индекс ссылки в виде строки для хранения рядом с зашифрованной ссылкой.
+func (k *Keyring) Hash(plain string) string {
+	return base64.RawURLEncoding.EncodeToString(k.BlindIndex(plain))
+}
+
+// Encrypt шифрует ссылку активным ключом со случайным nonce.
+// Шифртексты равных ссылок различаются, ссылки сравниваются по слепому индексу.
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	aead := k.keys[k.active]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(k.active))
+	return prefix + k.active + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает ссылку. Ссылки, сохраненные до включения шифрования, возвращаются без изменений.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	id, payload, ok := split(value)
+	if !ok {
+		return value, nil
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("key `%s`: %w", id, ErrUnknownKey)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted url with key `%s`", id)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed decrypt url with key `%s`: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// IsCurrent проверяет, что ссылка зашифрована активным ключом.
+func (k *Keyring) IsCurrent(value string) bool {
+	id, _, ok := split(value)
+	return ok && id == k.active
+}
+
+// split разбирает зашифрованную ссылку на идентификатор ключа и шифртекст.
+func split(value string) (id, payload string, ok bool) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
@@ -0,0 +1,116 @@
+package crypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+var (
+	defaultReencryptInterval = time.Hour // период перешифрования по умолчанию.
+	reencryptBatch           = 100       // количество ссылок, перешифровываемых после одного обхода.
+
+	errBatchFull = errors.New("batch is full")
+)
+
+// Reencrypt перешифровывает активным ключом ссылки, сохраненные открытыми или зашифрованные другими ключами,
+// и возвращает количество перешифрованных ссылок.
+// Ссылки собираются обходом хранилища порциями и заменяются после обхода, чтобы не держать чтение открытым во время записи.
+func (c *Crypt) Reencrypt(ctx context.Context) (int, error) {
+	rw, ok := c.Store.(Rewriter)
+	w, wok := c.Store.(walker)
+	if !ok || !wok {
+		return 0, fmt.Errorf("storage does not support re-encryption: %w", errors.ErrUnsupported)
+	}
+
+	var total, pos int
+	for {
+		batch, next, err := c.collect(ctx, w, pos)
+		if err != nil {
+			return total, err
+		}
+		for _, link := range batch {
+			plain, err := c.keys.Decrypt(link.OriginalURL)
+			if err != nil {
+				c.log.Warn("failed decrypt link for re-encryption",
+					zap.String("domain", link.Domain), zap.String("short", link.ShortURL), zap.Error(err))
+				continue
+			}
+			encrypted, err := c.keys.Encrypt(plain)
+			if err != nil {
+				return total, fmt.Errorf("failed encrypt link `%s`: %w", link.ShortURL, err)
+			}
+			rewritten, err := rw.RewriteOriginalURL(ctx, link, encrypted, c.keys.Hash(plain))
+			if errors.Is(err, storeerror.ErrNotUnique) {
+				c.log.Warn("link duplicates another link of user, skip re-encryption",
+					zap.String("domain", link.Domain), zap.String("short", link.ShortURL))
+				continue
+			}
+			if err != nil {
+				return total, fmt.Errorf("failed re-encrypt link `%s`: %w", link.ShortURL, err)
+			}
+			if rewritten {
+				total++
+			}
+		}
+		if len(batch) < reencryptBatch {
+			return total, nil
+		}
+		pos = next
+	}
+}
+
+// collect возвращает до reencryptBatch ссылок, не зашифрованных активным ключом, начиная с позиции обхода pos,
+// и позицию, с которой продолжить обход.
+func (c *Crypt) collect(ctx context.Context, w walker, pos int) ([]models.ShortLink, int, error) {
+	batch := make([]models.ShortLink, 0, reencryptBatch)
+	i := 0
+	err := w.Walk(ctx, func(link models.ShortLink) error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("re-encryption interrupted: %w", err)
+		}
+		i++
+		if i <= pos || c.keys.IsCurrent(link.OriginalURL) {
+			return nil
+		}
+		batch = append(batch, link)
+		if len(batch) == reencryptBatch {
+			return errBatchFull
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchFull) {
+		return nil, 0, fmt.Errorf("failed walk links: %w", err)
+	}
+	return batch, i, nil
+}
+
+// runReencrypt перешифровывает ссылки с периодом interval до отмены контекста.
+func (c *Crypt) runReencrypt(ctx context.Context) {
+	for {
+		n, err := c.Reencrypt(ctx)
+		switch {
+		case errors.Is(err, errors.ErrUnsupported):
+			c.log.Warn("storage does not support re-encryption of links")
+			return
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			c.log.Warn("failed re-encrypt links", zap.Error(err))
+		case n > 0:
+			c.log.Info("links re-encrypted", zap.Int("count", n), zap.String("key", c.keys.ActiveKey()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.interval):
+		}
+	}
+}
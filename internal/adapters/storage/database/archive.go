@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+// TouchLinks отмечает переходы по ссылкам в момент at.
+func (s *Store) TouchLinks(ctx context.Context, links []models.ShortLink, at time.Time) error {
+	if len(links) == 0 {
+		return nil
+	}
+	domains, shorts := linkKeys(links)
+	_, err := s.pool.Exec(ctx, `update short_link set accessed_at = $1
+where (domain, short_url) in (select * from unnest($2::varchar[], $3::varchar[]))
+and (accessed_at is null or accessed_at < $1)`,
+		at, domains, shorts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed touch links: %w", classify(err))
+	}
+	return nil
+}
+
+// ArchiveCandidates возвращает до limit неудаленных ссылок, по которым не переходили с inactiveSince
+// или созданных раньше createdBefore. Нулевое время отключает условие.
+func (s *Store) ArchiveCandidates(ctx context.Context, inactiveSince, createdBefore time.Time, limit int) (
+	[]models.ArchivedLink,
+	error,
+) {
+	if inactiveSince.IsZero() && createdBefore.IsZero() {
+		return []models.ArchivedLink{}, nil
+	}
+	rows, err := s.pool.Query(ctx,
+		`select short_url, original_url, user_id, domain, redirect_code, created_at, accessed_at from short_link
+where is_deleted = false and (
+	($1::timestamptz is not null and coalesce(accessed_at, created_at) < $1)
+	or ($2::timestamptz is not null and created_at < $2)
+)
+order by id limit $3`,
+		nullTime(inactiveSince), nullTime(createdBefore), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed select archive candidates: %w", classify(err))
+	}
+	links, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.ArchivedLink, error) {
+		var link models.ArchivedLink
+		var accessedAt *time.Time
+		err := row.Scan(&link.ShortURL, &link.OriginalURL, &link.UserID, &link.Domain, &link.RedirectCode,
+			&link.CreatedAt, &accessedAt)
+		if accessedAt != nil {
+			link.AccessedAt = *accessedAt
+		}
+		return link, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed read archive candidates: %w", classify(err))
+	}
+	return links, nil
+}
+
+// RemoveArchived удаляет перенесенные в архив ссылки, по которым не переходили после выбора, и возвращает удаленные.
+// Удаление рассылается экземплярам сервиса для инвалидации кэша.
+func (s *Store) RemoveArchived(ctx context.Context, links []models.ArchivedLink) ([]models.ArchivedLink, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	batch := &pgx.Batch{}
+	for _, link := range links {
+		batch.Queue(`delete from short_link
+where domain = $1 and short_url = $2 and user_id = $3 and is_deleted = false and accessed_at is not distinct from $4`,
+			link.Domain, link.ShortURL, link.UserID, nullTime(link.AccessedAt))
+	}
+	result := tx.SendBatch(ctx, batch)
+	removed := make([]models.ArchivedLink, 0, len(links))
+	for _, link := range links {
+		tag, err := result.Exec()
+		if err != nil {
+			_ = result.Close()
+			return nil, fmt.Errorf("failed remove archived link `%s`: %w", link.ShortURL, classify(err))
+		}
+		if tag.RowsAffected() > 0 {
+			removed = append(removed, link)
+		}
+	}
+	if err = result.Close(); err != nil {
+		return nil, fmt.Errorf("failed closing result batch: %w", classify(err))
+	}
+	invalidated := make([]models.ShortLink, 0, len(removed))
+	for _, link := range removed {
+		invalidated = append(invalidated, link.Link())
+	}
+	if err = notify(ctx, tx, invalidated); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed commit transaction: %w", classify(err))
+	}
+	return removed, nil
+}
+
+func linkKeys(links []models.ShortLink) (domains, shorts []string) {
+	domains = make([]string, 0, len(links))
+	shorts = make([]string, 0, len(links))
+	for _, link := range links {
+		domains = append(domains, link.Domain)
+		shorts = append(shorts, link.ShortURL)
+	}
+	return domains, shorts
+}
+
+// nullTime заменяет нулевое время на NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
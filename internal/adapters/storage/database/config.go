@@ -1,11 +1,25 @@
 package database
 
-import "go.uber.org/zap"
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
 
 // Config конфигурация подключени к базе данных.
 type Config struct {
 	log *zap.Logger
 	DSN string `env:"DATABASE_DSN"`
+	// SkipMigrations отключает применение миграций при запуске.
+	SkipMigrations bool `env:"DATABASE_SKIP_MIGRATIONS"`
+	// ReplicaDSNs реплики для чтения ссылок и статистики.
+	ReplicaDSNs []string `env:"DATABASE_REPLICA_DSNS" envSeparator:","`
+	// ReplicaMaxLag допустимое отставание реплики, отстающие реплики исключаются из чтения.
+	ReplicaMaxLag time.Duration `env:"DATABASE_REPLICA_MAX_LAG"`
+	// ReplicaCheckInterval периодичность проверки реплик.
+	ReplicaCheckInterval time.Duration `env:"DATABASE_REPLICA_CHECK_INTERVAL"`
+	// Outbox запись событий изменения ссылок в таблицу outbox в транзакции изменения.
+	Outbox bool
 }
 
 // SetLogger установить логгер.
@@ -2,9 +2,9 @@ package database
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
@@ -18,20 +18,25 @@ import (
 
 // Store имплементация коннекта с хранилищем бд.
 type Store struct {
-	pool *pgxpool.Pool
-	log  *zap.Logger
+	pool     *pgxpool.Pool
+	replicas *replicaSet
+	log      *zap.Logger
+	outbox   bool // запись событий изменения ссылок в outbox.
 }
 
 // New создает Store.
 func New(ctx context.Context, cfg *Config) (*Store, error) {
 	var err error
 
-	if err = runMigrations(cfg.DSN); err != nil {
-		return nil, fmt.Errorf("failed initialize tables: %w", err)
+	if !cfg.SkipMigrations {
+		if err = RunMigrations(cfg.DSN); err != nil {
+			return nil, fmt.Errorf("failed initialize tables: %w", err)
+		}
 	}
 
 	s := &Store{
-		log: cfg.log,
+		log:    cfg.log,
+		outbox: cfg.Outbox,
 	}
 	poolCfg, err := pgxpool.ParseConfig(cfg.DSN)
 	if err != nil {
@@ -41,65 +46,85 @@ func New(ctx context.Context, cfg *Config) (*Store, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed open database: %w", err)
 	}
+	if s.log == nil {
+		s.log = zap.NewNop()
+	}
+	s.replicas, err = newReplicaSet(ctx, cfg, s.log)
+	if err != nil {
+		s.pool.Close()
+		return nil, fmt.Errorf("failed initialize replicas: %w", err)
+	}
 
 	return s, nil
 }
 
 // Close - закрыть соединение.
 func (s *Store) Close() {
+	s.replicas.close()
 	s.pool.Close()
 }
 
 // Set Сохраняет ссылку.
-func (s *Store) Set(ctx context.Context, userID, short, original string) (output string, err error) {
+func (s *Store) Set(ctx context.Context, userID string, link models.ShortLink) (output string, err error) {
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed begin transaction: %w", err)
+		return "", fmt.Errorf("failed begin transaction: %w", classify(err))
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
-	output, err = s.getByOriginal(ctx, tx, userID, original)
+	output, err = s.getByOriginal(ctx, tx, userID, link.Domain, link.OriginalURL, link.OriginalHash)
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		return output, fmt.Errorf("failed select URL %s %w", original, err)
+		return output, fmt.Errorf("failed select URL %s %w", link.OriginalURL, err)
 	}
 	if output != "" {
-		return output, fmt.Errorf("url `%s` is not unique: %w", original, storeerror.ErrNotUnique)
+		return output, fmt.Errorf("url `%s` is not unique: %w", link.OriginalURL, storeerror.ErrNotUnique)
 	}
 
 	_, err = tx.Exec(
 		ctx,
-		"insert into short_link (short_url, original_url, user_id) values ($1, $2, $3)",
-		short, original, userID,
+		`insert into short_link (short_url, original_url, original_hash, user_id, domain, redirect_code, created_at)
+values ($1, $2, nullif($3, ''), $4, $5, $6, coalesce($7, now()))`,
+		link.ShortURL, link.OriginalURL, link.OriginalHash, userID, link.Domain, link.RedirectCode, createdAt(link),
 	)
 	if err != nil {
 		var sqlError *pgconn.PgError
 		if errors.As(err, &sqlError) && pgerrcode.UniqueViolation == sqlError.Code {
-			return output, fmt.Errorf("pgerror: %w: %w", storeerror.ErrNotUnique, err)
+			return output, fmt.Errorf("pgerror: %w: %w", storeerror.ErrDuplicateShortURL, err)
 		}
-		return output, fmt.Errorf("failed setting short url: %w", err)
+		return output, fmt.Errorf("failed setting short url: %w", classify(err))
+	}
+	err = s.writeOutbox(ctx, tx, []models.LinkEvent{models.NewLinkEvent(models.LinkEventCreated, userID, link)})
+	if err != nil {
+		return "", err
 	}
 	err = tx.Commit(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed committing transaction: %w", err)
+		return "", fmt.Errorf("failed committing transaction: %w", classify(err))
 	}
-	return short, nil
+	return link.ShortURL, nil
 }
 
-// Get Возвращает оригинальную ссылку.
-func (s *Store) Get(ctx context.Context, short string) (string, error) {
-	row := s.pool.QueryRow(ctx,
-		"select original_url, is_deleted from short_link where short_url = $1",
-		short,
-	)
-	var value string
-	var isDeleted bool
-	err := row.Scan(&value, &isDeleted)
+// Get Возвращает ссылку.
+func (s *Store) Get(ctx context.Context, domain, short string) (models.ShortLink, error) {
+	link := models.ShortLink{ShortURL: short, Domain: domain}
+	err := s.read(ctx, func(pool *pgxpool.Pool) error {
+		row := pool.QueryRow(ctx,
+			`select original_url, coalesce(original_hash, ''), user_id, redirect_code, is_deleted, created_at
+from short_link where domain = $1 and short_url = $2`,
+			domain, short,
+		)
+		return row.Scan(&link.OriginalURL, &link.OriginalHash, &link.UserID, &link.RedirectCode, &link.IsDeleted,
+			&link.CreatedAt)
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed scan url: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ShortLink{}, storeerror.ErrNotFoundKey
+		}
+		return models.ShortLink{}, fmt.Errorf("failed scan url: %w", classify(err))
 	}
-	if isDeleted {
-		return short, storeerror.ErrShortURLDeleted
+	if link.IsDeleted {
+		return link, storeerror.ErrShortURLDeleted
 	}
-	return value, nil
+	return link, nil
 }
 
 // SetBatch Сохраняет список ссылок.
@@ -109,11 +134,23 @@ func (s *Store) SetBatch(ctx context.Context, userID string, data []models.Short
 ) {
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		return []models.ShortLink{}, fmt.Errorf("failed begin transaction: %w", err)
+		return []models.ShortLink{}, fmt.Errorf("failed begin transaction: %w", classify(err))
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
+	type originalKey struct{ domain, original, hash string }
+	originals := make(map[originalKey]string, len(data))
 	for _, d := range data {
-		short, err := s.getByOriginal(ctx, tx, userID, d.OriginalURL)
+		key := originalKey{d.Domain, d.OriginalURL, d.OriginalHash}
+		if d.OriginalHash != "" {
+			key.original = ""
+		}
+		if short, ok := originals[key]; ok {
+			return []models.ShortLink{{ShortURL: short, OriginalURL: d.OriginalURL, Domain: d.Domain}},
+				fmt.Errorf("URL `%s` is not unique: %w", d.OriginalURL, storeerror.ErrNotUnique)
+		}
+		originals[key] = d.ShortURL
+
+		short, err := s.getByOriginal(ctx, tx, userID, d.Domain, d.OriginalURL, d.OriginalHash)
 		if err != nil && errors.Is(err, pgx.ErrNoRows) {
 			continue
 		}
@@ -121,20 +158,26 @@ func (s *Store) SetBatch(ctx context.Context, userID string, data []models.Short
 			return []models.ShortLink{}, fmt.Errorf("failed getting URL `%s`: %w", d.OriginalURL, err)
 		}
 		if short != "" {
-			return []models.ShortLink{{ShortURL: short, OriginalURL: d.OriginalURL}},
+			return []models.ShortLink{{ShortURL: short, OriginalURL: d.OriginalURL, Domain: d.Domain}},
 				fmt.Errorf("URL `%s` is not unique: %w", d.OriginalURL, storeerror.ErrNotUnique)
 		}
 	}
 
 	output = make([]models.ShortLink, 0)
-	sqlString := "insert into short_link (short_url, original_url, user_id) values (@short, @original, @user_id)"
+	sqlString := `insert into short_link
+	(short_url, original_url, original_hash, user_id, domain, redirect_code, created_at)
+values (@short, @original, nullif(@hash, ''), @user_id, @domain, @redirect_code, coalesce(@created_at, now()))`
 	batch := &pgx.Batch{}
 
 	for _, v := range data {
 		args := pgx.NamedArgs{
-			"short":    v.ShortURL,
-			"original": v.OriginalURL,
-			"user_id":  userID,
+			"short":         v.ShortURL,
+			"original":      v.OriginalURL,
+			"hash":          v.OriginalHash,
+			"user_id":       userID,
+			"domain":        v.Domain,
+			"redirect_code": v.RedirectCode,
+			"created_at":    createdAt(v),
 		}
 		batch.Queue(sqlString, args)
 	}
@@ -143,30 +186,48 @@ func (s *Store) SetBatch(ctx context.Context, userID string, data []models.Short
 	for _, v := range data {
 		_, err := result.Exec()
 		if err != nil {
+			_ = result.Close()
+			var sqlError *pgconn.PgError
+			if errors.As(err, &sqlError) && pgerrcode.UniqueViolation == sqlError.Code {
+				return []models.ShortLink{}, fmt.Errorf("pgerror: %w: %w", storeerror.ErrDuplicateShortURL, err)
+			}
 			return []models.ShortLink{}, fmt.Errorf("failed insert URL %s: %w", v.OriginalURL, err)
 		}
 		output = append(output, v)
 	}
 	err = result.Close()
 	if err != nil {
-		return []models.ShortLink{}, fmt.Errorf("failed closing result batch: %w", err)
+		return []models.ShortLink{}, fmt.Errorf("failed closing result batch: %w", classify(err))
+	}
+	events := make([]models.LinkEvent, 0, len(output))
+	for _, v := range output {
+		events = append(events, models.NewLinkEvent(models.LinkEventCreated, userID, v))
+	}
+	if err = s.writeOutbox(ctx, tx, events); err != nil {
+		return []models.ShortLink{}, err
 	}
 	err = tx.Commit(ctx)
 	if err != nil {
-		return []models.ShortLink{}, fmt.Errorf("failed commit transaction: %w", err)
+		return []models.ShortLink{}, fmt.Errorf("failed commit transaction: %w", classify(err))
 	}
 	return output, nil
 }
 
-func (s *Store) getByOriginal(ctx context.Context, tx pgx.Tx, userID, original string) (string, error) {
-	row := tx.QueryRow(ctx,
-		"select short_url from short_link where original_url =$1 and user_id = $2 and is_deleted = false",
-		original, userID,
-	)
+// getByOriginal возвращает короткий код неудаленной ссылки пользователя: со слепым индексом hash,
+// если он задан, иначе без слепого индекса с оригинальной ссылкой original.
+func (s *Store) getByOriginal(ctx context.Context, tx pgx.Tx, userID, domain, original, hash string) (string, error) {
+	query := `select short_url from short_link
+where original_url = $1 and original_hash is null and user_id = $2 and domain = $3 and is_deleted = false`
+	if hash != "" {
+		query = `select short_url from short_link
+where original_hash = $1 and user_id = $2 and domain = $3 and is_deleted = false`
+		original = hash
+	}
+	row := tx.QueryRow(ctx, query, original, userID, domain)
 	var value string
 	err := row.Scan(&value)
 	if err != nil {
-		return "", fmt.Errorf("failed scan url %w", err)
+		return "", fmt.Errorf("failed scan url %w", classify(err))
 	}
 	return value, nil
 }
@@ -175,7 +236,7 @@ func (s *Store) getByOriginal(ctx context.Context, tx pgx.Tx, userID, original s
 func (s *Store) Ping(ctx context.Context) error {
 	err := s.pool.Ping(ctx)
 	if err != nil {
-		return fmt.Errorf("failed ping database: %w", err)
+		return fmt.Errorf("failed ping database: %w", classify(err))
 	}
 
 	return nil
@@ -184,85 +245,321 @@ func (s *Store) Ping(ctx context.Context) error {
 // GetAllURL Возвращает все ссылки пользователя.
 func (s *Store) GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error) {
 	result := []models.ShortenURL{}
-	rows, err := s.pool.Query(ctx,
-		"select short_url, original_url from short_link where user_id = $1 and is_deleted = false", userID)
-	if err != nil {
-		return result, fmt.Errorf("failed selecting all URLs by user: %w", err)
-	}
-	for rows.Next() {
-		value := models.ShortenURL{}
-		err := rows.Scan(&value.ShortURL, &value.OriginalURL)
+	err := s.read(ctx, func(pool *pgxpool.Pool) error {
+		rows, err := pool.Query(ctx,
+			"select short_url, original_url, domain from short_link where user_id = $1 and is_deleted = false order by id", userID)
+		if err != nil {
+			return fmt.Errorf("failed selecting all URLs by user: %w", classify(err))
+		}
+		defer rows.Close()
+		result = result[:0]
+		for rows.Next() {
+			value := models.ShortenURL{}
+			err := rows.Scan(&value.ShortURL, &value.OriginalURL, &value.Domain)
+			if err != nil {
+				return fmt.Errorf("failed scan url %w", classify(err))
+			}
+			result = append(result, value)
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed read URLs by user: %w", classify(err))
+		}
+		return nil
+	})
+	return result, err
+}
+
+// GetUserURLs Возвращает страницу ссылок пользователя.
+func (s *Store) GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error) {
+	result := []models.ShortenURL{}
+	err := s.read(ctx, func(pool *pgxpool.Pool) error {
+		rows, err := pool.Query(ctx,
+			`select id, short_url, original_url, domain from short_link
+where user_id = $1 and is_deleted = false and id > $2 order by id limit $3`,
+			userID, page.After, page.Limit)
 		if err != nil {
-			return result, fmt.Errorf("failed scan url %w", err)
+			return fmt.Errorf("failed selecting URLs by user: %w", classify(err))
+		}
+		defer rows.Close()
+		result = result[:0]
+		for rows.Next() {
+			value := models.ShortenURL{}
+			err := rows.Scan(&value.ID, &value.ShortURL, &value.OriginalURL, &value.Domain)
+			if err != nil {
+				return fmt.Errorf("failed scan url %w", classify(err))
+			}
+			result = append(result, value)
 		}
-		result = append(result, value)
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed read URLs by user: %w", classify(err))
+		}
+		return nil
+	})
+	return result, err
+}
+
+// createdAt возвращает известное время создания ссылки, nil - время создания задает база данных.
+func createdAt(link models.ShortLink) *time.Time {
+	if link.CreatedAt.IsZero() {
+		return nil
 	}
-	return result, nil
+	return &link.CreatedAt
 }
 
 // DeleteShortURLs Мягкое удаляет ссылки.
-func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) error {
+func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
 	sqlString := `update short_link set is_deleted = true 
-where user_id = @user_id and short_url = @short_url and is_deleted = false`
+where user_id = @user_id and domain = @domain and short_url = @short_url and is_deleted = false
+returning original_url`
 	batch := &pgx.Batch{}
 
 	for _, v := range shorts {
 		args := pgx.NamedArgs{
 			"short_url": v.ShortURL,
 			"user_id":   v.UserID,
+			"domain":    v.Domain,
 		}
 		batch.Queue(sqlString, args)
 	}
 
-	result := s.pool.SendBatch(ctx, batch)
-	defer func() {
-		err := result.Close()
-		if err != nil {
-			s.log.Debug("error closing result batch", zap.Error(err))
-		}
-	}()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
+	result := tx.SendBatch(ctx, batch)
+	deleted := make([]models.ShortLink, 0, len(shorts))
+	events := make([]models.LinkEvent, 0, len(shorts))
 	for _, v := range shorts {
-		_, err := result.Exec()
+		link := v
+		err := result.QueryRow().Scan(&link.OriginalURL)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("failed deleting short url `%s`: %w", v.ShortURL, err)
+			_ = result.Close()
+			return nil, fmt.Errorf("failed deleting short url `%s`: %w", v.ShortURL, classify(err))
 		}
+		deleted = append(deleted, v)
+		events = append(events, models.NewLinkEvent(models.LinkEventDeleted, link.UserID, link))
 	}
-	return nil
+	if err = result.Close(); err != nil {
+		return nil, fmt.Errorf("failed closing result batch: %w", classify(err))
+	}
+	if err = notify(ctx, tx, deleted); err != nil {
+		return nil, err
+	}
+	if err = s.writeOutbox(ctx, tx, events); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed commit transaction: %w", classify(err))
+	}
+	return deleted, nil
 }
 
 // HardDeleteURLs Хард удаление ссылок.
 func (s *Store) HardDeleteURLs(ctx context.Context) error {
-	sqlString := `delete from short_link where is_deleted = true`
-	_, err := s.pool.Exec(ctx, sqlString)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `delete from short_link where is_deleted = true returning domain, short_url`)
 	if err != nil {
-		return fmt.Errorf("failed hard deleting URLs: %w", err)
+		return fmt.Errorf("failed hard deleting URLs: %w", classify(err))
+	}
+	deleted, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.ShortLink, error) {
+		var link models.ShortLink
+		err := row.Scan(&link.Domain, &link.ShortURL)
+		return link, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed hard deleting URLs: %w", classify(err))
+	}
+	if err = notify(ctx, tx, deleted); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed commit transaction: %w", classify(err))
 	}
 	return nil
 }
 
 // GetState Получение статисики.
-func (s *Store) GetState(ctx context.Context) (urls int, users int, err error) {
-	var userCount int
-	var urlCount int
-	rows, err := s.pool.Query(ctx,
-		"select sl.user_id, count(*) from short_link sl where is_deleted = false group by sl.user_id",
-	)
+func (s *Store) GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error) {
+	var stats models.ShortenStats
+	err := s.read(ctx, func(pool *pgxpool.Pool) error {
+		stats = models.ShortenStats{CreatedPerDay: []models.DailyCount{}, TopUsers: []models.UserCount{}}
+		err := pool.QueryRow(ctx,
+			`select count(*) filter (where not is_deleted), count(distinct user_id) filter (where not is_deleted),
+count(*) filter (where is_deleted), pg_total_relation_size('short_link')
+from short_link`,
+		).Scan(&stats.URLs, &stats.Users, &stats.Deleted, &stats.StorageBytes)
+		if err != nil {
+			return fmt.Errorf("failed get stats: %w", classify(err))
+		}
+
+		// окно выбирается по частичному индексу short_link_created_at_idx.
+		rows, err := pool.Query(ctx,
+			`select to_char(created_at at time zone 'UTC', 'YYYY-MM-DD') as day, count(*)
+from short_link where is_deleted = false and created_at >= $1 group by day order by day`,
+			query.Since,
+		)
+		if err != nil {
+			return fmt.Errorf("failed get links per day: %w", classify(err))
+		}
+		stats.CreatedPerDay, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.DailyCount, error) {
+			var day models.DailyCount
+			err := row.Scan(&day.Day, &day.Count)
+			return day, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed scan links per day: %w", classify(err))
+		}
+
+		if query.Top <= 0 {
+			return nil
+		}
+		rows, err = pool.Query(ctx,
+			`select user_id, count(*) as urls from short_link where is_deleted = false
+group by user_id order by urls desc, user_id limit $1`,
+			query.Top,
+		)
+		if err != nil {
+			return fmt.Errorf("failed get top users: %w", classify(err))
+		}
+		stats.TopUsers, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.UserCount, error) {
+			var user models.UserCount
+			err := row.Scan(&user.UserID, &user.URLs)
+			return user, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed scan top users: %w", classify(err))
+		}
+		return nil
+	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return 0, 0, nil
+		return models.ShortenStats{}, err
+	}
+	return stats, nil
+}
+
+// FindOriginal возвращает короткий код неудаленной ссылки пользователя без слепого индекса
+// с оригинальной ссылкой original.
+func (s *Store) FindOriginal(ctx context.Context, userID, domain, original string) (string, error) {
+	var short string
+	err := s.pool.QueryRow(ctx,
+		`select short_url from short_link
+where user_id = $1 and domain = $2 and is_deleted = false and original_url = $3 and original_hash is null limit 1`,
+		userID, domain, original,
+	).Scan(&short)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", storeerror.ErrNotFoundKey
 		}
-		return 0, 0, fmt.Errorf("failed get stats: %w", err)
+		return "", fmt.Errorf("failed find original: %w", classify(err))
+	}
+	return short, nil
+}
+
+// RewriteOriginalURL заменяет оригинальную ссылку и ее слепой индекс, если ссылка не изменилась с момента чтения link.
+// Замена рассылается экземплярам сервиса для инвалидации кэша.
+func (s *Store) RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error) {
+	if link.OriginalURL == original {
+		return false, nil
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var userID string
+	var isDeleted bool
+	err = tx.QueryRow(ctx,
+		`select user_id, is_deleted from short_link
+where domain = $1 and short_url = $2 and original_url = $3 for update`,
+		link.Domain, link.ShortURL, link.OriginalURL,
+	).Scan(&userID, &isDeleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
 	}
+	if err != nil {
+		return false, fmt.Errorf("failed select link: %w", classify(err))
+	}
+	if !isDeleted {
+		short, err := s.getByOriginal(ctx, tx, userID, link.Domain, original, hash)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return false, err
+		}
+		if short != "" && short != link.ShortURL {
+			return false, fmt.Errorf("rewrite short url `%s`: %w", link.ShortURL, storeerror.ErrNotUnique)
+		}
+	}
+	_, err = tx.Exec(ctx,
+		`update short_link set original_url = $1, original_hash = nullif($2, '') where domain = $3 and short_url = $4`,
+		original, hash, link.Domain, link.ShortURL,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed rewrite original url: %w", classify(err))
+	}
+	if err = notify(ctx, tx, []models.ShortLink{link}); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed commit transaction: %w", classify(err))
+	}
+	return true, nil
+}
+
+// Walk обходит все ссылки в порядке добавления.
+func (s *Store) Walk(ctx context.Context, fn func(link models.ShortLink) error) error {
+	return walk(ctx, s.pool, fn)
+}
+
+// Snapshot обходит все ссылки в транзакции REPEATABLE READ, которая видит состояние базы на момент начала.
+func (s *Store) Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed begin snapshot: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err = walk(ctx, tx, fn); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed commit snapshot: %w", classify(err))
+	}
+	return nil
+}
+
+// walk обходит ссылки запросом q в порядке добавления.
+func walk(ctx context.Context, q interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}, fn func(link models.ShortLink) error) error {
+	rows, err := q.Query(ctx,
+		`select short_url, original_url, coalesce(original_hash, ''), user_id, domain, redirect_code, is_deleted,
+	created_at from short_link order by id`)
+	if err != nil {
+		return fmt.Errorf("failed selecting links: %w", classify(err))
+	}
+	defer rows.Close()
 	for rows.Next() {
-		var count int
-		err := rows.Scan(nil, &count)
+		var link models.ShortLink
+		err := rows.Scan(&link.ShortURL, &link.OriginalURL, &link.OriginalHash, &link.UserID, &link.Domain,
+			&link.RedirectCode, &link.IsDeleted, &link.CreatedAt)
 		if err != nil {
-			return 0, 0, fmt.Errorf("failed scan url %w", err)
+			return fmt.Errorf("failed scan link: %w", classify(err))
+		}
+		if err = fn(link); err != nil {
+			return err
 		}
-		userCount++
-		urlCount += count
 	}
-
-	return urlCount, userCount, nil
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed read links: %w", classify(err))
+	}
+	return nil
 }
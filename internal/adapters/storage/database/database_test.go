@@ -0,0 +1,84 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/database"
+	"github.com/playmixer/short-link/internal/adapters/storage/storagetest"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+func createStore(t *testing.T, cfg *database.Config) *database.Store {
+	t.Helper()
+	ctx := context.Background()
+	s, err := database.New(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	conn, err := pgx.Connect(ctx, cfg.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close(ctx) }()
+	if _, err = conn.Exec(ctx, "truncate short_link, outbox restart identity"); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func testDSN(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	return dsn
+}
+
+// TestConformance запускается на тестовой базе из TEST_DATABASE_DSN, данные таблиц short_link и outbox удаляются.
+func TestConformance(t *testing.T) {
+	dsn := testDSN(t)
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return createStore(t, &database.Config{DSN: dsn})
+	})
+}
+
+func TestStore_Outbox(t *testing.T) {
+	ctx := context.Background()
+	s := createStore(t, &database.Config{DSN: testDSN(t), Outbox: true})
+
+	_, err := s.Set(ctx, "1", models.ShortLink{ShortURL: "a", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+	_, err = s.SetBatch(ctx, "1", []models.ShortLink{{ShortURL: "b", OriginalURL: "https://github.com/"}})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a", UserID: "1"}, {ShortURL: "c", UserID: "1"}})
+	require.NoError(t, err)
+
+	errSink := errors.New("sink failed")
+	_, err = s.RelayOutbox(ctx, 10, func(events []models.LinkEvent) error { return errSink })
+	require.ErrorIs(t, err, errSink)
+
+	types := []string{}
+	n, err := s.RelayOutbox(ctx, 10, func(events []models.LinkEvent) error {
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{models.LinkEventCreated, models.LinkEventDeleted}, types)
+
+	n, err = s.RelayOutbox(ctx, 10, func(events []models.LinkEvent) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/puddle/v2"
+
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// classify переводит ошибки соединения с Postgres в ошибки хранилища.
+func classify(err error) error {
+	if err == nil || storeerror.KindOf(err) != storeerror.KindUnknown {
+		return err
+	}
+	var (
+		pgErr      *pgconn.PgError
+		connectErr *pgconn.ConnectError
+	)
+	switch {
+	case pgconn.Timeout(err):
+		return fmt.Errorf("%w: %w", storeerror.ErrTimeout, err)
+	case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.QueryCanceled:
+		return fmt.Errorf("%w: %w", storeerror.ErrTimeout, err)
+	case errors.As(err, &pgErr) && (pgerrcode.IsConnectionException(pgErr.Code) ||
+		pgerrcode.IsOperatorIntervention(pgErr.Code) ||
+		pgErr.Code == pgerrcode.TooManyConnections ||
+		pgErr.Code == pgerrcode.ReadOnlySQLTransaction):
+		return fmt.Errorf("%w: %w", storeerror.ErrUnavailable, err)
+	case errors.As(err, &connectErr), errors.Is(err, puddle.ErrClosedPool):
+		return fmt.Errorf("%w: %w", storeerror.ErrUnavailable, err)
+	}
+	return storeerror.Classify(err)
+}
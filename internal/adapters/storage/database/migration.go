@@ -3,33 +3,83 @@ package database
 import (
 	"errors"
 	"fmt"
-	"os"
-	"path"
-	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/mattes/migrate/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+
+	"github.com/playmixer/short-link/migrations"
 )
 
-func runMigrations(dsn string) error {
-	curPath, err := os.Getwd()
+// Migrator управляет версией схемы базы данных по встроенным миграциям.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// NewMigrator создает Migrator.
+func NewMigrator(dsn string) (*Migrator, error) {
+	source, err := iofs.New(migrations.FS, ".")
 	if err != nil {
-		return fmt.Errorf("failed getting current path: %w", err)
+		return nil, fmt.Errorf("failed open migrations: %w", err)
 	}
-	migrationDir := strings.ReplaceAll(path.Join(curPath, "migrations"), string(os.PathSeparator), "/")
-	sourceURL := "file://" + migrationDir
-	m, err := migrate.New(sourceURL, dsn)
+	m, err := migrate.NewWithSourceInstance("iofs", source, dsn)
 	if err != nil {
-		return fmt.Errorf("failed to get a new migrate instance: %w", err)
+		return nil, fmt.Errorf("failed to get a new migrate instance: %w", err)
 	}
+	return &Migrator{m: m}, nil
+}
+
+// Close - закрыть соединение.
+func (m *Migrator) Close() {
+	_, _ = m.m.Close()
+}
 
-	if err := m.Up(); err != nil {
-		if !errors.Is(err, migrate.ErrNoChange) {
-			return fmt.Errorf("failed apply migrations to DB: %w", err)
-		}
+// Up применяет все новые миграции.
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed apply migrations to DB: %w", err)
 	}
+	return nil
+}
 
+// Down откатывает steps последних миграций.
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps %d", steps)
+	}
+	if err := m.m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed rollback migrations: %w", err)
+	}
 	return nil
 }
+
+// Status возвращает текущую версию схемы и признак незавершенной миграции.
+// Для пустой базы возвращается версия 0.
+func (m *Migrator) Status() (version uint, dirty bool, err error) {
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed get migration version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Force устанавливает версию схемы без применения миграций, снимая признак незавершенной миграции.
+func (m *Migrator) Force(version int) error {
+	if err := m.m.Force(version); err != nil {
+		return fmt.Errorf("failed force migration version: %w", err)
+	}
+	return nil
+}
+
+// RunMigrations применяет миграции к базе данных.
+func RunMigrations(dsn string) error {
+	m, err := NewMigrator(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return m.Up()
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+// InvalidateChannel - канал NOTIFY с ключами измененных ссылок.
+const InvalidateChannel = "short_link_invalidate"
+
+var (
+	maxNotifyPayload    = 7000             // размер сообщения NOTIFY, Postgres ограничивает его 8000 байт.
+	listenRetryDelay    = time.Second      // начальная задержка переподключения.
+	listenRetryMaxDelay = time.Second * 30 // максимальная задержка переподключения.
+)
+
+// invalidateKey - ключ ссылки в сообщении инвалидации.
+type invalidateKey struct {
+	Domain string `json:"d,omitempty"`
+	Short  string `json:"s"`
+}
+
+// encodeInvalidation разбивает ключи ссылок на сообщения NOTIFY допустимого размера.
+func encodeInvalidation(links []models.ShortLink) ([]string, error) {
+	payloads := []string{}
+	keys := []invalidateKey{}
+	size := 0
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		b, err := json.Marshal(keys)
+		if err != nil {
+			return fmt.Errorf("failed marshal invalidation: %w", err)
+		}
+		payloads = append(payloads, string(b))
+		keys, size = keys[:0], 0
+		return nil
+	}
+	for _, link := range links {
+		// ключ с разделителями и кавычками.
+		n := len(link.Domain) + len(link.ShortURL) + 16
+		if size+n > maxNotifyPayload {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		keys = append(keys, invalidateKey{Domain: link.Domain, Short: link.ShortURL})
+		size += n
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return payloads, nil
+}
+
+func decodeInvalidation(payload string) ([]models.ShortLink, error) {
+	keys := []invalidateKey{}
+	if err := json.Unmarshal([]byte(payload), &keys); err != nil {
+		return nil, fmt.Errorf("failed unmarshal invalidation: %w", err)
+	}
+	links := make([]models.ShortLink, 0, len(keys))
+	for _, k := range keys {
+		links = append(links, models.ShortLink{Domain: k.Domain, ShortURL: k.Short})
+	}
+	return links, nil
+}
+
+// notify публикует ключи измененных ссылок, в транзакции сообщения доставляются после фиксации.
+func notify(ctx context.Context, tx pgx.Tx, links []models.ShortLink) error {
+	payloads, err := encodeInvalidation(links)
+	if err != nil {
+		return err
+	}
+	for _, p := range payloads {
+		if _, err = tx.Exec(ctx, "select pg_notify($1, $2)", InvalidateChannel, p); err != nil {
+			return fmt.Errorf("failed notify invalidation: %w", err)
+		}
+	}
+	return nil
+}
+
+// Invalidator - получатель событий инвалидации.
+type Invalidator interface {
+	// Invalidate удаляет ссылки из кэша.
+	Invalidate(links ...models.ShortLink)
+	// Flush очищает кэш.
+	Flush()
+}
+
+// Bus - шина инвалидации кэшей экземпляров сервиса через LISTEN/NOTIFY.
+type Bus struct {
+	log *zap.Logger
+	dsn string
+}
+
+// NewBus создает Bus.
+func NewBus(dsn string, log *zap.Logger) *Bus {
+	return &Bus{dsn: dsn, log: log}
+}
+
+// Listen доставляет события инвалидации до отмены контекста.
+// После каждого подключения кэш очищается полностью: события, отправленные без подписки, теряются.
+func (b *Bus) Listen(ctx context.Context, inv Invalidator) {
+	delay := listenRetryDelay
+	for {
+		err := b.listen(ctx, inv, func() { delay = listenRetryDelay })
+		if ctx.Err() != nil {
+			return
+		}
+		b.log.Warn("invalidation listener disconnected", zap.Error(err), zap.Duration("retry", delay))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, listenRetryMaxDelay)
+	}
+}
+
+func (b *Bus) listen(ctx context.Context, inv Invalidator, connected func()) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return fmt.Errorf("failed connect: %w", err)
+	}
+	defer func() { _ = conn.Close(context.Background()) }()
+	if _, err = conn.Exec(ctx, "listen "+pgx.Identifier{InvalidateChannel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed listen: %w", err)
+	}
+	inv.Flush()
+	connected()
+	b.log.Info("invalidation listener connected")
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed wait notification: %w", err)
+		}
+		links, err := decodeInvalidation(n.Payload)
+		if err != nil {
+			b.log.Error("invalid invalidation payload, flush cache", zap.Error(err))
+			inv.Flush()
+			continue
+		}
+		inv.Invalidate(links...)
+	}
+}
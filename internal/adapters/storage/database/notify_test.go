@@ -0,0 +1,34 @@
+package database
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+func TestInvalidationPayload(t *testing.T) {
+	links := []models.ShortLink{}
+	for i := range 1000 {
+		links = append(links, models.ShortLink{Domain: "go.brand.com", ShortURL: "short" + strconv.Itoa(i)})
+	}
+
+	payloads, err := encodeInvalidation(links)
+	require.NoError(t, err)
+	require.Greater(t, len(payloads), 1)
+
+	got := []models.ShortLink{}
+	for _, p := range payloads {
+		require.LessOrEqual(t, len(p), maxNotifyPayload)
+		decoded, err := decodeInvalidation(p)
+		require.NoError(t, err)
+		got = append(got, decoded...)
+	}
+	require.Equal(t, links, got)
+
+	payloads, err = encodeInvalidation(nil)
+	require.NoError(t, err)
+	require.Empty(t, payloads)
+}
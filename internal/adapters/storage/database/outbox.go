@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+// writeOutbox записывает события изменения ссылок в транзакции изменения.
+func (s *Store) writeOutbox(ctx context.Context, tx pgx.Tx, events []models.LinkEvent) error {
+	if !s.outbox || len(events) == 0 {
+		return nil
+	}
+	var types, users, domains, shorts, originals []string
+	for _, e := range events {
+		types = append(types, e.Type)
+		users = append(users, e.UserID)
+		domains = append(domains, e.Domain)
+		shorts = append(shorts, e.ShortURL)
+		originals = append(originals, e.OriginalURL)
+	}
+	_, err := tx.Exec(ctx, `insert into outbox (event_type, user_id, domain, short_url, original_url)
+select * from unnest($1::varchar[], $2::varchar[], $3::varchar[], $4::varchar[], $5::varchar[])`,
+		types, users, domains, shorts, originals,
+	)
+	if err != nil {
+		return fmt.Errorf("failed write outbox: %w", classify(err))
+	}
+	return nil
+}
+
+// RelayOutbox передает fn неотправленные события и отмечает их отправленными в одной транзакции.
+// Выбранные строки блокируются, поэтому экземпляры сервиса отправляют разные события.
+func (s *Store) RelayOutbox(ctx context.Context, limit int, fn func(events []models.LinkEvent) error) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `select id, created_at, event_type, user_id, domain, short_url, original_url
+from outbox where sent_at is null order by id limit $1 for update skip locked`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed select outbox: %w", classify(err))
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.LinkEvent, error) {
+		var e models.LinkEvent
+		err := row.Scan(&e.ID, &e.CreatedAt, &e.Type, &e.UserID, &e.Domain, &e.ShortURL, &e.OriginalURL)
+		return e, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed read outbox: %w", classify(err))
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err = fn(events); err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	if _, err = tx.Exec(ctx, "update outbox set sent_at = now() where id = any($1)", ids); err != nil {
+		return 0, fmt.Errorf("failed mark outbox sent: %w", classify(err))
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed commit transaction: %w", classify(err))
+	}
+	return len(events), nil
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	defaultReplicaMaxLag        = time.Second * 5 // допустимое отставание реплики.
+	defaultReplicaCheckInterval = time.Second * 5 // периодичность проверки реплик.
+)
+
+// lagQuery - отставание реплики в секундах. Реплика, воспроизведшая весь полученный журнал, не отстает,
+// даже если основная база давно не менялась.
+const lagQuery = `select case
+	when pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() then 0
+	else coalesce(extract(epoch from now() - pg_last_xact_replay_timestamp()), 0)
+end`
+
+// replica - реплика для чтения.
+type replica struct {
+	pool    *pgxpool.Pool
+	host    string
+	healthy atomic.Bool
+}
+
+// replicaSet распределяет чтения по исправным репликам по кругу.
+type replicaSet struct {
+	log      *zap.Logger
+	replicas []*replica
+	next     atomic.Uint64
+	maxLag   time.Duration
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newReplicaSet(ctx context.Context, cfg *Config, log *zap.Logger) (*replicaSet, error) {
+	rs := &replicaSet{
+		log:    log,
+		maxLag: cfg.ReplicaMaxLag,
+		done:   make(chan struct{}),
+	}
+	if rs.maxLag <= 0 {
+		rs.maxLag = defaultReplicaMaxLag
+	}
+	for _, dsn := range cfg.ReplicaDSNs {
+		poolCfg, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			rs.close()
+			return nil, fmt.Errorf("failed parse replica config: %w", err)
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+		if err != nil {
+			rs.close()
+			return nil, fmt.Errorf("failed open replica: %w", err)
+		}
+		rs.replicas = append(rs.replicas, &replica{pool: pool, host: poolCfg.ConnConfig.Host})
+	}
+	if len(rs.replicas) == 0 {
+		return rs, nil
+	}
+
+	rs.check(ctx)
+	interval := cfg.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = defaultReplicaCheckInterval
+	}
+	rs.wg.Add(1)
+	go func() {
+		defer rs.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rs.done:
+				return
+			case <-ticker.C:
+				rs.check(context.Background())
+			}
+		}
+	}()
+	return rs, nil
+}
+
+// check проверяет доступность и отставание реплик.
+func (rs *replicaSet) check(ctx context.Context) {
+	for _, r := range rs.replicas {
+		ctxCheck, cancel := context.WithTimeout(ctx, rs.maxLag)
+		var lag float64
+		err := r.pool.QueryRow(ctxCheck, lagQuery).Scan(&lag)
+		cancel()
+		switch {
+		case err != nil:
+			rs.setHealthy(r, false, zap.Error(err))
+		case time.Duration(lag*float64(time.Second)) > rs.maxLag:
+			rs.setHealthy(r, false, zap.Float64("lag_seconds", lag))
+		default:
+			rs.setHealthy(r, true)
+		}
+	}
+}
+
+func (rs *replicaSet) setHealthy(r *replica, healthy bool, fields ...zap.Field) {
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	fields = append(fields, zap.String("host", r.host))
+	if healthy {
+		rs.log.Info("replica is available for reads", fields...)
+		return
+	}
+	rs.log.Warn("replica is excluded from reads", fields...)
+}
+
+// pick возвращает следующую исправную реплику, nil - исправных реплик нет.
+func (rs *replicaSet) pick() *replica {
+	n := uint64(len(rs.replicas))
+	if n == 0 {
+		return nil
+	}
+	start := rs.next.Add(1)
+	for i := range n {
+		r := rs.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+func (rs *replicaSet) close() {
+	close(rs.done)
+	rs.wg.Wait()
+	for _, r := range rs.replicas {
+		r.pool.Close()
+	}
+}
+
+// read выполняет чтение на реплике, при ошибке реплики или отсутствии строки - на основной базе.
+// Отсутствие строки на реплике может означать, что запись еще не дошла до нее.
+func (s *Store) read(ctx context.Context, fn func(pool *pgxpool.Pool) error) error {
+	if r := s.replicas.pick(); r != nil {
+		err := fn(r.pool)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.replicas.setHealthy(r, false, zap.Error(err))
+		}
+	}
+	return fn(s.pool)
+}
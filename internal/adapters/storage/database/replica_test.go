@@ -0,0 +1,35 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReplicaSet_pick(t *testing.T) {
+	rs := &replicaSet{log: zap.NewNop()}
+	require.Nil(t, rs.pick())
+
+	a, b, c := &replica{host: "a"}, &replica{host: "b"}, &replica{host: "c"}
+	rs.replicas = []*replica{a, b, c}
+	require.Nil(t, rs.pick(), "no healthy replicas")
+
+	for _, r := range rs.replicas {
+		rs.setHealthy(r, true)
+	}
+	picked := map[string]int{}
+	for range 6 {
+		picked[rs.pick().host]++
+	}
+	require.Equal(t, map[string]int{"a": 2, "b": 2, "c": 2}, picked)
+
+	rs.setHealthy(b, false)
+	for range 4 {
+		require.NotEqual(t, "b", rs.pick().host)
+	}
+
+	rs.setHealthy(a, false)
+	rs.setHealthy(c, false)
+	require.Nil(t, rs.pick())
+}
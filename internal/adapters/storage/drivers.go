@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/storage/bolt"
+	"github.com/playmixer/short-link/internal/adapters/storage/cache"
+	"github.com/playmixer/short-link/internal/adapters/storage/database"
+	"github.com/playmixer/short-link/internal/adapters/storage/file"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/redis"
+	"github.com/playmixer/short-link/internal/adapters/storage/sqlite"
+)
+
+func init() {
+	Register("memory", openMemory)
+	Register("file", openFile)
+	Register("bolt", openBolt)
+	Register("sqlite", openSQLite)
+	Register("postgres", openPostgres)
+	Register("postgresql", openPostgres)
+	Register("redis", openRedis)
+	Register("rediss", openRedis)
+}
+
+// openMemory открывает хранилище в памяти: memory://.
+func openMemory(ctx context.Context, dsn string, cfg *Config, log *zap.Logger) (Store, error) {
+	if _, _, err := parsePathDSN(dsn); err != nil {
+		return nil, err
+	}
+	memCfg := memory.Config{}
+	if cfg.Memory != nil {
+		memCfg = *cfg.Memory
+	}
+	store, err := memory.New(&memCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed create memory storage: %w", err)
+	}
+	log.Info("memory storage initialized")
+	return store, nil
+}
+
+// openFile открывает файловое хранилище: file:///path?sync=interval&sync_interval=1s&compact_interval=1h.
+func openFile(ctx context.Context, dsn string, cfg *Config, log *zap.Logger) (Store, error) {
+	path, q, err := parsePathDSN(dsn, "sync", "sync_interval", "compact_interval")
+	if err != nil {
+		return nil, err
+	}
+	fileCfg := file.Config{}
+	if cfg.File != nil {
+		fileCfg = *cfg.File
+	}
+	fileCfg.StoragePath = path
+	if q.Has("sync") {
+		fileCfg.SyncPolicy = q.Get("sync")
+	}
+	if fileCfg.SyncInterval, err = queryDuration(q, "sync_interval", fileCfg.SyncInterval); err != nil {
+		return nil, err
+	}
+	if fileCfg.CompactInterval, err = queryDuration(q, "compact_interval", fileCfg.CompactInterval); err != nil {
+		return nil, err
+	}
+	store, err := file.New(&fileCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed create file storage: %w", err)
+	}
+	log.Info("file storage initialized")
+	return store, nil
+}
+
+// openBolt открывает хранилище bbolt: bolt:///path.
+func openBolt(ctx context.Context, dsn string, cfg *Config, log *zap.Logger) (Store, error) {
+	path, _, err := parsePathDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	store, err := bolt.New(&bolt.Config{Path: path})
+	if err != nil {
+		return nil, fmt.Errorf("failed create bolt storage: %w", err)
+	}
+	log.Info("bolt storage initialized")
+	return store, nil
+}
+
+// openSQLite открывает хранилище SQLite: sqlite:///path.
+func openSQLite(ctx context.Context, dsn string, cfg *Config, log *zap.Logger) (Store, error) {
+	store, err := sqlite.New(ctx, &sqlite.Config{DSN: dsn})
+	if err != nil {
+		return nil, fmt.Errorf("failed create sqlite storage: %w", err)
+	}
+	log.Info("sqlite storage initialized")
+	return store, nil
+}
+
+// openPostgres открывает хранилище PostgreSQL.
+// Параметры хранилища skip_migrations, replica, replica_max_lag, replica_check_interval,
+// cache_size и cache_ttl удаляются из DSN, остальные параметры передаются драйверу базы.
+func openPostgres(ctx context.Context, dsn string, cfg *Config, log *zap.Logger) (Store, error) {
+	dbCfg := database.Config{}
+	if cfg.Database != nil {
+		dbCfg = *cfg.Database
+	}
+	cacheCfg := cache.Config{}
+	if cfg.Cache != nil {
+		cacheCfg = *cfg.Cache
+	}
+	if err := parsePostgresDSN(dsn, &dbCfg, &cacheCfg); err != nil {
+		return nil, err
+	}
+	dbCfg.SetLogger(log)
+	store, err := database.New(ctx, &dbCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed create database storage: %w", err)
+	}
+	log.Info("database storage initialized")
+	if cacheCfg.Size > 0 {
+		log.Info("redirect cache enabled", zap.Int("size", cacheCfg.Size))
+		return cache.New(store, &cacheCfg, database.NewBus(dbCfg.DSN, log)), nil
+	}
+	return store, nil
+}
+
+// parsePostgresDSN переносит параметры хранилища из DSN в конфигурацию.
+// DSN в формате key=value передается драйверу без изменений.
+func parsePostgresDSN(dsn string, dbCfg *database.Config, cacheCfg *cache.Config) error {
+	dbCfg.DSN = dsn
+	if !strings.Contains(dsn, "://") {
+		return nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return fmt.Errorf("invalid database dsn: %w", err)
+	}
+	q := u.Query()
+	if dbCfg.SkipMigrations, err = queryBool(q, "skip_migrations", dbCfg.SkipMigrations); err != nil {
+		return err
+	}
+	if q.Has("replica") {
+		dbCfg.ReplicaDSNs = q["replica"]
+	}
+	if dbCfg.ReplicaMaxLag, err = queryDuration(q, "replica_max_lag", dbCfg.ReplicaMaxLag); err != nil {
+		return err
+	}
+	if dbCfg.ReplicaCheckInterval, err = queryDuration(q, "replica_check_interval", dbCfg.ReplicaCheckInterval); err != nil {
+		return err
+	}
+	if cacheCfg.Size, err = queryInt(q, "cache_size", cacheCfg.Size); err != nil {
+		return err
+	}
+	if cacheCfg.TTL, err = queryDuration(q, "cache_ttl", cacheCfg.TTL); err != nil {
+		return err
+	}
+	for _, key := range []string{
+		"skip_migrations", "replica", "replica_max_lag", "replica_check_interval", "cache_size", "cache_ttl",
+	} {
+		q.Del(key)
+	}
+	u.RawQuery = q.Encode()
+	dbCfg.DSN = u.String()
+	return nil
+}
+
+// openRedis открывает хранилище Redis: redis://host:port/db?prefix=short:.
+// Параметр prefix удаляется из DSN, остальные параметры передаются клиенту Redis.
+func openRedis(ctx context.Context, dsn string, cfg *Config, log *zap.Logger) (Store, error) {
+	redisCfg := redis.Config{}
+	if cfg.Redis != nil {
+		redisCfg = *cfg.Redis
+	}
+	redisCfg.URL = dsn
+	if u, err := url.Parse(dsn); err == nil && u.RawQuery != "" {
+		q := u.Query()
+		if q.Has("prefix") {
+			redisCfg.Prefix = q.Get("prefix")
+			q.Del("prefix")
+			u.RawQuery = q.Encode()
+			redisCfg.URL = u.String()
+		}
+	}
+	store, err := redis.New(ctx, &redisCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed create redis storage: %w", err)
+	}
+	log.Info("redis storage initialized")
+	return store, nil
+}
+
+// parsePathDSN возвращает путь и параметры DSN вида scheme:///abs/path, scheme://rel/path или scheme:rel/path.
+// Параметры, не входящие в allowed, считаются ошибкой.
+func parsePathDSN(dsn string, allowed ...string) (string, url.Values, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid storage dsn: %w", err)
+	}
+	q := u.Query()
+	for key := range q {
+		if !slices.Contains(allowed, key) {
+			return "", nil, fmt.Errorf("unknown %s storage option `%s`", u.Scheme, key)
+		}
+	}
+	if u.Opaque != "" {
+		path, err := url.PathUnescape(u.Opaque)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid storage path: %w", err)
+		}
+		return path, q, nil
+	}
+	return u.Host + u.Path, q, nil
+}
+
+// pathDSN формирует DSN хранилища с путем к файлу.
+func pathDSN(scheme, path string) string {
+	return (&url.URL{Scheme: scheme, Path: path}).String()
+}
+
+func queryDuration(q url.Values, key string, def time.Duration) (time.Duration, error) {
+	if !q.Has(key) {
+		return def, nil
+	}
+	d, err := time.ParseDuration(q.Get(key))
+	if err != nil {
+		return 0, fmt.Errorf("invalid storage option `%s`: %w", key, err)
+	}
+	return d, nil
+}
+
+func queryInt(q url.Values, key string, def int) (int, error) {
+	if !q.Has(key) {
+		return def, nil
+	}
+	v, err := strconv.Atoi(q.Get(key))
+	if err != nil {
+		return 0, fmt.Errorf("invalid storage option `%s`: %w", key, err)
+	}
+	return v, nil
+}
+
+func queryBool(q url.Values, key string, def bool) (bool, error) {
+	if !q.Has(key) {
+		return def, nil
+	}
+	if q.Get(key) == "" {
+		return true, nil
+	}
+	v, err := strconv.ParseBool(q.Get(key))
+	if err != nil {
+		return false, fmt.Errorf("invalid storage option `%s`: %w", key, err)
+	}
+	return v, nil
+}
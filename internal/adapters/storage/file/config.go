@@ -1,6 +1,20 @@
 package file
 
+import "time"
+
+// Политики сброса журнала на диск.
+const (
+	SyncAlways   = "always"   // fsync после каждой записи.
+	SyncInterval = "interval" // fsync с периодом SyncInterval.
+	SyncNone     = "none"     // сброс на диск остается операционной системе.
+)
+
 // Config настройка файлового хранилища.
 type Config struct {
-	StoragePath string `env:"FILE_STORAGE_PATH"`
+	StoragePath     string        `env:"FILE_STORAGE_PATH"`
+	SyncPolicy      string        `env:"FILE_STORAGE_SYNC"`             // политика fsync, по умолчанию always.
+	SyncInterval    time.Duration `env:"FILE_STORAGE_SYNC_INTERVAL"`    // период fsync для политики interval.
+	CompactInterval time.Duration `env:"FILE_STORAGE_COMPACT_INTERVAL"` // период сжатия журнала.
+	// Outbox запись событий изменения ссылок в памяти процесса для публикации.
+	Outbox bool
 }
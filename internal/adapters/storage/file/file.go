@@ -1,25 +1,38 @@
 package file
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/playmixer/short-link/internal/adapters/models"
 	"github.com/playmixer/short-link/internal/adapters/storage/memory"
-	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+var (
+	defaultSyncInterval    = time.Second
+	defaultCompactInterval = time.Hour
 )
 
 // Store имлементация файлового хранилища.
+// Данные хранятся в памяти, файл является журналом операций (write-ahead log):
+// каждое изменение дописывается в конец файла, при запуске журнал воспроизводится.
+// Журнал периодически сжимается до снимка текущего состояния.
 type Store struct {
 	*memory.Store
-	filepath string
+	mu              *sync.Mutex
+	f               *os.File
+	filepath        string
+	syncPolicy      string
+	syncInterval    time.Duration
+	compactInterval time.Duration
+	records         int
+	dirty           bool
+	done            chan struct{}
+	wg              *sync.WaitGroup
 }
 
 // New создает Store.
@@ -29,175 +42,307 @@ func New(cfg *Config) (*Store, error) {
 		return nil, fmt.Errorf("can`t initialize memory storage: %w", err)
 	}
 	s := &Store{
-		Store:    m,
-		filepath: cfg.StoragePath,
+		Store:           m,
+		mu:              &sync.Mutex{},
+		filepath:        cfg.StoragePath,
+		syncPolicy:      cfg.SyncPolicy,
+		syncInterval:    cfg.SyncInterval,
+		compactInterval: cfg.CompactInterval,
+		done:            make(chan struct{}),
+		wg:              &sync.WaitGroup{},
 	}
-	err = s.uploadFromFile()
-	if err != nil {
+	switch s.syncPolicy {
+	case "":
+		s.syncPolicy = SyncAlways
+	case SyncAlways, SyncInterval, SyncNone:
+	default:
+		return nil, fmt.Errorf("unknown sync policy `%s`", cfg.SyncPolicy)
+	}
+	if s.syncInterval <= 0 {
+		s.syncInterval = defaultSyncInterval
+	}
+	if s.compactInterval <= 0 {
+		s.compactInterval = defaultCompactInterval
+	}
+
+	if s.filepath == "" {
+		if cfg.Outbox {
+			m.EnableOutbox()
+		}
+		return s, nil
+	}
+	if err = s.recover(); err != nil {
 		return nil, fmt.Errorf("failed upload from file: %w", err)
 	}
+	// журнал не хранит события, восстановление ссылок из журнала событий не порождает.
+	if cfg.Outbox {
+		m.EnableOutbox()
+	}
+	if err = s.reopen(); err != nil {
+		return nil, err
+	}
+	if err = s.compactIfNeeded(); err != nil {
+		return nil, fmt.Errorf("failed compact file storage: %w", err)
+	}
+
+	if s.syncPolicy == SyncInterval {
+		s.wg.Add(1)
+		go s.every(s.syncInterval, s.sync)
+	}
+	s.wg.Add(1)
+	go s.every(s.compactInterval, s.compactIfNeeded)
 
 	return s, nil
 }
 
+// every выполняет фоновую задачу под mu с заданным периодом до закрытия хранилища.
+func (s *Store) every(period time.Duration, task func() error) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			_ = task()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// compactIfNeeded сжимает журнал, если в нем есть записи кроме текущего состояния.
+func (s *Store) compactIfNeeded() error {
+	if s.records <= len(s.GetAll()) {
+		return nil
+	}
+	return s.compact()
+}
+
 // Close - закрыть соединение.
-func (s *Store) Close() {}
+func (s *Store) Close() {
+	if s.filepath == "" {
+		return
+	}
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.sync()
+	if s.f != nil {
+		_ = s.f.Close()
+	}
+}
 
 // Set Сохраняет ссылку.
-func (s *Store) Set(ctx context.Context, userID, key, value string) (string, error) {
-	shortURL, err := s.Store.Set(ctx, userID, key, value)
+func (s *Store) Set(ctx context.Context, userID string, link models.ShortLink) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shortURL, err := s.Store.Set(ctx, userID, link)
 	if err != nil {
 		return shortURL, fmt.Errorf("failed setting data: %w", err)
 	}
 
 	if s.filepath != "" {
-		f, err := os.OpenFile(s.filepath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.ModePerm)
+		err = s.append(record{
+			Op:           opSet,
+			ID:           strconv.Itoa(time.Now().UTC().Nanosecond()),
+			UserID:       userID,
+			ShortURL:     shortURL,
+			OriginalURL:  link.OriginalURL,
+			OriginalHash: link.OriginalHash,
+			Domain:       link.Domain,
+			RedirectCode: link.RedirectCode,
+			CreatedAt:    link.CreationTime(time.Now()).Unix(),
+		})
 		if err != nil {
-			s.Store.RemoveShortURL(ctx, userID, shortURL)
-			return "", fmt.Errorf("failed open file: %w", err)
-		}
-		defer func() { _ = f.Close() }()
-		item := memory.StoreItem{
-			ID:          strconv.Itoa(time.Now().UTC().Nanosecond()),
-			UserID:      userID,
-			ShortURL:    shortURL,
-			OriginalURL: value,
-			IsDeleted:   false,
-		}
-		b, err := json.Marshal(item)
-		if err != nil {
-			s.Store.RemoveShortURL(ctx, userID, shortURL)
-			return "", fmt.Errorf("failed marshal storage item: %w", err)
-		}
-		_, err = f.WriteString(string(b) + "\n")
-		if err != nil {
-			s.Store.RemoveShortURL(ctx, userID, shortURL)
-			return "", fmt.Errorf("failed write to file storage: %w", err)
+			s.Store.RemoveShortURL(ctx, userID, link.Domain, shortURL)
+			return "", err
 		}
 	}
 
 	return shortURL, nil
 }
 
-// SetBatch Сохраняет список ссылок.
+// SetBatch Сохраняет список ссылок атомарно.
 func (s *Store) SetBatch(ctx context.Context, userID string, batch []models.ShortLink) (
 	output []models.ShortLink,
 	err error,
 ) {
-	for _, b := range batch {
-		if _, err := s.Store.GetByUser(ctx, userID, b.ShortURL); err == nil {
-			return []models.ShortLink{}, storeerror.ErrDuplicateShortURL
-		}
-		if shortURL, err := s.Store.GetByOriginal(ctx, userID, b.OriginalURL); err == nil {
-			return []models.ShortLink{{ShortURL: shortURL, OriginalURL: b.OriginalURL}}, storeerror.ErrNotUnique
-		}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	output, err = s.Store.SetBatch(ctx, userID, batch)
+	if err != nil {
+		return output, fmt.Errorf("failed save data: %w", err)
 	}
 
-	for _, req := range batch {
-		_, err := s.Set(ctx, userID, req.ShortURL, req.OriginalURL)
-		if err != nil {
-			return []models.ShortLink{}, fmt.Errorf("failed save data: %w", err)
+	if s.filepath != "" {
+		id := strconv.Itoa(time.Now().UTC().Nanosecond())
+		now := time.Now()
+		records := make([]record, 0, len(output))
+		for _, link := range output {
+			records = append(records, record{
+				Op:           opSet,
+				ID:           id,
+				UserID:       userID,
+				ShortURL:     link.ShortURL,
+				OriginalURL:  link.OriginalURL,
+				OriginalHash: link.OriginalHash,
+				Domain:       link.Domain,
+				RedirectCode: link.RedirectCode,
+				CreatedAt:    link.CreationTime(now).Unix(),
+			})
+		}
+		if err = s.append(records...); err != nil {
+			for _, link := range output {
+				s.Store.RemoveShortURL(ctx, userID, link.Domain, link.ShortURL)
+			}
+			return []models.ShortLink{}, err
 		}
-		output = append(output, req)
 	}
 	return output, nil
 }
 
 // DeleteShortURLs Мягкое удаляет ссылки.
-func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) error {
-	err := s.Store.DeleteShortURLs(ctx, shorts)
-	if err != nil {
-		return fmt.Errorf("failed deleting shorts: %w", err)
+func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.filepath != "" {
+		records := make([]record, 0, len(shorts))
+		for _, short := range shorts {
+			records = append(records, record{
+				Op:       opDelete,
+				UserID:   short.UserID,
+				ShortURL: short.ShortURL,
+				Domain:   short.Domain,
+			})
+		}
+		if err := s.append(records...); err != nil {
+			return nil, fmt.Errorf("failed log deleting shorts: %w", err)
+		}
 	}
-	err = s.reWriteStore()
+	deleted, err := s.Store.DeleteShortURLs(ctx, shorts)
 	if err != nil {
-		return fmt.Errorf("failed rewrite storage in file: %w", err)
+		return nil, fmt.Errorf("failed deleting shorts: %w", err)
 	}
 
-	return nil
+	return deleted, nil
 }
 
-func (s *Store) reWriteStore() error {
-	f, err := os.OpenFile(s.filepath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
-	if err != nil {
-		return fmt.Errorf("failed opend file: %w", err)
-	}
-	defer func() { _ = f.Close() }()
-	for _, v := range s.GetAll() {
-		item := memory.StoreItem{
-			ID:          v.ID,
-			UserID:      v.UserID,
-			ShortURL:    v.ShortURL,
-			OriginalURL: v.OriginalURL,
-			IsDeleted:   v.IsDeleted,
-		}
-		line, err := json.Marshal(item)
-		if err != nil {
-			return fmt.Errorf("failed marshal data: %w", err)
-		}
-		_, err = f.WriteString(string(line) + "\n")
+// RewriteOriginalURL заменяет оригинальную ссылку и записывает замену в журнал.
+func (s *Store) RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok, err := s.Store.RewriteOriginalURL(ctx, link, original, hash)
+	if err != nil || !ok {
+		return ok, err
+	}
+	if s.filepath != "" {
+		err = s.append(record{
+			Op:           opRewrite,
+			ShortURL:     link.ShortURL,
+			OriginalURL:  original,
+			OriginalHash: hash,
+			Domain:       link.Domain,
+			PrevURL:      link.OriginalURL,
+		})
 		if err != nil {
-			return fmt.Errorf("failed write to file: %w", err)
+			rewritten := models.ShortLink{ShortURL: link.ShortURL, Domain: link.Domain, OriginalURL: original}
+			_, _ = s.Store.RewriteOriginalURL(ctx, rewritten, link.OriginalURL, link.OriginalHash)
+			return false, err
 		}
 	}
+	return true, nil
+}
 
-	return nil
+// RemoveArchived удаляет перенесенные в архив ссылки и записывает удаление в журнал.
+// Время переходов журнал не хранит: после перезапуска неактивность ссылок отсчитывается заново.
+func (s *Store) RemoveArchived(ctx context.Context, links []models.ArchivedLink) ([]models.ArchivedLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed, err := s.Store.RemoveArchived(ctx, links)
+	if err != nil || s.filepath == "" {
+		return removed, err
+	}
+	records := make([]record, 0, len(removed))
+	for _, link := range removed {
+		records = append(records, record{
+			Op:       opRemove,
+			UserID:   link.UserID,
+			ShortURL: link.ShortURL,
+			Domain:   link.Domain,
+		})
+	}
+	if err = s.append(records...); err != nil {
+		for _, link := range removed {
+			_, _ = s.Store.Set(ctx, link.UserID, link.Link())
+		}
+		return nil, fmt.Errorf("failed log removing archived links: %w", err)
+	}
+	return removed, nil
 }
 
 // HardDeleteURLs Хард удаление ссылок.
+// Запись очистки дописывается в журнал, только если есть удаленные ссылки.
 func (s *Store) HardDeleteURLs(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.Store.HasDeleted() {
+		return nil
+	}
+	if s.filepath != "" {
+		if err := s.append(record{Op: opPurge}); err != nil {
+			return fmt.Errorf("failed log hard deleting URLs: %w", err)
+		}
+	}
 	err := s.Store.HardDeleteURLs(ctx)
 	if err != nil {
 		return fmt.Errorf("failed hard deleting URLs: %w", err)
 	}
-	err = s.reWriteStore()
-	if err != nil {
-		return fmt.Errorf("faile rewrite file store: %w", err)
-	}
 
 	return nil
 }
 
-func (s *Store) uploadFromFile() error {
-	if s.filepath != "" {
-		var f *os.File
-		if _, err := os.Stat(s.filepath); errors.Is(err, os.ErrNotExist) {
-			path := filepath.Dir(s.filepath)
-			err := os.MkdirAll(path, os.ModePerm)
-			if err != nil {
-				return fmt.Errorf("failed create path %s storage: %w", path, err)
-			}
-			f, err = os.OpenFile(s.filepath, os.O_CREATE|os.O_RDONLY, os.ModePerm)
-			if err != nil {
-				return fmt.Errorf("failed create storage file: %w", err)
-			}
-			_, err = f.Seek(0, 0)
-			if err != nil {
-				return fmt.Errorf("failed seek file: %w", err)
-			}
-		} else {
-			f, err = os.OpenFile(s.filepath, os.O_RDONLY, os.ModePerm)
-			if err != nil {
-				return fmt.Errorf("failed open storage file: %w", err)
-			}
-		}
-		defer func() { _ = f.Close() }()
-
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			var item memory.StoreItem
-			err := json.Unmarshal(scanner.Bytes(), &item)
-			if err != nil {
-				return fmt.Errorf("failed unmarshal data from storage: %w", err)
-			}
-			_, err = s.Store.Set(context.Background(), item.UserID, item.ShortURL, item.OriginalURL)
-			if err != nil {
-				return fmt.Errorf("failed set (%s, %s, %s): %w", item.UserID, item.ShortURL, item.OriginalURL, err)
-			}
-		}
+// GetState Получение статисики, размер хранилища - размер файла журнала.
+func (s *Store) GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error) {
+	stats, err := s.Store.GetState(ctx, query)
+	if err != nil {
+		return models.ShortenStats{}, fmt.Errorf("failed get stats: %w", err)
+	}
+	if s.filepath == "" {
+		return stats, nil
+	}
+	info, err := os.Stat(s.filepath)
+	if err != nil {
+		return models.ShortenStats{}, fmt.Errorf("failed stat storage file: %w", err)
+	}
+	stats.StorageBytes = info.Size()
+	return stats, nil
+}
 
-		if err := scanner.Err(); err != nil {
-			return fmt.Errorf("failed scanner file storage: %w", err)
+// Snapshot обходит копию ссылок, снятую под блокировкой журнала.
+func (s *Store) Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error {
+	s.mu.Lock()
+	links := make([]models.ShortLink, 0)
+	err := s.Store.Snapshot(ctx, func(link models.ShortLink) error {
+		links = append(links, link)
+		return nil
+	})
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed snapshot links: %w", err)
+	}
+	for _, link := range links {
+		if err = fn(link); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -2,14 +2,20 @@ package file_test
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
 	"github.com/playmixer/short-link/internal/adapters/storage/file"
 	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/storagetest"
 	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
 )
 
@@ -52,9 +58,9 @@ func TestStorage_Get(t *testing.T) {
 		t.Run(test.name, func(tt *testing.T) {
 			ctx := context.Background()
 			s := createFileStorage(tt)
-			original, err := s.Get(ctx, test.short)
+			original, err := s.Get(ctx, "", test.short)
 			require.Error(tt, err, storeerror.ErrNotFoundKey)
-			require.Equal(tt, original, test.original)
+			require.Equal(tt, original.OriginalURL, test.original)
 		})
 	}
 	removeFileStorage(t)
@@ -133,14 +139,14 @@ func TestStorage_RemoveShortURL(t *testing.T) {
 		},
 	}
 	s := createFileStorage(t)
-	_, err := s.Set(context.Background(), "1", shortLink, "https://practicum.yandex.ru/")
+	_, err := s.Set(context.Background(), "1", models.ShortLink{ShortURL: shortLink, OriginalURL: "https://practicum.yandex.ru/"})
 	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(tt *testing.T) {
 			ctx := context.Background()
-			_, err := s.Get(ctx, test.short)
+			_, err := s.Get(ctx, "", test.short)
 			require.NoError(t, err)
-			s.RemoveShortURL(ctx, test.userID, test.short)
+			s.RemoveShortURL(ctx, test.userID, "", test.short)
 			for _, short := range s.GetAll() {
 				if short.ShortURL == test.short && short.IsDeleted == false {
 					t.Fatal("short is not deleted")
@@ -168,14 +174,14 @@ func TestStorage_HardDeleteURLs(t *testing.T) {
 		},
 	}
 	s := createFileStorage(t)
-	_, err := s.Set(context.Background(), "1", shortLink, "https://practicum.yandex.ru/")
+	_, err := s.Set(context.Background(), "1", models.ShortLink{ShortURL: shortLink, OriginalURL: "https://practicum.yandex.ru/"})
 	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(tt *testing.T) {
 			ctx := context.Background()
-			_, err := s.Get(ctx, test.short)
+			_, err := s.Get(ctx, "", test.short)
 			require.NoError(t, err)
-			s.RemoveShortURL(ctx, test.userID, test.short)
+			s.RemoveShortURL(ctx, test.userID, "", test.short)
 			err = s.HardDeleteURLs(ctx)
 			require.NoError(t, err)
 			for _, short := range s.GetAll() {
@@ -194,3 +200,218 @@ func TestStorage_Ping(t *testing.T) {
 	err := s.Ping(ctx)
 	require.NoError(t, err)
 }
+
+func TestStorage_Recover(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	s, err := file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "deleted", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "alive", OriginalURL: "https://gitlab.com/"})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "purged", OriginalURL: "https://bitbucket.org/"})
+	require.NoError(t, err)
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "purged", UserID: "1"}})
+	require.NoError(t, err)
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "deleted", UserID: "1"}})
+	require.NoError(t, err)
+	s.Close()
+
+	s, err = file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	defer s.Close()
+	_, err = s.Get(ctx, "", "deleted")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	_, err = s.Get(ctx, "", "purged")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	link, err := s.Get(ctx, "", "alive")
+	require.NoError(t, err)
+	require.Equal(t, "https://gitlab.com/", link.OriginalURL)
+}
+
+func TestStorage_RecoverRewrite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	s, err := file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "a", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+	ok, err := s.RewriteOriginalURL(ctx, models.ShortLink{ShortURL: "a", OriginalURL: "https://github.com/"},
+		"enc:v1:k1:secret", "hash")
+	require.NoError(t, err)
+	require.True(t, ok)
+	s.Close()
+
+	s, err = file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	defer s.Close()
+	link, err := s.Get(ctx, "", "a")
+	require.NoError(t, err)
+	require.Equal(t, "enc:v1:k1:secret", link.OriginalURL)
+	require.Equal(t, "hash", link.OriginalHash)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "b", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "c", OriginalURL: "enc:v1:k1:other", OriginalHash: "hash"})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+}
+
+func TestStorage_RecoverCreatedAt(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	created := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	data := fmt.Sprintf(`{"op":"set","user_id":"1","short_url":"a","original_url":"https://a.ru/","created_at":%d}
+{"user_id":"1","short_url":"b","original_url":"https://b.ru/"}
+`, created.Unix())
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	s, err := file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	defer s.Close()
+	stats, err := s.GetState(ctx, models.StatsQuery{Since: created.Add(-time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.URLs)
+	require.Equal(t, int64(len(data)), stats.StorageBytes)
+	require.Equal(t, models.DailyCount{Day: "2024-03-10", Count: 1}, stats.CreatedPerDay[0])
+}
+
+func TestStorage_RecoverRemoveArchived(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	s, err := file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "a", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+	links, err := s.ArchiveCandidates(ctx, time.Now().Add(time.Hour), time.Time{}, 10)
+	require.NoError(t, err)
+	removed, err := s.RemoveArchived(ctx, links)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	s.Close()
+
+	s, err = file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	defer s.Close()
+	_, err = s.Get(ctx, "", "a")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+}
+
+func TestStorage_RecoverTruncatedTail(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	data := `{"id":"1","user_id":"1","short_url":"legacy","original_url":"https://github.com/","is_deleted":false}
+{"op":"set","user_id":"1","short_url":"abc","original_url":"https://gitlab.com/"}
+{"op":"delete","user_id":"1","short_url":"ab`
+	require.NoError(t, os.WriteFile(path, []byte(data), os.ModePerm))
+
+	s, err := file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	_, err = s.Get(ctx, "", "legacy")
+	require.NoError(t, err)
+	_, err = s.Get(ctx, "", "abc")
+	require.NoError(t, err)
+
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "new", OriginalURL: "https://bitbucket.org/"})
+	require.NoError(t, err)
+	s.Close()
+
+	s, err = file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	defer s.Close()
+	require.Len(t, s.GetAll(), 3)
+}
+
+func TestStorage_Compact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	s, err := file.New(&file.Config{StoragePath: path, SyncPolicy: file.SyncInterval})
+	require.NoError(t, err)
+	for _, short := range []string{"a", "b", "c"} {
+		_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://github.com/" + short})
+		require.NoError(t, err)
+	}
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "b", UserID: "1"}})
+	require.NoError(t, err)
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	require.NoError(t, s.Compact())
+	s.Close()
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(b), "\n"))
+	_, err = os.Stat(path + ".tmp")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	s, err = file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	defer s.Close()
+	require.Len(t, s.GetAll(), 2)
+}
+
+func TestStorage_HardDeleteWithoutDeleted(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	s, err := file.New(&file.Config{StoragePath: path})
+	require.NoError(t, err)
+	defer s.Close()
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "a", OriginalURL: "https://github.com/a"})
+	require.NoError(t, err)
+	require.NoError(t, s.HardDeleteURLs(ctx))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), `"op":"purge"`)
+
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a", UserID: "1"}})
+	require.NoError(t, err)
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(b), `"op":"purge"`))
+}
+
+func TestStorage_Outbox(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	s, err := file.New(&file.Config{StoragePath: path, Outbox: true})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "a", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a", UserID: "1"}})
+	require.NoError(t, err)
+
+	types := []string{}
+	n, err := s.RelayOutbox(ctx, 10, func(events []models.LinkEvent) error {
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{models.LinkEventCreated, models.LinkEventDeleted}, types)
+	s.Close()
+
+	s, err = file.New(&file.Config{StoragePath: path, Outbox: true})
+	require.NoError(t, err)
+	defer s.Close()
+	n, err = s.RelayOutbox(ctx, 10, func(events []models.LinkEvent) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, 0, n, "recovery does not emit events")
+}
+
+func TestNew_UnknownSyncPolicy(t *testing.T) {
+	_, err := file.New(&file.Config{StoragePath: filepath.Join(t.TempDir(), "data.json"), SyncPolicy: "sometimes"})
+	require.Error(t, err)
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		s, err := file.New(&file.Config{StoragePath: filepath.Join(t.TempDir(), "data.json")})
+		require.NoError(t, err)
+		t.Cleanup(s.Close)
+		return s
+	})
+}
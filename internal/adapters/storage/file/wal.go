@@ -0,0 +1,286 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// Операции журнала.
+const (
+	opSet     = "set"     // сохранение ссылки.
+	opDelete  = "delete"  // мягкое удаление ссылки.
+	opPurge   = "purge"   // удаление помеченных ссылок.
+	opRewrite = "rewrite" // замена оригинальной ссылки.
+	opRemove  = "remove"  // перенос ссылки в архив.
+)
+
+// record - запись журнала.
+// Строки без операции записаны предыдущими версиями хранилища и читаются как сохранение.
+type record struct {
+	Op           string `json:"op,omitempty"`
+	ID           string `json:"id,omitempty"`
+	UserID       string `json:"user_id,omitempty"`
+	ShortURL     string `json:"short_url,omitempty"`
+	OriginalURL  string `json:"original_url,omitempty"`
+	OriginalHash string `json:"original_hash,omitempty"`
+	Domain       string `json:"domain,omitempty"`
+	RedirectCode int    `json:"redirect_code,omitempty"`
+	IsDeleted    bool   `json:"is_deleted,omitempty"`
+	PrevURL      string `json:"prev_url,omitempty"` // оригинальная ссылка до замены.
+	// CreatedAt время создания ссылки, Unix секунды; 0 - записано предыдущими версиями хранилища.
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+func setRecord(item memory.StoreItem) record {
+	return record{
+		Op:           opSet,
+		ID:           item.ID,
+		UserID:       item.UserID,
+		ShortURL:     item.ShortURL,
+		OriginalURL:  item.OriginalURL,
+		OriginalHash: item.OriginalHash,
+		Domain:       item.Domain,
+		RedirectCode: item.RedirectCode,
+		IsDeleted:    item.IsDeleted,
+		CreatedAt:    item.CreatedAt().Unix(),
+	}
+}
+
+func encodeRecords(records ...record) ([]byte, error) {
+	var buf []byte
+	for _, r := range records {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed marshal log record: %w", err)
+		}
+		buf = append(buf, b...)
+		buf = append(buf, '\n')
+	}
+	return buf, nil
+}
+
+// apply применяет запись журнала к памяти. Повторное применение записи не меняет состояние.
+func (s *Store) apply(ctx context.Context, r record) error {
+	switch r.Op {
+	case "", opSet:
+		link := models.ShortLink{
+			ShortURL:     r.ShortURL,
+			OriginalURL:  r.OriginalURL,
+			OriginalHash: r.OriginalHash,
+			Domain:       r.Domain,
+			RedirectCode: r.RedirectCode,
+		}
+		_, err := s.Store.Set(ctx, r.UserID, link)
+		if err != nil && !errors.Is(err, storeerror.ErrDuplicateShortURL) && !errors.Is(err, storeerror.ErrNotUnique) {
+			return fmt.Errorf("failed set (%s, %s, %s): %w", r.UserID, r.ShortURL, r.OriginalURL, err)
+		}
+		if r.CreatedAt != 0 {
+			s.Store.RestoreCreatedAt(r.Domain, r.ShortURL, time.Unix(r.CreatedAt, 0))
+		}
+		if r.IsDeleted {
+			link.UserID = r.UserID
+			if _, err = s.Store.DeleteShortURLs(ctx, []models.ShortLink{link}); err != nil {
+				return fmt.Errorf("failed delete %s: %w", r.ShortURL, err)
+			}
+		}
+	case opDelete:
+		link := models.ShortLink{ShortURL: r.ShortURL, Domain: r.Domain, UserID: r.UserID}
+		if _, err := s.Store.DeleteShortURLs(ctx, []models.ShortLink{link}); err != nil {
+			return fmt.Errorf("failed delete %s: %w", r.ShortURL, err)
+		}
+	case opRewrite:
+		link := models.ShortLink{ShortURL: r.ShortURL, Domain: r.Domain, OriginalURL: r.PrevURL}
+		if _, err := s.Store.RewriteOriginalURL(ctx, link, r.OriginalURL, r.OriginalHash); err != nil {
+			return fmt.Errorf("failed rewrite %s: %w", r.ShortURL, err)
+		}
+	case opRemove:
+		s.Store.RemoveShortURL(ctx, r.UserID, r.Domain, r.ShortURL)
+	case opPurge:
+		if err := s.Store.HardDeleteURLs(ctx); err != nil {
+			return fmt.Errorf("failed purge: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown log operation `%s`", r.Op)
+	}
+	return nil
+}
+
+// recover восстанавливает состояние из журнала.
+// Последняя строка, оборванная при сбое, отбрасывается, и файл обрезается до последней целой записи.
+func (s *Store) recover() error {
+	if err := os.MkdirAll(filepath.Dir(s.filepath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed create path %s storage: %w", filepath.Dir(s.filepath), err)
+	}
+	f, err := os.OpenFile(s.filepath, os.O_CREATE|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed open storage file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	ctx := context.Background()
+	reader := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) == 0 {
+				return nil
+			}
+			// последняя строка без перевода строки: запись могла оборваться.
+			var r record
+			if json.Unmarshal(line, &r) != nil {
+				if err := f.Truncate(offset); err != nil {
+					return fmt.Errorf("failed truncate broken tail of storage: %w", err)
+				}
+				return nil
+			}
+			if err := s.apply(ctx, r); err != nil {
+				return err
+			}
+			s.records++
+			if _, err := f.WriteAt([]byte{'\n'}, offset+int64(len(line))); err != nil {
+				return fmt.Errorf("failed complete last record of storage: %w", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed read storage file: %w", err)
+		}
+
+		var r record
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("failed unmarshal data from storage at offset %d: %w", offset, err)
+		}
+		if err := s.apply(ctx, r); err != nil {
+			return err
+		}
+		offset += int64(len(line))
+		s.records++
+	}
+}
+
+// openLog открывает журнал для дозаписи.
+var openLog = func(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_WRONLY, os.ModePerm)
+}
+
+// append дописывает записи в журнал, вызывается под mu.
+func (s *Store) append(records ...record) error {
+	b, err := encodeRecords(records...)
+	if err != nil {
+		return err
+	}
+	if err = s.reopen(); err != nil {
+		return err
+	}
+	if _, err = s.f.Write(b); err != nil {
+		return fmt.Errorf("failed write to file storage: %w", err)
+	}
+	s.records += len(records)
+	s.dirty = true
+	if s.syncPolicy == SyncAlways {
+		return s.sync()
+	}
+	return nil
+}
+
+// sync сбрасывает журнал на диск, вызывается под mu.
+func (s *Store) sync() error {
+	if !s.dirty {
+		return nil
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("failed sync file storage: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// Compact сжимает журнал: текущее состояние записывается во временный файл,
+// который атомарно заменяет журнал.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
+
+func (s *Store) compact() error {
+	items := s.GetAll()
+	records := make([]record, 0, len(items))
+	for _, item := range items {
+		records = append(records, setRecord(item))
+	}
+	b, err := encodeRecords(records...)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.filepath + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed create snapshot file: %w", err)
+	}
+	if _, err = tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed write snapshot: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed sync snapshot: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed close snapshot: %w", err)
+	}
+	if err = os.Rename(tmpPath, s.filepath); err != nil {
+		return fmt.Errorf("failed replace storage by snapshot: %w", err)
+	}
+	// журнал заменен снимком: прежний дескриптор указывает на удаленный файл и закрывается при любом исходе.
+	// Если новый журнал не открылся, он откроется при следующей записи.
+	s.records = len(records)
+	s.dirty = false
+	if s.f != nil {
+		_ = s.f.Close()
+		s.f = nil
+	}
+	if err = s.reopen(); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(s.filepath))
+}
+
+// reopen открывает журнал для дозаписи, если он закрыт после сбоя сжатия, вызывается под mu.
+func (s *Store) reopen() error {
+	if s.f != nil {
+		return nil
+	}
+	f, err := openLog(s.filepath)
+	if err != nil {
+		return fmt.Errorf("failed open storage file: %w", err)
+	}
+	s.f = f
+	return nil
+}
+
+// syncDir сбрасывает на диск каталог, чтобы переименование файла пережило сбой.
+func syncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed open storage dir: %w", err)
+	}
+	defer func() { _ = d.Close() }()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("failed sync storage dir: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+func TestStore_CompactReopenFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.json")
+	s, err := New(&Config{StoragePath: path})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "a", OriginalURL: "https://github.com/a"})
+	require.NoError(t, err)
+
+	open := openLog
+	defer func() { openLog = open }()
+	errOpen := errors.New("open failed")
+	openLog = func(string) (*os.File, error) { return nil, errOpen }
+
+	require.ErrorIs(t, s.Compact(), errOpen)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "b", OriginalURL: "https://github.com/b"})
+	require.ErrorIs(t, err, errOpen)
+
+	openLog = open
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "c", OriginalURL: "https://github.com/c"})
+	require.NoError(t, err)
+	s.Close()
+
+	s, err = New(&Config{StoragePath: path})
+	require.NoError(t, err)
+	defer s.Close()
+	for _, short := range []string{"a", "c"} {
+		_, err = s.Get(ctx, "", short)
+		require.NoError(t, err)
+	}
+}
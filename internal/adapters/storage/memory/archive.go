@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+)
+
+// TouchLinks отмечает переходы по ссылкам в момент at.
+func (s *Store) TouchLinks(ctx context.Context, links []models.ShortLink, at time.Time) error {
+	for _, link := range links {
+		key := linkKey{link.Domain, link.ShortURL}
+		sh := s.shard(key)
+		sh.mu.Lock()
+		if item, ok := sh.items[key]; ok && item.accessedAt.Before(at) {
+			item.accessedAt = at
+		}
+		sh.mu.Unlock()
+	}
+	return nil
+}
+
+// ArchiveCandidates возвращает до limit неудаленных ссылок, по которым не переходили с inactiveSince
+// или созданных раньше createdBefore. Нулевое время отключает условие.
+func (s *Store) ArchiveCandidates(ctx context.Context, inactiveSince, createdBefore time.Time, limit int) (
+	[]models.ArchivedLink,
+	error,
+) {
+	result := []models.ArchivedLink{}
+	for _, item := range s.GetAll() {
+		if len(result) >= limit {
+			break
+		}
+		if item.IsDeleted {
+			continue
+		}
+		active := item.createdAt
+		if item.accessedAt.After(active) {
+			active = item.accessedAt
+		}
+		if (inactiveSince.IsZero() || !active.Before(inactiveSince)) &&
+			(createdBefore.IsZero() || !item.createdAt.Before(createdBefore)) {
+			continue
+		}
+		result = append(result, models.ArchivedLink{
+			CreatedAt:    item.createdAt,
+			AccessedAt:   item.accessedAt,
+			ShortURL:     item.ShortURL,
+			OriginalURL:  item.OriginalURL,
+			UserID:       item.UserID,
+			Domain:       item.Domain,
+			RedirectCode: item.RedirectCode,
+		})
+	}
+	return result, nil
+}
+
+// RemoveArchived удаляет перенесенные в архив ссылки, по которым не переходили после выбора, и возвращает удаленные.
+func (s *Store) RemoveArchived(ctx context.Context, links []models.ArchivedLink) ([]models.ArchivedLink, error) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	removed := make([]models.ArchivedLink, 0, len(links))
+	for _, link := range links {
+		key := linkKey{link.Domain, link.ShortURL}
+		sh := s.shard(key)
+		sh.mu.Lock()
+		item, ok := sh.items[key]
+		if ok && item.UserID == link.UserID && !item.IsDeleted && item.accessedAt.Equal(link.AccessedAt) {
+			delete(sh.items, key)
+			s.unindex(item)
+			removed = append(removed, link)
+		}
+		sh.mu.Unlock()
+	}
+	return removed, nil
+}
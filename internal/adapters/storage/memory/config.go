@@ -2,4 +2,5 @@ package memory
 
 // Config конфигурация памяти.
 type Config struct {
+	Outbox bool // запись событий изменения ссылок для публикации.
 }
@@ -4,143 +4,391 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/outbox"
 	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
 )
 
+const (
+	shardCount = 64 // количество сегментов хранилища.
+
+	fnvOffset uint64 = 14695981039346656037
+	fnvPrime  uint64 = 1099511628211
+)
+
 // StoreItem элемент хранения ссылки.
 type StoreItem struct {
-	ID          string `json:"id"`
-	UserID      string `json:"user_id"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
-	IsDeleted   bool   `json:"is_deleted"`
+	ID           string `json:"id"`
+	UserID       string `json:"user_id"`
+	ShortURL     string `json:"short_url"`
+	OriginalURL  string `json:"original_url"`
+	OriginalHash string `json:"original_hash,omitempty"`
+	Domain       string `json:"domain,omitempty"`
+	RedirectCode int    `json:"redirect_code,omitempty"`
+	IsDeleted    bool   `json:"is_deleted"`
+	seq          uint64
+	createdAt    time.Time
+	accessedAt   time.Time // время последнего перехода, нулевое - переходов не было.
+}
+
+// linkKey - ключ ссылки: короткий код уникален в пределах домена.
+type linkKey struct {
+	domain string
+	short  string
+}
+
+// originalKey - ключ оригинальной ссылки пользователя.
+// Ссылки со слепым индексом сравниваются по нему, оригинальная ссылка в ключе тогда пустая.
+type originalKey struct {
+	userID   string
+	domain   string
+	original string
+	hash     string
+}
+
+func newOriginalKey(userID, domain, original, hash string) originalKey {
+	if hash != "" {
+		original = ""
+	}
+	return originalKey{userID, domain, original, hash}
+}
+
+// shard - сегмент хранилища со своей блокировкой.
+type shard struct {
+	mu    *sync.RWMutex
+	items map[linkKey]*StoreItem
 }
 
 // Store имплементация хранилища.
+// Ссылки распределены по сегментам по короткому коду, поэтому переходы по ссылкам
+// не блокируют друг друга. Индексы по пользователю защищены отдельной блокировкой,
+// при одновременном захвате она берется раньше блокировки сегмента.
 type Store struct {
-	mu   *sync.Mutex
-	data []StoreItem
+	shards     []*shard
+	usersMu    *sync.RWMutex
+	byOriginal map[originalKey]linkKey
+	byUser     map[string]map[linkKey]struct{}
+	deleted    int // количество мягко удаленных ссылок, защищено usersMu.
+	seq        *atomic.Uint64
+	outbox     *outbox.Queue // события изменения ссылок, nil - события не записываются.
 }
 
 // New создает Store.
 func New(cfg *Config) (*Store, error) {
-	return &Store{
-		data: make([]StoreItem, 0),
-		mu:   &sync.Mutex{},
-	}, nil
+	s := &Store{
+		shards:     make([]*shard, shardCount),
+		usersMu:    &sync.RWMutex{},
+		byOriginal: make(map[originalKey]linkKey),
+		byUser:     make(map[string]map[linkKey]struct{}),
+		seq:        &atomic.Uint64{},
+	}
+	for i := range s.shards {
+		s.shards[i] = &shard{
+			mu:    &sync.RWMutex{},
+			items: make(map[linkKey]*StoreItem),
+		}
+	}
+	if cfg.Outbox {
+		s.EnableOutbox()
+	}
+	return s, nil
+}
+
+// EnableOutbox включает запись событий изменения ссылок, изменения до вызова не публикуются.
+func (s *Store) EnableOutbox() {
+	s.outbox = outbox.NewQueue()
+}
+
+// emit записывает событие изменения ссылки, вызывается под usersMu.
+func (s *Store) emit(eventType, userID string, link models.ShortLink) {
+	if s.outbox != nil {
+		s.outbox.Append(models.NewLinkEvent(eventType, userID, link))
+	}
+}
+
+// RelayOutbox передает fn неотправленные события изменения ссылок.
+func (s *Store) RelayOutbox(ctx context.Context, limit int, fn func(events []models.LinkEvent) error) (int, error) {
+	if s.outbox == nil {
+		return 0, nil
+	}
+	return s.outbox.RelayOutbox(ctx, limit, fn)
+}
+
+// shard возвращает сегмент ссылки, сегмент выбирается по хешу FNV-1a ключа.
+func (s *Store) shard(key linkKey) *shard {
+	h := fnvOffset
+	for i := 0; i < len(key.domain); i++ {
+		h = (h ^ uint64(key.domain[i])) * fnvPrime
+	}
+	h = (h ^ 0) * fnvPrime
+	for i := 0; i < len(key.short); i++ {
+		h = (h ^ uint64(key.short[i])) * fnvPrime
+	}
+	return s.shards[h%shardCount]
 }
 
 // Close - закрыть соединение.
 func (s *Store) Close() {}
 
 // Set Сохраняет ссылку.
-func (s *Store) Set(ctx context.Context, userID, shortURL, originalURL string) (string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	for _, v := range s.data {
-		if v.OriginalURL == originalURL && v.UserID == userID {
-			return v.ShortURL, storeerror.ErrNotUnique
-		}
-		if v.ShortURL == shortURL && v.UserID == userID {
-			return v.ShortURL, storeerror.ErrDuplicateShortURL
-		}
+func (s *Store) Set(ctx context.Context, userID string, link models.ShortLink) (string, error) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	if short, err := s.check(userID, link); err != nil {
+		return short, err
 	}
+	s.insert(userID, link)
+	s.emit(models.LinkEventCreated, userID, link)
+	return link.ShortURL, nil
+}
+
+// check проверяет, что ссылку можно сохранить, вызывается под usersMu.
+// Для неуникальной оригинальной ссылки возвращает ее короткий код.
+func (s *Store) check(userID string, link models.ShortLink) (string, error) {
+	if key, ok := s.byOriginal[newOriginalKey(userID, link.Domain, link.OriginalURL, link.OriginalHash)]; ok {
+		return key.short, storeerror.ErrNotUnique
+	}
+	if _, ok := s.item(linkKey{link.Domain, link.ShortURL}); ok {
+		return "", storeerror.ErrDuplicateShortURL
+	}
+	return "", nil
+}
 
-	s.data = append(s.data, StoreItem{
-		ID:          strconv.Itoa(time.Now().Nanosecond()),
-		UserID:      userID,
-		ShortURL:    shortURL,
-		OriginalURL: originalURL,
-	})
+// insert сохраняет проверенную ссылку, вызывается под usersMu.
+func (s *Store) insert(userID string, link models.ShortLink) {
+	key := linkKey{link.Domain, link.ShortURL}
+	sh := s.shard(key)
+	sh.mu.Lock()
+	sh.items[key] = &StoreItem{
+		ID:           strconv.Itoa(time.Now().Nanosecond()),
+		UserID:       userID,
+		ShortURL:     link.ShortURL,
+		OriginalURL:  link.OriginalURL,
+		OriginalHash: link.OriginalHash,
+		Domain:       link.Domain,
+		RedirectCode: link.RedirectCode,
+		seq:          s.seq.Add(1),
+		createdAt:    link.CreationTime(time.Now()),
+	}
+	sh.mu.Unlock()
 
-	return shortURL, nil
+	s.index(newOriginalKey(userID, link.Domain, link.OriginalURL, link.OriginalHash), key)
 }
 
-// GetByUser Возвращает оригинальную ссылку пользователя.
-func (s *Store) GetByUser(ctx context.Context, userID, shortURL string) (string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	for _, v := range s.data {
-		if v.ShortURL == shortURL && v.UserID == userID {
-			return v.OriginalURL, nil
-		}
+// index добавляет ссылку в индексы пользователя, вызывается под usersMu.
+func (s *Store) index(orig originalKey, key linkKey) {
+	s.byOriginal[orig] = key
+	links, ok := s.byUser[orig.userID]
+	if !ok {
+		links = make(map[linkKey]struct{})
+		s.byUser[orig.userID] = links
 	}
-	return "", storeerror.ErrNotFoundKey
+	links[key] = struct{}{}
 }
 
-// Get Возвращает оригинальную ссылку.
-func (s *Store) Get(ctx context.Context, shortURL string) (string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	for _, v := range s.data {
-		if v.ShortURL == shortURL {
-			if v.IsDeleted {
-				return v.OriginalURL, storeerror.ErrShortURLDeleted
-			}
-			return v.OriginalURL, nil
+// unindex удаляет ссылку из индексов пользователя, вызывается под usersMu.
+func (s *Store) unindex(item *StoreItem) {
+	key := linkKey{item.Domain, item.ShortURL}
+	s.unindexOriginal(item)
+	if links, ok := s.byUser[item.UserID]; ok {
+		delete(links, key)
+		if len(links) == 0 {
+			delete(s.byUser, item.UserID)
 		}
 	}
-	return "", storeerror.ErrNotFoundKey
 }
 
-// SetBatch Сохраняет список ссылок.
+// unindexOriginal удаляет ссылку из индекса оригинальных ссылок, если индекс указывает на нее.
+// Вызывается под usersMu.
+func (s *Store) unindexOriginal(item *StoreItem) {
+	orig := newOriginalKey(item.UserID, item.Domain, item.OriginalURL, item.OriginalHash)
+	if s.byOriginal[orig] == (linkKey{item.Domain, item.ShortURL}) {
+		delete(s.byOriginal, orig)
+	}
+}
+
+// GetByUser Возвращает оригинальную ссылку пользователя.
+func (s *Store) GetByUser(ctx context.Context, userID, domain, shortURL string) (string, error) {
+	item, ok := s.item(linkKey{domain, shortURL})
+	if !ok || item.UserID != userID {
+		return "", storeerror.ErrNotFoundKey
+	}
+	return item.OriginalURL, nil
+}
+
+// item возвращает копию элемента по ключу.
+func (s *Store) item(key linkKey) (StoreItem, bool) {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	item, ok := sh.items[key]
+	if !ok {
+		return StoreItem{}, false
+	}
+	return *item, true
+}
+
+// Get Возвращает ссылку.
+func (s *Store) Get(ctx context.Context, domain, shortURL string) (models.ShortLink, error) {
+	item, ok := s.item(linkKey{domain, shortURL})
+	if !ok {
+		return models.ShortLink{}, storeerror.ErrNotFoundKey
+	}
+	if item.IsDeleted {
+		return item.toLink(), storeerror.ErrShortURLDeleted
+	}
+	return item.toLink(), nil
+}
+
+// CreatedAt возвращает время создания ссылки.
+func (i *StoreItem) CreatedAt() time.Time {
+	return i.createdAt
+}
+
+func (i *StoreItem) toLink() models.ShortLink {
+	return models.ShortLink{
+		ShortURL:     i.ShortURL,
+		OriginalURL:  i.OriginalURL,
+		OriginalHash: i.OriginalHash,
+		UserID:       i.UserID,
+		Domain:       i.Domain,
+		RedirectCode: i.RedirectCode,
+		IsDeleted:    i.IsDeleted,
+		CreatedAt:    i.createdAt,
+	}
+}
+
+// Exists проверяет, занят ли короткий код в домене.
+func (s *Store) Exists(ctx context.Context, domain, shortURL string) bool {
+	_, ok := s.item(linkKey{domain, shortURL})
+	return ok
+}
+
+// SetBatch Сохраняет список ссылок атомарно.
 func (s *Store) SetBatch(ctx context.Context, userID string, batch []models.ShortLink) (
 	output []models.ShortLink,
 	err error,
 ) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+
+	shorts := make(map[linkKey]struct{}, len(batch))
+	originals := make(map[originalKey]string, len(batch))
 	for _, b := range batch {
-		if _, err := s.GetByUser(ctx, userID, b.ShortURL); err == nil {
-			return []models.ShortLink{}, storeerror.ErrDuplicateShortURL
-		}
-		if shortURL, err := s.GetByOriginal(ctx, userID, b.OriginalURL); err == nil {
-			return []models.ShortLink{{ShortURL: shortURL, OriginalURL: b.OriginalURL}}, storeerror.ErrNotUnique
+		short, err := s.check(userID, b)
+		if errors.Is(err, storeerror.ErrNotUnique) {
+			return []models.ShortLink{{ShortURL: short, OriginalURL: b.OriginalURL, Domain: b.Domain}},
+				fmt.Errorf("URL `%s` is not unique: %w", b.OriginalURL, err)
 		}
-	}
-	shortAppled := make([]string, 0)
-	for _, req := range batch {
-		_, err := s.Set(ctx, userID, req.ShortURL, req.OriginalURL)
 		if err != nil {
-			if !errors.Is(err, storeerror.ErrDuplicateShortURL) {
-				for _, a := range shortAppled {
-					s.RemoveShortURL(ctx, userID, a)
-				}
-			}
-			return []models.ShortLink{}, fmt.Errorf("set link `%s` failed: %w", req.OriginalURL, err)
+			return []models.ShortLink{}, fmt.Errorf("set link `%s` failed: %w", b.OriginalURL, err)
+		}
+		orig := newOriginalKey(userID, b.Domain, b.OriginalURL, b.OriginalHash)
+		if short, ok := originals[orig]; ok {
+			return []models.ShortLink{{ShortURL: short, OriginalURL: b.OriginalURL, Domain: b.Domain}},
+				fmt.Errorf("URL `%s` is not unique: %w", b.OriginalURL, storeerror.ErrNotUnique)
+		}
+		if _, ok := shorts[linkKey{b.Domain, b.ShortURL}]; ok {
+			return []models.ShortLink{}, fmt.Errorf("set link `%s` failed: %w", b.OriginalURL, storeerror.ErrDuplicateShortURL)
 		}
-		shortAppled = append(shortAppled, req.ShortURL)
-		output = append(output, req)
+		originals[orig] = b.ShortURL
+		shorts[linkKey{b.Domain, b.ShortURL}] = struct{}{}
+	}
+
+	output = make([]models.ShortLink, 0, len(batch))
+	for _, b := range batch {
+		s.insert(userID, b)
+		s.emit(models.LinkEventCreated, userID, b)
+		output = append(output, b)
 	}
 	return output, nil
 }
 
 // GetByOriginal возврашает коротку ссылку по оригинальной.
-func (s *Store) GetByOriginal(ctx context.Context, userID, originalURL string) (string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	for _, v := range s.data {
-		if v.OriginalURL == originalURL && v.UserID == userID {
-			return v.ShortURL, nil
-		}
+func (s *Store) GetByOriginal(ctx context.Context, userID, domain, originalURL string) (string, error) {
+	s.usersMu.RLock()
+	defer s.usersMu.RUnlock()
+	if key, ok := s.byOriginal[newOriginalKey(userID, domain, originalURL, "")]; ok {
+		return key.short, nil
 	}
 	return "", fmt.Errorf("not found short by original URL: %s", originalURL)
 }
 
-// RemoveShortURL удаление ссылки.
-func (s *Store) RemoveShortURL(ctx context.Context, userID, short string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	newStorage := []StoreItem{}
-	for _, v := range s.data {
-		if !(v.ShortURL == short && v.UserID == userID) {
-			newStorage = append(newStorage, v)
+// FindOriginal возвращает короткий код неудаленной ссылки пользователя без слепого индекса
+// с оригинальной ссылкой original.
+func (s *Store) FindOriginal(ctx context.Context, userID, domain, original string) (string, error) {
+	s.usersMu.RLock()
+	defer s.usersMu.RUnlock()
+	if key, ok := s.byOriginal[newOriginalKey(userID, domain, original, "")]; ok {
+		return key.short, nil
+	}
+	return "", storeerror.ErrNotFoundKey
+}
+
+// RewriteOriginalURL заменяет оригинальную ссылку и ее слепой индекс, если ссылка не изменилась с момента чтения link.
+func (s *Store) RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	key := linkKey{link.Domain, link.ShortURL}
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	item, ok := sh.items[key]
+	if !ok || item.OriginalURL != link.OriginalURL || item.OriginalURL == original {
+		return false, nil
+	}
+	if !item.IsDeleted {
+		orig := newOriginalKey(item.UserID, item.Domain, original, hash)
+		if other, ok := s.byOriginal[orig]; ok && other != key {
+			return false, storeerror.ErrNotUnique
 		}
+		s.unindexOriginal(item)
+		s.byOriginal[orig] = key
+	}
+	item.OriginalURL = original
+	item.OriginalHash = hash
+	return true, nil
+}
+
+// RemoveShortURL удаление ссылки, отменяет сохранение ссылки вместе с неотправленным событием ее создания.
+func (s *Store) RemoveShortURL(ctx context.Context, userID, domain, short string) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	key := linkKey{domain, short}
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	item, ok := sh.items[key]
+	if !ok || item.UserID != userID {
+		return
+	}
+	delete(sh.items, key)
+	s.unindex(item)
+	if item.IsDeleted {
+		s.deleted--
+	}
+	if s.outbox != nil {
+		s.outbox.Drop(func(e models.LinkEvent) bool {
+			return e.Type == models.LinkEventCreated && e.UserID == userID && e.Domain == domain && e.ShortURL == short
+		})
+	}
+}
+
+// RestoreCreatedAt устанавливает время создания ссылки, восстановленной из журнала.
+func (s *Store) RestoreCreatedAt(domain, short string, at time.Time) {
+	key := linkKey{domain, short}
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if item, ok := sh.items[key]; ok {
+		item.createdAt = at
 	}
-	s.data = newStorage
 }
 
 // Ping Проверка соединения с хранилищем.
@@ -150,60 +398,171 @@ func (s *Store) Ping(ctx context.Context) error {
 
 // GetAllURL Возвращает все ссылки пользователя.
 func (s *Store) GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error) {
-	result := []models.ShortenURL{}
-	for _, v := range s.data {
-		if v.UserID == userID {
-			result = append(result, models.ShortenURL{ShortURL: v.ShortURL, OriginalURL: v.OriginalURL})
+	s.usersMu.RLock()
+	items := make([]StoreItem, 0, len(s.byUser[userID]))
+	for key := range s.byUser[userID] {
+		if item, ok := s.item(key); ok && !item.IsDeleted {
+			items = append(items, item)
 		}
 	}
+	s.usersMu.RUnlock()
+	sortItems(items)
+
+	result := make([]models.ShortenURL, 0, len(items))
+	for _, v := range items {
+		result = append(result, models.ShortenURL{ShortURL: v.ShortURL, OriginalURL: v.OriginalURL, Domain: v.Domain})
+	}
 	return result, nil
 }
 
-// GetAll возвращает все ссылки.
+// GetUserURLs Возвращает страницу ссылок пользователя.
+// Позиция ссылки - порядковый номер добавления в хранилище.
+func (s *Store) GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error) {
+	s.usersMu.RLock()
+	items := make([]StoreItem, 0, len(s.byUser[userID]))
+	for key := range s.byUser[userID] {
+		if item, ok := s.item(key); ok && !item.IsDeleted && item.seq > uint64(page.After) {
+			items = append(items, item)
+		}
+	}
+	s.usersMu.RUnlock()
+	sortItems(items)
+	if len(items) > page.Limit {
+		items = items[:page.Limit]
+	}
+
+	result := make([]models.ShortenURL, 0, len(items))
+	for _, v := range items {
+		result = append(result, models.ShortenURL{
+			ShortURL:    v.ShortURL,
+			OriginalURL: v.OriginalURL,
+			Domain:      v.Domain,
+			ID:          int64(v.seq),
+		})
+	}
+	return result, nil
+}
+
+// GetAll возвращает все ссылки в порядке добавления.
 func (s *Store) GetAll() []StoreItem {
-	return s.data
+	result := []StoreItem{}
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		for _, item := range sh.items {
+			result = append(result, *item)
+		}
+		sh.mu.RUnlock()
+	}
+	sortItems(result)
+	return result
 }
 
-// DeleteShortURLs Мягкое удаляет ссылки.
-func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Walk обходит все ссылки в порядке добавления.
+func (s *Store) Walk(ctx context.Context, fn func(link models.ShortLink) error) error {
+	for _, item := range s.GetAll() {
+		if err := fn(item.toLink()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	for _, short := range shorts {
-		for i, v := range s.data {
-			if v.ShortURL == short.ShortURL && v.UserID == short.UserID {
-				s.data[i].IsDeleted = true
-				break
-			}
+// Snapshot обходит копию ссылок, снятую под блокировкой записи.
+func (s *Store) Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error {
+	s.usersMu.RLock()
+	items := s.GetAll()
+	s.usersMu.RUnlock()
+	for _, item := range items {
+		if err := fn(item.toLink()); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+func sortItems(items []StoreItem) {
+	sort.Slice(items, func(i, j int) bool { return items[i].seq < items[j].seq })
+}
+
+// DeleteShortURLs Мягкое удаляет ссылки.
+// Удаленная ссылка не мешает сократить оригинальную ссылку повторно.
+func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	deleted := make([]models.ShortLink, 0, len(shorts))
+	for _, short := range shorts {
+		key := linkKey{short.Domain, short.ShortURL}
+		sh := s.shard(key)
+		sh.mu.Lock()
+		if item, ok := sh.items[key]; ok && item.UserID == short.UserID && !item.IsDeleted {
+			item.IsDeleted = true
+			s.unindexOriginal(item)
+			s.emit(models.LinkEventDeleted, item.UserID, models.ShortLink{
+				ShortURL:    item.ShortURL,
+				OriginalURL: item.OriginalURL,
+				Domain:      item.Domain,
+			})
+			deleted = append(deleted, short)
+		}
+		sh.mu.Unlock()
+	}
+	s.deleted += len(deleted)
+	return deleted, nil
+}
+
+// HasDeleted сообщает, есть ли мягко удаленные ссылки, ожидающие очистки.
+func (s *Store) HasDeleted() bool {
+	s.usersMu.RLock()
+	defer s.usersMu.RUnlock()
+	return s.deleted > 0
+}
+
 // HardDeleteURLs Хард удаление ссылок.
 func (s *Store) HardDeleteURLs(ctx context.Context) error {
-	newData := make([]StoreItem, 0)
-	for _, v := range s.data {
-		if !v.IsDeleted {
-			newData = append(newData, v)
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	if s.deleted == 0 {
+		return nil
+	}
+	s.deleted = 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for key, item := range sh.items {
+			if item.IsDeleted {
+				delete(sh.items, key)
+				s.unindex(item)
+			}
 		}
+		sh.mu.Unlock()
 	}
-	s.data = newData
 
 	return nil
 }
 
 // GetState Получение статисики.
-func (s *Store) GetState(ctx context.Context) (urls int, users int, err error) {
-	var urlCount int
-	userMap := make(map[string]any)
-	for _, d := range s.data {
-		if d.IsDeleted {
-			continue
+// Размер хранилища оценивается по длине строковых полей ссылок.
+func (s *Store) GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error) {
+	stats := models.ShortenStats{}
+	userCount := make(map[string]int)
+	dayCount := make(map[string]int)
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		for _, d := range sh.items {
+			stats.StorageBytes += int64(len(d.ID) + len(d.UserID) + len(d.ShortURL) + len(d.OriginalURL) + len(d.Domain))
+			if d.IsDeleted {
+				stats.Deleted++
+				continue
+			}
+			stats.URLs++
+			userCount[d.UserID]++
+			if !d.createdAt.Before(query.Since) {
+				dayCount[d.createdAt.UTC().Format(models.DayLayout)]++
+			}
 		}
-		userMap[d.UserID] = struct{}{}
-		urlCount++
+		sh.mu.RUnlock()
 	}
-
-	return urlCount, len(userMap), nil
+	stats.Users = len(userCount)
+	stats.CreatedPerDay = models.DailyCounts(dayCount)
+	stats.TopUsers = models.TopUsers(userCount, query.Top)
+	return stats, nil
 }
@@ -2,11 +2,17 @@ package memory_test
 
 import (
 	"context"
+	"strconv"
+	"sync"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
 	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/storagetest"
 	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
 )
 
@@ -39,9 +45,9 @@ func TestStorage_Get(t *testing.T) {
 		t.Run(test.name, func(tt *testing.T) {
 			ctx := context.Background()
 			s := createMemoryStorage(tt)
-			original, err := s.Get(ctx, test.short)
+			original, err := s.Get(ctx, "", test.short)
 			require.Error(tt, err, storeerror.ErrNotFoundKey)
-			require.Equal(tt, original, test.original)
+			require.Equal(tt, original.OriginalURL, test.original)
 		})
 	}
 }
@@ -85,14 +91,14 @@ func TestStorage_RemoveShortURL(t *testing.T) {
 		},
 	}
 	s := createMemoryStorage(t)
-	_, err := s.Set(context.Background(), "1", shortLink, "https://practicum.yandex.ru/")
+	_, err := s.Set(context.Background(), "1", models.ShortLink{ShortURL: shortLink, OriginalURL: "https://practicum.yandex.ru/"})
 	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(tt *testing.T) {
 			ctx := context.Background()
-			_, err := s.Get(ctx, test.short)
+			_, err := s.Get(ctx, "", test.short)
 			require.NoError(t, err)
-			s.RemoveShortURL(ctx, test.userID, test.short)
+			s.RemoveShortURL(ctx, test.userID, "", test.short)
 			for _, short := range s.GetAll() {
 				if short.ShortURL == test.short && short.IsDeleted == false {
 					t.Fatal("short is not deleted")
@@ -119,14 +125,14 @@ func TestStorage_HardDeleteURLs(t *testing.T) {
 		},
 	}
 	s := createMemoryStorage(t)
-	_, err := s.Set(context.Background(), "1", shortLink, "https://practicum.yandex.ru/")
+	_, err := s.Set(context.Background(), "1", models.ShortLink{ShortURL: shortLink, OriginalURL: "https://practicum.yandex.ru/"})
 	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(tt *testing.T) {
 			ctx := context.Background()
-			_, err := s.Get(ctx, test.short)
+			_, err := s.Get(ctx, "", test.short)
 			require.NoError(t, err)
-			s.RemoveShortURL(ctx, test.userID, test.short)
+			s.RemoveShortURL(ctx, test.userID, "", test.short)
 			err = s.HardDeleteURLs(ctx)
 			require.NoError(t, err)
 			for _, short := range s.GetAll() {
@@ -144,3 +150,149 @@ func TestStorage_Ping(t *testing.T) {
 	err := s.Ping(ctx)
 	require.NoError(t, err)
 }
+
+func TestStorage_Indexes(t *testing.T) {
+	ctx := context.Background()
+	s := createMemoryStorage(t)
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Set(ctx, strconv.Itoa(i%5), models.ShortLink{
+				ShortURL:    "s" + strconv.Itoa(i),
+				OriginalURL: "https://example.com/" + strconv.Itoa(i),
+			})
+			assert.NoError(t, err)
+			_, err = s.Get(ctx, "", "s"+strconv.Itoa(i))
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	short, err := s.GetByOriginal(ctx, "3", "", "https://example.com/13")
+	require.NoError(t, err)
+	require.Equal(t, "s13", short)
+
+	_, err = s.Set(ctx, "3", models.ShortLink{ShortURL: "new", OriginalURL: "https://example.com/13"})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	_, err = s.Set(ctx, "4", models.ShortLink{ShortURL: "s13", OriginalURL: "https://example.com/new"})
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+
+	urls, err := s.GetAllURL(ctx, "3")
+	require.NoError(t, err)
+	require.Len(t, urls, 10)
+
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "s13", UserID: "3"}})
+
+	require.NoError(t, err)
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	_, err = s.GetByOriginal(ctx, "3", "", "https://example.com/13")
+	require.Error(t, err)
+	urls, err = s.GetAllURL(ctx, "3")
+	require.NoError(t, err)
+	require.Len(t, urls, 9)
+	require.Len(t, s.GetAll(), 49)
+}
+
+func TestStorage_Outbox(t *testing.T) {
+	ctx := context.Background()
+	s, err := memory.New(&memory.Config{Outbox: true})
+	require.NoError(t, err)
+
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "a", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "b", OriginalURL: "https://github.com/"})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	_, err = s.SetBatch(ctx, "1", []models.ShortLink{{ShortURL: "c", OriginalURL: "https://gitlab.com/"}})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "d", OriginalURL: "https://bitbucket.org/"})
+	require.NoError(t, err)
+	s.RemoveShortURL(ctx, "1", "", "d")
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a", UserID: "1"}, {ShortURL: "c", UserID: "2"}})
+	require.NoError(t, err)
+
+	events := []models.LinkEvent{}
+	_, err = s.RelayOutbox(ctx, 10, func(batch []models.LinkEvent) error {
+		events = append(events, batch...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, models.LinkEventCreated, events[0].Type)
+	require.Equal(t, "c", events[1].ShortURL)
+	require.Equal(t, models.LinkEventDeleted, events[2].Type)
+	require.Equal(t, "https://github.com/", events[2].OriginalURL)
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return createMemoryStorage(t)
+	})
+}
+
+func fillMemoryStorage(b *testing.B, n int) *memory.Store {
+	b.Helper()
+
+	s, err := memory.New(&memory.Config{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+	for i := range n {
+		_, err := s.Set(ctx, strconv.Itoa(i%1000), models.ShortLink{
+			ShortURL:    "s" + strconv.Itoa(i),
+			OriginalURL: "https://example.com/" + strconv.Itoa(i),
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	return s
+}
+
+const benchmarkLinks = 20_000
+
+func BenchmarkStore_Get(b *testing.B) {
+	s := fillMemoryStorage(b, benchmarkLinks)
+	ctx := context.Background()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			_, _ = s.Get(ctx, "", "s"+strconv.Itoa(i%benchmarkLinks))
+			i += 7919
+		}
+	})
+}
+
+func BenchmarkStore_Set(b *testing.B) {
+	s := fillMemoryStorage(b, benchmarkLinks)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := range b.N {
+		_, _ = s.Set(ctx, "bench", models.ShortLink{
+			ShortURL:    "b" + strconv.Itoa(i),
+			OriginalURL: "https://example.org/" + strconv.Itoa(i),
+		})
+	}
+}
+
+func BenchmarkStore_GetByOriginal(b *testing.B) {
+	s := fillMemoryStorage(b, benchmarkLinks)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := range b.N {
+		n := i * 7919 % benchmarkLinks
+		_, _ = s.GetByOriginal(ctx, strconv.Itoa(n%1000), "", "https://example.com/"+strconv.Itoa(n))
+	}
+}
+
+func BenchmarkStore_GetAllURL(b *testing.B) {
+	s := fillMemoryStorage(b, benchmarkLinks)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := range b.N {
+		_, _ = s.GetAllURL(ctx, strconv.Itoa(i%1000))
+	}
+}
@@ -0,0 +1,29 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// unavailablePrefixes - ответы Redis, при которых сервер временно не обслуживает запросы.
+var unavailablePrefixes = []string{"LOADING", "READONLY", "MASTERDOWN", "CLUSTERDOWN", "TRYAGAIN"}
+
+// classify переводит ошибки соединения с Redis в ошибки хранилища.
+func classify(err error) error {
+	if err == nil || storeerror.KindOf(err) != storeerror.KindUnknown {
+		return err
+	}
+	if errors.Is(err, goredis.ErrClosed) {
+		return fmt.Errorf("%w: %w", storeerror.ErrUnavailable, err)
+	}
+	for _, prefix := range unavailablePrefixes {
+		if goredis.HasErrorPrefix(err, prefix) {
+			return fmt.Errorf("%w: %w", storeerror.ErrUnavailable, err)
+		}
+	}
+	return storeerror.Classify(err)
+}
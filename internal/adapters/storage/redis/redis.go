@@ -0,0 +1,566 @@
+// Модуль redis реализует хранилище ссылок в Redis.
+//
+// Ссылка хранится в хеше prefix+link:<домен>/<код>, индексы пользователя - в хеше
+// prefix+orig:<пользователь> (<домен>/<оригинальная ссылка> или <домен>#<слепой индекс> -> код)
+// и в упорядоченном множестве prefix+user:<пользователь> (ссылки в порядке добавления). Счетчики статистики хранятся в хеше
+// prefix+stats, упорядоченном множестве prefix+stats:users (пользователь -> количество ссылок)
+// и хеше prefix+stats:days (сутки Unix -> количество созданных ссылок). Проверки уникальности
+// и изменения индексов и счетчиков выполняются Lua скриптами атомарно.
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+const (
+	defaultPrefix = "short:"
+	scanCount     = 1000 // размер страницы SCAN при пересчете статистики.
+	purgeCount    = 500  // размер части множества удаленных, очищаемой одним скриптом.
+	secondsPerDay = 86400
+)
+
+// Store имплементация хранилища Redis.
+type Store struct {
+	client *goredis.Client
+	prefix string
+}
+
+// New создает Store.
+func New(ctx context.Context, cfg *Config) (*Store, error) {
+	opt, err := goredis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed parse redis url: %w", err)
+	}
+	s := &Store{
+		client: goredis.NewClient(opt),
+		prefix: cfg.Prefix,
+	}
+	if s.prefix == "" {
+		s.prefix = defaultPrefix
+	}
+	if err = s.Ping(ctx); err != nil {
+		_ = s.client.Close()
+		return nil, err
+	}
+	if err = s.initStats(ctx); err != nil {
+		_ = s.client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close - закрыть соединение.
+func (s *Store) Close() {
+	_ = s.client.Close()
+}
+
+func member(domain, short string) string {
+	return domain + "/" + short
+}
+
+// originalField - поле индекса оригинальных ссылок. Ссылка со слепым индексом hash индексируется по нему.
+func originalField(domain, original, hash string) string {
+	if hash != "" {
+		return domain + "#" + hash
+	}
+	return member(domain, original)
+}
+
+func (s *Store) linkKey(domain, short string) string {
+	return s.prefix + "link:" + member(domain, short)
+}
+
+func (s *Store) originalKey(userID string) string {
+	return s.prefix + "orig:" + userID
+}
+
+func (s *Store) userKey(userID string) string {
+	return s.prefix + "user:" + userID
+}
+
+func (s *Store) seqKey() string {
+	return s.prefix + "seq"
+}
+
+func (s *Store) deletedKey() string {
+	return s.prefix + "deleted"
+}
+
+func (s *Store) statsKey() string {
+	return s.prefix + "stats"
+}
+
+func (s *Store) statsUsersKey() string {
+	return s.prefix + "stats:users"
+}
+
+func (s *Store) statsDaysKey() string {
+	return s.prefix + "stats:days"
+}
+
+func toLink(v map[string]string) models.ShortLink {
+	code, _ := strconv.Atoi(v["redirect_code"])
+	createdAt, _ := strconv.ParseInt(v["created_at"], 10, 64)
+	return models.ShortLink{
+		ShortURL:     v["short_url"],
+		OriginalURL:  v["original_url"],
+		OriginalHash: v["original_hash"],
+		UserID:       v["user_id"],
+		Domain:       v["domain"],
+		RedirectCode: code,
+		IsDeleted:    v["is_deleted"] == "1",
+		CreatedAt:    models.UnixTime(createdAt),
+	}
+}
+
+// Get Возвращает ссылку.
+func (s *Store) Get(ctx context.Context, domain, short string) (models.ShortLink, error) {
+	v, err := s.client.HGetAll(ctx, s.linkKey(domain, short)).Result()
+	if err != nil {
+		return models.ShortLink{}, fmt.Errorf("failed get link: %w", classify(err))
+	}
+	if len(v) == 0 {
+		return models.ShortLink{}, storeerror.ErrNotFoundKey
+	}
+	link := toLink(v)
+	if link.IsDeleted {
+		return link, storeerror.ErrShortURLDeleted
+	}
+	return link, nil
+}
+
+// GetAllURL Возвращает все ссылки пользователя.
+func (s *Store) GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error) {
+	result := []models.ShortenURL{}
+	members, err := s.client.ZRange(ctx, s.userKey(userID), 0, -1).Result()
+	if err != nil {
+		return result, fmt.Errorf("failed selecting all URLs by user: %w", classify(err))
+	}
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, 0, len(members))
+	for _, m := range members {
+		cmds = append(cmds, pipe.HGetAll(ctx, s.prefix+"link:"+m))
+	}
+	if _, err = pipe.Exec(ctx); err != nil {
+		return result, fmt.Errorf("failed selecting all URLs by user: %w", classify(err))
+	}
+	for _, cmd := range cmds {
+		link := toLink(cmd.Val())
+		if link.ShortURL == "" || link.IsDeleted {
+			continue
+		}
+		result = append(result, models.ShortenURL{ShortURL: link.ShortURL, OriginalURL: link.OriginalURL, Domain: link.Domain})
+	}
+	return result, nil
+}
+
+// GetUserURLs Возвращает страницу ссылок пользователя.
+// Позиция ссылки - ее вес в упорядоченном множестве пользователя.
+// Удаленные ссылки остаются в множестве до очистки, поэтому множество читается частями до заполнения страницы.
+func (s *Store) GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error) {
+	result := []models.ShortenURL{}
+	after := page.After
+	for len(result) < page.Limit {
+		members, err := s.client.ZRangeByScoreWithScores(ctx, s.userKey(userID), &goredis.ZRangeBy{
+			Min:   "(" + strconv.FormatInt(after, 10),
+			Max:   "+inf",
+			Count: int64(page.Limit - len(result)),
+		}).Result()
+		if err != nil {
+			return result, fmt.Errorf("failed selecting URLs by user: %w", classify(err))
+		}
+		if len(members) == 0 {
+			break
+		}
+		pipe := s.client.Pipeline()
+		cmds := make([]*goredis.MapStringStringCmd, 0, len(members))
+		for _, m := range members {
+			cmds = append(cmds, pipe.HGetAll(ctx, s.prefix+"link:"+m.Member.(string)))
+		}
+		if _, err = pipe.Exec(ctx); err != nil {
+			return result, fmt.Errorf("failed selecting URLs by user: %w", classify(err))
+		}
+		for i, cmd := range cmds {
+			after = int64(members[i].Score)
+			link := toLink(cmd.Val())
+			if link.ShortURL == "" || link.IsDeleted {
+				continue
+			}
+			result = append(result, models.ShortenURL{
+				ShortURL:    link.ShortURL,
+				OriginalURL: link.OriginalURL,
+				Domain:      link.Domain,
+				ID:          after,
+			})
+		}
+	}
+	return result, nil
+}
+
+// Set Сохраняет ссылку.
+func (s *Store) Set(ctx context.Context, userID string, link models.ShortLink) (string, error) {
+	res, err := s.setBatch(ctx, userID, []models.ShortLink{link})
+	if err != nil {
+		return "", fmt.Errorf("failed setting short url: %w", classify(err))
+	}
+	switch res.status {
+	case setNotUnique:
+		return res.short, fmt.Errorf("url `%s` is not unique: %w", link.OriginalURL, storeerror.ErrNotUnique)
+	case setDuplicate:
+		return "", fmt.Errorf("short `%s`: %w", link.ShortURL, storeerror.ErrDuplicateShortURL)
+	}
+	return link.ShortURL, nil
+}
+
+// SetBatch Сохраняет список ссылок атомарно.
+func (s *Store) SetBatch(ctx context.Context, userID string, batch []models.ShortLink) (
+	output []models.ShortLink,
+	err error,
+) {
+	if len(batch) == 0 {
+		return []models.ShortLink{}, nil
+	}
+	res, err := s.setBatch(ctx, userID, batch)
+	if err != nil {
+		return []models.ShortLink{}, fmt.Errorf("failed save batch: %w", classify(err))
+	}
+	switch res.status {
+	case setNotUnique:
+		d := batch[res.index]
+		return []models.ShortLink{{ShortURL: res.short, OriginalURL: d.OriginalURL, Domain: d.Domain}},
+			fmt.Errorf("URL `%s` is not unique: %w", d.OriginalURL, storeerror.ErrNotUnique)
+	case setDuplicate:
+		return []models.ShortLink{}, fmt.Errorf("short `%s`: %w", batch[res.index].ShortURL, storeerror.ErrDuplicateShortURL)
+	}
+	return batch, nil
+}
+
+// setResult - ответ скрипта сохранения.
+type setResult struct {
+	short  string
+	status int64
+	index  int
+}
+
+func (s *Store) setBatch(ctx context.Context, userID string, batch []models.ShortLink) (setResult, error) {
+	keys := make([]string, 0, len(batch)+6)
+	keys = append(keys, s.originalKey(userID), s.userKey(userID), s.seqKey(),
+		s.statsKey(), s.statsUsersKey(), s.statsDaysKey())
+	args := make([]any, 0, len(batch)*8+1)
+	args = append(args, userID)
+	now := time.Now()
+	for _, link := range batch {
+		keys = append(keys, s.linkKey(link.Domain, link.ShortURL))
+		args = append(args,
+			originalField(link.Domain, link.OriginalURL, link.OriginalHash),
+			link.ShortURL,
+			link.OriginalURL,
+			link.Domain,
+			link.RedirectCode,
+			member(link.Domain, link.ShortURL),
+			link.CreationTime(now).Unix(),
+			link.OriginalHash,
+		)
+	}
+	v, err := scriptSetBatch.Run(ctx, s.client, keys, args...).Slice()
+	if err != nil {
+		return setResult{}, fmt.Errorf("failed run set script: %w", classify(err))
+	}
+	if len(v) != 3 {
+		return setResult{}, errors.New("unexpected set script response")
+	}
+	status, _ := v[0].(int64)
+	index, _ := v[1].(int64)
+	short, _ := v[2].(string)
+	return setResult{status: status, index: int(index) - 1, short: short}, nil
+}
+
+// Ping Проверка соединения с хранилищем.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed ping redis: %w", classify(err))
+	}
+	return nil
+}
+
+// DeleteShortURLs Мягкое удаляет ссылки.
+func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
+	deleted := make([]models.ShortLink, 0, len(shorts))
+	for _, v := range shorts {
+		link, err := s.client.HMGet(ctx, s.linkKey(v.Domain, v.ShortURL), "original_url", "original_hash").Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed deleting short url `%s`: %w", v.ShortURL, classify(err))
+		}
+		original, _ := link[0].(string)
+		hash, _ := link[1].(string)
+		keys := []string{
+			s.linkKey(v.Domain, v.ShortURL), s.originalKey(v.UserID), s.deletedKey(),
+			s.statsKey(), s.statsUsersKey(), s.statsDaysKey(),
+		}
+		ok, err := scriptDelete.Run(ctx, s.client, keys, v.UserID, originalField(v.Domain, original, hash)).Bool()
+		if err != nil {
+			return nil, fmt.Errorf("failed deleting short url `%s`: %w", v.ShortURL, classify(err))
+		}
+		if ok {
+			deleted = append(deleted, v)
+		}
+	}
+	return deleted, nil
+}
+
+// HardDeleteURLs Хард удаление ссылок.
+// Множество удаленных читается частями через SSCAN, каждая часть очищается отдельным скриптом,
+// чтобы не блокировать Redis на все множество и передавать скрипту все ключи через KEYS.
+// Ключи убираются из множества во время обхода, поэтому обход повторяется,
+// пока множество меняется.
+func (s *Store) HardDeleteURLs(ctx context.Context) error {
+	for {
+		purged, err := s.purgePass(ctx)
+		if err != nil {
+			return fmt.Errorf("failed hard deleting URLs: %w", err)
+		}
+		if purged == 0 {
+			return nil
+		}
+	}
+}
+
+// purgePass обходит множество удаленных один раз и возвращает количество убранных из него ключей.
+func (s *Store) purgePass(ctx context.Context) (int64, error) {
+	var cursor uint64
+	var purged int64
+	for {
+		keys, next, err := s.client.SScan(ctx, s.deletedKey(), cursor, "", purgeCount).Result()
+		if err != nil {
+			return purged, fmt.Errorf("failed scanning deleted URLs: %w", classify(err))
+		}
+		n, err := s.purge(ctx, keys)
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+		if next == 0 {
+			return purged, nil
+		}
+		cursor = next
+	}
+}
+
+// purge удаляет часть помеченных ссылок и возвращает количество убранных из множества ключей.
+func (s *Store) purge(ctx context.Context, keys []string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.StringCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, pipe.HGet(ctx, key, "user_id"))
+	}
+	_, _ = pipe.Exec(ctx)
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && !errors.Is(err, goredis.Nil) {
+			return 0, fmt.Errorf("failed reading deleted URLs: %w", classify(err))
+		}
+	}
+	scriptKeys := make([]string, 0, len(keys)*2+1)
+	scriptKeys = append(scriptKeys, s.deletedKey())
+	args := make([]any, 0, len(keys)*2)
+	linkPrefix := s.prefix + "link:"
+	for i, key := range keys {
+		userID := cmds[i].Val()
+		scriptKeys = append(scriptKeys, key, s.userKey(userID))
+		args = append(args, userID, strings.TrimPrefix(key, linkPrefix))
+	}
+	n, err := scriptPurge.Run(ctx, s.client, scriptKeys, args...).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed run purge script: %w", classify(err))
+	}
+	return n, nil
+}
+
+// GetState Получение статисики по счетчикам, которые обновляются скриптами сохранения и удаления.
+// Размер хранилища - память, занятая экземпляром Redis.
+func (s *Store) GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error) {
+	var urls *goredis.StringCmd
+	var users, deleted *goredis.IntCmd
+	var days *goredis.MapStringStringCmd
+	var top *goredis.ZSliceCmd
+	cmds, _ := s.client.Pipelined(ctx, func(p goredis.Pipeliner) error {
+		urls = p.HGet(ctx, s.statsKey(), "urls")
+		users = p.ZCard(ctx, s.statsUsersKey())
+		deleted = p.SCard(ctx, s.deletedKey())
+		days = p.HGetAll(ctx, s.statsDaysKey())
+		if query.Top > 0 {
+			top = p.ZRevRangeWithScores(ctx, s.statsUsersKey(), 0, int64(query.Top)-1)
+		}
+		return nil
+	})
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && !errors.Is(err, goredis.Nil) {
+			return models.ShortenStats{}, fmt.Errorf("failed get stats: %w", classify(err))
+		}
+	}
+	stats := models.ShortenStats{TopUsers: []models.UserCount{}}
+	stats.URLs, _ = strconv.Atoi(urls.Val())
+	stats.Users = int(users.Val())
+	stats.Deleted = int(deleted.Val())
+
+	since := query.Since.Unix() / secondsPerDay
+	dayCount := make(map[string]int)
+	for field, value := range days.Val() {
+		day, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || day < since {
+			continue
+		}
+		count, _ := strconv.Atoi(value)
+		if count > 0 {
+			dayCount[time.Unix(day*secondsPerDay, 0).UTC().Format(models.DayLayout)] = count
+		}
+	}
+	stats.CreatedPerDay = models.DailyCounts(dayCount)
+	if top != nil {
+		for _, z := range top.Val() {
+			userID, _ := z.Member.(string)
+			stats.TopUsers = append(stats.TopUsers, models.UserCount{UserID: userID, URLs: int(z.Score)})
+		}
+	}
+
+	// INFO может быть отключен у управляемого Redis, тогда размер хранилища неизвестен.
+	if info, err := s.client.InfoMap(ctx, "memory").Result(); err == nil {
+		stats.StorageBytes, _ = strconv.ParseInt(info["Memory"]["used_memory"], 10, 64)
+	}
+	return stats, nil
+}
+
+// initStats пересчитывает счетчики статистики, если их нет: хранилище создано предыдущими версиями или пустое.
+func (s *Store) initStats(ctx context.Context) error {
+	n, err := s.client.Exists(ctx, s.statsKey()).Result()
+	if err != nil {
+		return fmt.Errorf("failed check stats: %w", classify(err))
+	}
+	if n > 0 {
+		return nil
+	}
+	var urls int
+	userCount := make(map[string]int)
+	dayCount := make(map[string]int)
+	iter := s.client.Scan(ctx, 0, s.prefix+"link:*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		v, err := s.client.HMGet(ctx, iter.Val(), "user_id", "is_deleted", "created_at").Result()
+		if err != nil {
+			return fmt.Errorf("failed count stats: %w", classify(err))
+		}
+		userID, _ := v[0].(string)
+		isDeleted, _ := v[1].(string)
+		if userID == "" || isDeleted == "1" {
+			continue
+		}
+		urls++
+		userCount[userID]++
+		if createdAt, ok := v[2].(string); ok {
+			if ts, err := strconv.ParseInt(createdAt, 10, 64); err == nil {
+				dayCount[strconv.FormatInt(ts/secondsPerDay, 10)]++
+			}
+		}
+	}
+	if err = iter.Err(); err != nil {
+		return fmt.Errorf("failed count stats: %w", classify(err))
+	}
+	_, err = s.client.TxPipelined(ctx, func(p goredis.Pipeliner) error {
+		p.Del(ctx, s.statsUsersKey(), s.statsDaysKey())
+		for userID, count := range userCount {
+			p.ZAdd(ctx, s.statsUsersKey(), goredis.Z{Score: float64(count), Member: userID})
+		}
+		for day, count := range dayCount {
+			p.HSet(ctx, s.statsDaysKey(), day, count)
+		}
+		p.HSet(ctx, s.statsKey(), "urls", urls)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed save stats: %w", classify(err))
+	}
+	return nil
+}
+
+// FindOriginal возвращает короткий код неудаленной ссылки пользователя без слепого индекса
+// с оригинальной ссылкой original.
+func (s *Store) FindOriginal(ctx context.Context, userID, domain, original string) (string, error) {
+	short, err := s.client.HGet(ctx, s.originalKey(userID), originalField(domain, original, "")).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", storeerror.ErrNotFoundKey
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed find original: %w", classify(err))
+	}
+	return short, nil
+}
+
+// RewriteOriginalURL заменяет оригинальную ссылку и ее слепой индекс, если ссылка не изменилась с момента чтения link.
+func (s *Store) RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error) {
+	if link.OriginalURL == original {
+		return false, nil
+	}
+	key := s.linkKey(link.Domain, link.ShortURL)
+	v, err := s.client.HMGet(ctx, key, "user_id", "original_hash").Result()
+	if err != nil {
+		return false, fmt.Errorf("failed get link: %w", classify(err))
+	}
+	userID, ok := v[0].(string)
+	if !ok {
+		return false, nil
+	}
+	// прежний слепой индекс меняется только вместе с оригинальной ссылкой, которую проверяет скрипт.
+	prevHash, _ := v[1].(string)
+	status, err := scriptRewrite.Run(ctx, s.client, []string{key, s.originalKey(userID)},
+		link.OriginalURL, original,
+		originalField(link.Domain, link.OriginalURL, prevHash), originalField(link.Domain, original, hash), hash,
+	).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed rewrite original url: %w", classify(err))
+	}
+	switch status {
+	case rewriteSkipped:
+		return false, nil
+	case rewriteNotUnique:
+		return false, fmt.Errorf("rewrite short url `%s`: %w", link.ShortURL, storeerror.ErrNotUnique)
+	}
+	return status == rewriteOK, nil
+}
+
+// Walk обходит все ссылки в порядке ключей.
+func (s *Store) Walk(ctx context.Context, fn func(link models.ShortLink) error) error {
+	keys := []string{}
+	iter := s.client.Scan(ctx, 0, s.prefix+"link:*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed scan links: %w", classify(err))
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v, err := s.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed get link: %w", classify(err))
+		}
+		if len(v) == 0 {
+			continue
+		}
+		if err = fn(toLink(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package redis_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/redis"
+	"github.com/playmixer/short-link/internal/adapters/storage/storagetest"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+func createRedisStorage(t *testing.T) (*redis.Store, *redis.Config) {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+	cfg := &redis.Config{URL: "redis://" + srv.Addr()}
+	s, err := redis.New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s, cfg
+}
+
+func TestStore_SetGet(t *testing.T) {
+	ctx := context.Background()
+	s, _ := createRedisStorage(t)
+
+	_, err := s.Get(ctx, "", "WQEAWE")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+
+	short, err := s.Set(ctx, "1", models.ShortLink{ShortURL: "abc", OriginalURL: "https://github.com/", RedirectCode: 301})
+	require.NoError(t, err)
+	require.Equal(t, "abc", short)
+
+	link, err := s.Get(ctx, "", "abc")
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/", link.OriginalURL)
+	require.Equal(t, "1", link.UserID)
+	require.Equal(t, 301, link.RedirectCode)
+
+	short, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "def", OriginalURL: "https://github.com/"})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "abc", short)
+
+	_, err = s.Set(ctx, "2", models.ShortLink{ShortURL: "abc", OriginalURL: "https://gitlab.com/"})
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+
+	_, err = s.Set(ctx, "2", models.ShortLink{ShortURL: "abc", OriginalURL: "https://gitlab.com/", Domain: "go.dev"})
+	require.NoError(t, err)
+}
+
+func TestStore_SetBatch(t *testing.T) {
+	ctx := context.Background()
+	s, _ := createRedisStorage(t)
+	_, err := s.Set(ctx, "1", models.ShortLink{ShortURL: "abc", OriginalURL: "https://github.com/"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		batch   []models.ShortLink
+		wantErr error
+	}{
+		{
+			name: "duplicate short",
+			batch: []models.ShortLink{
+				{ShortURL: "q1", OriginalURL: "https://go.dev/"},
+				{ShortURL: "abc", OriginalURL: "https://gitlab.com/"},
+			},
+			wantErr: storeerror.ErrDuplicateShortURL,
+		},
+		{
+			name: "not unique original",
+			batch: []models.ShortLink{
+				{ShortURL: "q1", OriginalURL: "https://go.dev/"},
+				{ShortURL: "q2", OriginalURL: "https://github.com/"},
+			},
+			wantErr: storeerror.ErrNotUnique,
+		},
+		{
+			name: "saved",
+			batch: []models.ShortLink{
+				{ShortURL: "q1", OriginalURL: "https://go.dev/"},
+				{ShortURL: "q2", OriginalURL: "https://gitlab.com/"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.SetBatch(ctx, "1", tt.batch)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				// транзакция откатывается целиком.
+				_, err = s.Get(ctx, "", "q1")
+				require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, res, len(tt.batch))
+		})
+	}
+
+	urls, err := s.GetAllURL(ctx, "1")
+	require.NoError(t, err)
+	require.Equal(t, []models.ShortenURL{
+		{ShortURL: "abc", OriginalURL: "https://github.com/"},
+		{ShortURL: "q1", OriginalURL: "https://go.dev/"},
+		{ShortURL: "q2", OriginalURL: "https://gitlab.com/"},
+	}, urls)
+}
+
+func TestStore_Delete(t *testing.T) {
+	ctx := context.Background()
+	s, cfg := createRedisStorage(t)
+	for _, short := range []string{"a", "b", "c"} {
+		_, err := s.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://github.com/" + short})
+		require.NoError(t, err)
+	}
+	_, err := s.Set(ctx, "2", models.ShortLink{ShortURL: "d", OriginalURL: "https://github.com/d"})
+	require.NoError(t, err)
+
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{
+		{ShortURL: "a", UserID: "1"},
+		{ShortURL: "d", UserID: "1"},
+	})
+	require.NoError(t, err)
+	_, err = s.Get(ctx, "", "a")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	_, err = s.Get(ctx, "", "d")
+	require.NoError(t, err)
+	userURLs, err := s.GetAllURL(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, userURLs, 2)
+
+	stats, err := s.GetState(ctx, models.StatsQuery{})
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.URLs)
+	require.Equal(t, 2, stats.Users)
+
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	_, err = s.Get(ctx, "", "a")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "e", OriginalURL: "https://github.com/a"})
+	require.NoError(t, err)
+
+	s.Close()
+	s, err = redis.New(ctx, cfg)
+	require.NoError(t, err)
+	defer s.Close()
+	urlsList, err := s.GetAllURL(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, urlsList, 3)
+	require.NoError(t, s.Ping(ctx))
+}
+
+func TestStore_HardDeleteBatches(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	s, err := redis.New(ctx, &redis.Config{URL: "redis://" + srv.Addr()})
+	require.NoError(t, err)
+	defer s.Close()
+
+	const count = 1200
+	batch := make([]models.ShortLink, 0, count)
+	for i := range count {
+		batch = append(batch, models.ShortLink{
+			ShortURL:    "s" + strconv.Itoa(i),
+			OriginalURL: "https://github.com/" + strconv.Itoa(i),
+		})
+	}
+	_, err = s.SetBatch(ctx, "1", batch)
+	require.NoError(t, err)
+	for i := range batch {
+		batch[i].UserID = "1"
+	}
+	_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: "live", OriginalURL: "https://github.com/live"})
+	require.NoError(t, err)
+	deleted, err := s.DeleteShortURLs(ctx, batch)
+	require.NoError(t, err)
+	require.Len(t, deleted, count)
+	_, err = srv.SAdd("short:deleted", "short:link:/missing")
+	require.NoError(t, err)
+
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	require.False(t, srv.Exists("short:deleted"))
+	members, err := srv.ZMembers("short:user:1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"/live"}, members)
+	_, err = s.Get(ctx, "", "s0")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = s.Get(ctx, "", "live")
+	require.NoError(t, err)
+}
+
+func TestStore_InitStats(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	cfg := &redis.Config{URL: "redis://" + srv.Addr()}
+	s, err := redis.New(ctx, cfg)
+	require.NoError(t, err)
+	for _, short := range []string{"a", "b"} {
+		_, err = s.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://github.com/" + short})
+		require.NoError(t, err)
+	}
+	_, err = s.Set(ctx, "2", models.ShortLink{ShortURL: "c", OriginalURL: "https://github.com/c"})
+	require.NoError(t, err)
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "c", UserID: "2"}})
+	require.NoError(t, err)
+	s.Close()
+
+	// счетчики, которых нет у хранилища предыдущей версии, пересчитываются при запуске.
+	srv.Del("short:stats")
+	srv.Del("short:stats:users")
+	srv.Del("short:stats:days")
+	s, err = redis.New(ctx, cfg)
+	require.NoError(t, err)
+	defer s.Close()
+	stats, err := s.GetState(ctx, models.StatsQuery{Top: 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.URLs)
+	require.Equal(t, 1, stats.Users)
+	require.Equal(t, 1, stats.Deleted)
+	require.Equal(t, []models.UserCount{{UserID: "1", URLs: 2}}, stats.TopUsers)
+	require.Len(t, stats.CreatedPerDay, 1)
+	require.Equal(t, 2, stats.CreatedPerDay[0].Count)
+}
+
+func TestNew_InvalidURL(t *testing.T) {
+	_, err := redis.New(context.Background(), &redis.Config{URL: "localhost"})
+	require.Error(t, err)
+}
+
+func TestStore_Unavailable(t *testing.T) {
+	srv := miniredis.RunT(t)
+	s, err := redis.New(context.Background(), &redis.Config{URL: "redis://" + srv.Addr()})
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	srv.Close()
+
+	_, err = s.Get(context.Background(), "", "abc")
+	require.ErrorIs(t, err, storeerror.ErrUnavailable)
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		s, _ := createRedisStorage(t)
+		return s
+	})
+}
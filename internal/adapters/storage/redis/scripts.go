@@ -0,0 +1,138 @@
+package redis
+
+import goredis "github.com/redis/go-redis/v9"
+
+// Ответы скриптов сохранения.
+const (
+	setOK        = 0 // ссылка сохранена.
+	setNotUnique = 1 // оригинальная ссылка уже сокращена пользователем.
+	setDuplicate = 2 // короткий код занят.
+)
+
+// scriptSetBatch атомарно сохраняет ссылки пользователя и обновляет счетчики статистики.
+// KEYS: индекс оригинальных ссылок, индекс ссылок пользователя, счетчик, счетчики статистики,
+// ссылки по пользователям, ссылки по суткам создания, ключи ссылок.
+// ARGV: пользователь, затем на каждую ссылку: поле индекса, короткий код, оригинал, домен,
+// код перенаправления, элемент индекса, время создания (Unix секунды), слепой индекс оригинала.
+// Возвращает {статус, номер ссылки, короткий код}.
+var scriptSetBatch = goredis.NewScript(`
+local n = #KEYS - 6
+for i = 1, n do
+	local base = 1 + (i - 1) * 8
+	local existing = redis.call('HGET', KEYS[1], ARGV[base + 1])
+	if existing then
+		return {1, i, existing}
+	end
+	if redis.call('EXISTS', KEYS[6 + i]) == 1 then
+		return {2, i, ''}
+	end
+	for j = 1, i - 1 do
+		if KEYS[6 + j] == KEYS[6 + i] then
+			return {2, i, ''}
+		end
+		if ARGV[1 + (j - 1) * 8 + 1] == ARGV[base + 1] then
+			return {1, i, ARGV[1 + (j - 1) * 8 + 2]}
+		end
+	end
+end
+for i = 1, n do
+	local base = 1 + (i - 1) * 8
+	local seq = redis.call('INCR', KEYS[3])
+	redis.call('HSET', KEYS[6 + i],
+		'user_id', ARGV[1],
+		'short_url', ARGV[base + 2],
+		'original_url', ARGV[base + 3],
+		'original_hash', ARGV[base + 8],
+		'domain', ARGV[base + 4],
+		'redirect_code', ARGV[base + 5],
+		'is_deleted', '0',
+		'seq', seq,
+		'created_at', ARGV[base + 7])
+	redis.call('HSET', KEYS[1], ARGV[base + 1], ARGV[base + 2])
+	redis.call('ZADD', KEYS[2], seq, ARGV[base + 6])
+	redis.call('HINCRBY', KEYS[6], math.floor(tonumber(ARGV[base + 7]) / 86400), 1)
+end
+redis.call('HINCRBY', KEYS[4], 'urls', n)
+redis.call('ZINCRBY', KEYS[5], n, ARGV[1])
+return {0, 0, ''}
+`)
+
+// scriptDelete мягко удаляет ссылку пользователя и обновляет счетчики статистики.
+// KEYS: ключ ссылки, индекс оригинальных ссылок, множество удаленных, счетчики статистики,
+// ссылки по пользователям, ссылки по суткам создания.
+// ARGV: пользователь, поле индекса оригинальных ссылок.
+var scriptDelete = goredis.NewScript(`
+local link = redis.call('HMGET', KEYS[1], 'user_id', 'is_deleted', 'short_url', 'created_at')
+if link[1] ~= ARGV[1] or link[2] ~= '0' then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'is_deleted', '1')
+redis.call('SADD', KEYS[3], KEYS[1])
+if redis.call('HGET', KEYS[2], ARGV[2]) == link[3] then
+	redis.call('HDEL', KEYS[2], ARGV[2])
+end
+redis.call('HINCRBY', KEYS[4], 'urls', -1)
+if tonumber(redis.call('ZINCRBY', KEYS[5], -1, ARGV[1])) <= 0 then
+	redis.call('ZREM', KEYS[5], ARGV[1])
+end
+if link[4] then
+	local day = math.floor(tonumber(link[4]) / 86400)
+	if redis.call('HINCRBY', KEYS[6], day, -1) <= 0 then
+		redis.call('HDEL', KEYS[6], day)
+	end
+end
+return 1
+`)
+
+// scriptPurge удаляет часть помеченных ссылок и их элементы в индексах пользователей.
+// KEYS: множество удаленных, затем на каждую ссылку ключ ссылки и индекс ссылок пользователя.
+// ARGV: на каждую ссылку пользователь и элемент индекса ссылок пользователя.
+// Ссылка, пользователь которой изменился после чтения, остается до следующей очистки.
+// Возвращает количество ключей, убранных из множества удаленных.
+var scriptPurge = goredis.NewScript(`
+local n = 0
+for i = 1, (#KEYS - 1) / 2 do
+	local key = KEYS[2 * i]
+	local link = redis.call('HMGET', key, 'user_id', 'is_deleted')
+	if link[1] == ARGV[2 * i - 1] and link[2] == '1' then
+		redis.call('ZREM', KEYS[2 * i + 1], ARGV[2 * i])
+		redis.call('DEL', key)
+		redis.call('SREM', KEYS[1], key)
+		n = n + 1
+	elseif not link[1] or link[2] ~= '1' then
+		redis.call('SREM', KEYS[1], key)
+		n = n + 1
+	end
+end
+return n
+`)
+
+// Ответы скрипта замены оригинальной ссылки.
+const (
+	rewriteSkipped   = 0 // оригинальная ссылка изменилась или ссылки нет.
+	rewriteOK        = 1 // оригинальная ссылка заменена.
+	rewriteNotUnique = 2 // у пользователя уже есть ссылка с новой оригинальной ссылкой.
+)
+
+// scriptRewrite заменяет оригинальную ссылку и ее слепой индекс, если оригинальная ссылка не изменилась.
+// KEYS: ключ ссылки, индекс оригинальных ссылок.
+// ARGV: прежняя оригинальная ссылка, новая оригинальная ссылка, прежнее и новое поле индекса оригинальных ссылок,
+// новый слепой индекс.
+var scriptRewrite = goredis.NewScript(`
+local link = redis.call('HMGET', KEYS[1], 'original_url', 'is_deleted', 'short_url')
+if link[1] ~= ARGV[1] then
+	return 0
+end
+if link[2] == '0' then
+	local existing = redis.call('HGET', KEYS[2], ARGV[4])
+	if existing and existing ~= link[3] then
+		return 2
+	end
+	if redis.call('HGET', KEYS[2], ARGV[3]) == link[3] then
+		redis.call('HDEL', KEYS[2], ARGV[3])
+	end
+	redis.call('HSET', KEYS[2], ARGV[4], link[3])
+end
+redis.call('HSET', KEYS[1], 'original_url', ARGV[2], 'original_hash', ARGV[5])
+return 1
+`)
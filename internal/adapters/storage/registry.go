@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/storage/crypt"
+)
+
+// Factory создает хранилище по DSN.
+// cfg содержит типизированные настройки хранилищ, параметры из строки запроса DSN имеют приоритет над ними.
+type Factory func(ctx context.Context, dsn string, cfg *Config, log *zap.Logger) (Store, error)
+
+var (
+	driversMu = &sync.RWMutex{}
+	drivers   = map[string]Factory{}
+)
+
+// Register регистрирует драйвер хранилища для схемы DSN.
+// Паникует, если драйвер для схемы уже зарегистрирован.
+func Register(scheme string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	scheme = strings.ToLower(scheme)
+	if factory == nil {
+		panic("storage: register nil factory for scheme " + scheme)
+	}
+	if _, ok := drivers[scheme]; ok {
+		panic("storage: register called twice for scheme " + scheme)
+	}
+	drivers[scheme] = factory
+}
+
+// Drivers возвращает отсортированный список зарегистрированных схем.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	schemes := make([]string, 0, len(drivers))
+	for scheme := range drivers {
+		schemes = append(schemes, scheme)
+	}
+	slices.Sort(schemes)
+	return schemes
+}
+
+// Open создает хранилище драйвером, зарегистрированным для схемы DSN.
+func Open(ctx context.Context, dsn string, cfg *Config, log *zap.Logger) (Store, error) {
+	scheme, _, ok := strings.Cut(dsn, ":")
+	if !ok || scheme == "" {
+		return nil, fmt.Errorf("invalid storage dsn `%s`", dsn)
+	}
+	return open(ctx, strings.ToLower(scheme), dsn, cfg, log)
+}
+
+func open(ctx context.Context, scheme, dsn string, cfg *Config, log *zap.Logger) (Store, error) {
+	driversMu.RLock()
+	factory, ok := drivers[scheme]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage scheme `%s`", scheme)
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if log == nil {
+		log = zap.NewNop()
+	}
+	var keys *crypt.Keyring
+	if cfg.Encryption != nil && cfg.Encryption.Enabled() {
+		var err error
+		if keys, err = crypt.NewKeyring(cfg.Encryption); err != nil {
+			return nil, fmt.Errorf("failed initialize encryption: %w", err)
+		}
+	}
+	store, err := factory(ctx, dsn, cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed initialize %s storage: %w", scheme, err)
+	}
+	if keys != nil {
+		log.Info("url encryption enabled", zap.String("active_key", keys.ActiveKey()))
+		return crypt.New(store, keys,
+			crypt.SetLogger(log),
+			crypt.SetReencryptInterval(cfg.Encryption.ReencryptInterval),
+		), nil
+	}
+	return store, nil
+}
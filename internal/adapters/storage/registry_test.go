@@ -0,0 +1,137 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/bolt"
+	"github.com/playmixer/short-link/internal/adapters/storage/database"
+	"github.com/playmixer/short-link/internal/adapters/storage/file"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/redis"
+	"github.com/playmixer/short-link/internal/adapters/storage/sqlite"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	redisSrv := miniredis.RunT(t)
+	tests := []struct {
+		name string
+		dsn  string
+		want storage.Store
+	}{
+		{name: "memory", dsn: "memory://", want: &memory.Store{}},
+		{name: "file", dsn: "file://" + filepath.Join(dir, "data.json"), want: &file.Store{}},
+		{
+			name: "file options",
+			dsn:  "file://" + filepath.Join(dir, "opts.json") + "?sync=interval&sync_interval=100ms&compact_interval=1h",
+			want: &file.Store{},
+		},
+		{name: "bolt", dsn: "bolt://" + filepath.Join(dir, "data.db"), want: &bolt.Store{}},
+		{name: "sqlite", dsn: "sqlite://" + filepath.Join(dir, "data.sqlite"), want: &sqlite.Store{}},
+		{name: "redis", dsn: "redis://" + redisSrv.Addr() + "/0?prefix=test:", want: &redis.Store{}},
+		{name: "scheme case", dsn: "MEMORY://", want: &memory.Store{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := storage.Open(context.Background(), tt.dsn, &storage.Config{}, zap.NewNop())
+			require.NoError(t, err)
+			t.Cleanup(got.Close)
+			require.IsType(t, tt.want, got)
+		})
+	}
+}
+
+func TestOpenErr(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		dsn  string
+	}{
+		{name: "no scheme", dsn: "data.json"},
+		{name: "unknown scheme", dsn: "mongodb://localhost"},
+		{name: "unknown option", dsn: "file://" + filepath.Join(dir, "data.json") + "?fsync=none"},
+		{name: "invalid duration", dsn: "file://" + filepath.Join(dir, "data.json") + "?sync_interval=often"},
+		{name: "invalid sync policy", dsn: "file://" + filepath.Join(dir, "data.json") + "?sync=sometimes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := storage.Open(context.Background(), tt.dsn, nil, zap.NewNop())
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	var gotDSN string
+	storage.Register("test-registry", func(ctx context.Context, dsn string, cfg *storage.Config, log *zap.Logger) (storage.Store, error) {
+		gotDSN = dsn
+		return memory.New(&memory.Config{})
+	})
+	require.Contains(t, storage.Drivers(), "test-registry")
+
+	store, err := storage.NewStore(context.Background(), &storage.Config{DSN: "test-registry://host?x=1"}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(store.Close)
+	require.IsType(t, &memory.Store{}, store)
+	require.Equal(t, "test-registry://host?x=1", gotDSN)
+
+	require.Panics(t, func() {
+		storage.Register("test-registry", func(ctx context.Context, dsn string, cfg *storage.Config, log *zap.Logger) (storage.Store, error) {
+			return nil, nil
+		})
+	})
+	require.Panics(t, func() { storage.Register("memory", nil) })
+}
+
+func TestNewStore_DSNOverridesAliases(t *testing.T) {
+	cfg := &storage.Config{
+		DSN:      "bolt://" + filepath.Join(t.TempDir(), "data.db"),
+		Memory:   &memory.Config{},
+		Database: &database.Config{},
+	}
+	store, err := storage.NewStore(context.Background(), cfg, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(store.Close)
+	require.IsType(t, &bolt.Store{}, store)
+}
+
+func TestConfig_DatabaseDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  storage.Config
+		want string
+	}{
+		{
+			name: "storage dsn",
+			cfg:  storage.Config{DSN: "postgres://u:p@localhost/short?sslmode=disable&cache_size=100&replica=postgres://r/short"},
+			want: "postgres://u:p@localhost/short?sslmode=disable",
+		},
+		{
+			name: "alias",
+			cfg:  storage.Config{Database: &database.Config{DSN: "host=localhost dbname=short"}},
+			want: "host=localhost dbname=short",
+		},
+		{
+			name: "sqlite",
+			cfg:  storage.Config{DSN: "sqlite:///tmp/short.sqlite"},
+			want: "",
+		},
+		{
+			name: "not database",
+			cfg:  storage.Config{DSN: "memory://", Database: &database.Config{DSN: "postgres://localhost/short"}},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.cfg.DatabaseDSN())
+		})
+	}
+}
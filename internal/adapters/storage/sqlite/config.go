@@ -0,0 +1,7 @@
+package sqlite
+
+// Config конфигурация подключения к SQLite.
+// DSN берется из DATABASE_DSN, если он задан со схемой sqlite://.
+type Config struct {
+	DSN string
+}
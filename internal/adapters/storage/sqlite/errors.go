@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// classify переводит ошибки SQLite в ошибки хранилища.
+// Расширенные коды ошибок сравниваются по основному коду.
+func classify(err error) error {
+	if err == nil || storeerror.KindOf(err) != storeerror.KindUnknown {
+		return err
+	}
+	var sqlErr *sqlite.Error
+	if errors.As(err, &sqlErr) {
+		switch sqlErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return fmt.Errorf("%w: %w", storeerror.ErrTimeout, err)
+		case sqlite3.SQLITE_IOERR, sqlite3.SQLITE_FULL, sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_READONLY:
+			return fmt.Errorf("%w: %w", storeerror.ErrUnavailable, err)
+		}
+	}
+	if errors.Is(err, sql.ErrConnDone) {
+		return fmt.Errorf("%w: %w", storeerror.ErrUnavailable, err)
+	}
+	return storeerror.Classify(err)
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS short_link_user_id_original_url_idx;
+DROP INDEX IF EXISTS short_link_domain_short_url_idx;
+DROP TABLE IF EXISTS short_link;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS short_link (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	domain TEXT DEFAULT '' NOT NULL,
+	short_url TEXT NOT NULL,
+	original_url TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	redirect_code INTEGER DEFAULT 0 NOT NULL,
+	is_deleted INTEGER DEFAULT 0 NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS short_link_domain_short_url_idx ON short_link (domain, short_url);
+CREATE INDEX IF NOT EXISTS short_link_user_id_original_url_idx ON short_link (user_id, domain, original_url);
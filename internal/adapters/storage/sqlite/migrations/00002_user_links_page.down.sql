@@ -0,0 +1 @@
+DROP INDEX IF EXISTS short_link_user_id_id_idx;
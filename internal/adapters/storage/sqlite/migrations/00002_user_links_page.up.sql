@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS short_link_user_id_id_idx ON short_link (user_id, id);
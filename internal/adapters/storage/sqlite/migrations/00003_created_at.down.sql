@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS short_link_created_at_idx;
+ALTER TABLE short_link DROP COLUMN created_at;
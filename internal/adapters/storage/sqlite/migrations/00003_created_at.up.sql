@@ -0,0 +1,2 @@
+ALTER TABLE short_link ADD COLUMN created_at INTEGER DEFAULT 0 NOT NULL;
+CREATE INDEX IF NOT EXISTS short_link_created_at_idx ON short_link (created_at) WHERE is_deleted = false;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS short_link_user_id_original_hash_idx;
+ALTER TABLE short_link DROP COLUMN original_hash;
@@ -0,0 +1,2 @@
+ALTER TABLE short_link ADD COLUMN original_hash TEXT DEFAULT '' NOT NULL;
+CREATE INDEX IF NOT EXISTS short_link_user_id_original_hash_idx ON short_link (user_id, domain, original_hash) WHERE original_hash != '';
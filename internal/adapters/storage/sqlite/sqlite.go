@@ -0,0 +1,481 @@
+// Модуль sqlite реализует хранилище ссылок в SQLite.
+// Семантика уникальности, мягкого удаления и статистики совпадает с хранилищем Postgres.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// Scheme - схема DSN хранилища SQLite.
+const Scheme = "sqlite://"
+
+const (
+	busyTimeout = "_pragma=busy_timeout(5000)" // ожидание блокировки базы, мс.
+	walMode     = "_pragma=journal_mode(WAL)"  // журнал WAL: чтение не блокирует запись.
+)
+
+// IsDSN проверяет, что DSN указывает на базу SQLite.
+func IsDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, Scheme)
+}
+
+// Store имплементация хранилища SQLite.
+type Store struct {
+	db   *sql.DB // соединение записи.
+	read *sql.DB // соединения обхода ссылок.
+}
+
+// New создает Store. DSN задается в виде sqlite://path.
+func New(ctx context.Context, cfg *Config) (*Store, error) {
+	if !IsDSN(cfg.DSN) {
+		return nil, fmt.Errorf("invalid sqlite dsn `%s`", cfg.DSN)
+	}
+	path := strings.TrimPrefix(cfg.DSN, Scheme)
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed create path of sqlite storage: %w", err)
+	}
+
+	if err := RunMigrations(cfg.DSN); err != nil {
+		return nil, fmt.Errorf("failed initialize tables: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", path+"?"+busyTimeout+"&"+walMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed open database: %w", err)
+	}
+	// SQLite допускает одного писателя, общее соединение исключает ошибки блокировки.
+	db.SetMaxOpenConns(1)
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed open database: %w", err)
+	}
+	// обход ссылок идет через отдельные соединения: в режиме WAL чтение видит снимок базы
+	// и не занимает соединение записи, поэтому запросы и обработчик обхода не ждут его окончания.
+	read, err := sql.Open("sqlite", path+"?"+busyTimeout)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed open database: %w", err)
+	}
+	if err = read.PingContext(ctx); err != nil {
+		_ = read.Close()
+		_ = db.Close()
+		return nil, fmt.Errorf("failed open database: %w", err)
+	}
+
+	return &Store{db: db, read: read}, nil
+}
+
+// Close - закрыть соединение.
+func (s *Store) Close() {
+	_ = s.read.Close()
+	_ = s.db.Close()
+}
+
+func isUniqueViolation(err error) bool {
+	var sqlErr *sqlite.Error
+	return errors.As(err, &sqlErr) && sqlErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// Set Сохраняет ссылку.
+func (s *Store) Set(ctx context.Context, userID string, link models.ShortLink) (output string, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+	output, err = s.getByOriginal(ctx, tx, userID, link.Domain, link.OriginalURL, link.OriginalHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return output, fmt.Errorf("failed select URL %s %w", link.OriginalURL, classify(err))
+	}
+	if output != "" {
+		return output, fmt.Errorf("url `%s` is not unique: %w", link.OriginalURL, storeerror.ErrNotUnique)
+	}
+
+	if err = insert(ctx, tx, userID, link); err != nil {
+		return "", err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return "", fmt.Errorf("failed committing transaction: %w", classify(err))
+	}
+	return link.ShortURL, nil
+}
+
+func insert(ctx context.Context, tx *sql.Tx, userID string, link models.ShortLink) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`insert into short_link (short_url, original_url, original_hash, user_id, domain, redirect_code, created_at)
+values (?, ?, ?, ?, ?, ?, ?)`,
+		link.ShortURL, link.OriginalURL, link.OriginalHash, userID, link.Domain, link.RedirectCode,
+		link.CreationTime(time.Now()).Unix(),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("sqlite error: %w: %w", storeerror.ErrDuplicateShortURL, err)
+		}
+		return fmt.Errorf("failed setting short url: %w", classify(err))
+	}
+	return nil
+}
+
+// Get Возвращает ссылку.
+func (s *Store) Get(ctx context.Context, domain, short string) (models.ShortLink, error) {
+	row := s.db.QueryRowContext(ctx,
+		`select original_url, original_hash, user_id, redirect_code, is_deleted, created_at
+from short_link where domain = ? and short_url = ?`,
+		domain, short,
+	)
+	link := models.ShortLink{ShortURL: short, Domain: domain}
+	var createdAt int64
+	err := row.Scan(&link.OriginalURL, &link.OriginalHash, &link.UserID, &link.RedirectCode, &link.IsDeleted, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ShortLink{}, storeerror.ErrNotFoundKey
+		}
+		return models.ShortLink{}, fmt.Errorf("failed scan url: %w", classify(err))
+	}
+	link.CreatedAt = models.UnixTime(createdAt)
+	if link.IsDeleted {
+		return link, storeerror.ErrShortURLDeleted
+	}
+	return link, nil
+}
+
+// SetBatch Сохраняет список ссылок.
+func (s *Store) SetBatch(ctx context.Context, userID string, data []models.ShortLink) (
+	output []models.ShortLink,
+	reserr error,
+) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return []models.ShortLink{}, fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+	type originalKey struct{ domain, original, hash string }
+	originals := make(map[originalKey]string, len(data))
+	for _, d := range data {
+		key := originalKey{d.Domain, d.OriginalURL, d.OriginalHash}
+		if d.OriginalHash != "" {
+			key.original = ""
+		}
+		if short, ok := originals[key]; ok {
+			return []models.ShortLink{{ShortURL: short, OriginalURL: d.OriginalURL, Domain: d.Domain}},
+				fmt.Errorf("URL `%s` is not unique: %w", d.OriginalURL, storeerror.ErrNotUnique)
+		}
+		originals[key] = d.ShortURL
+
+		short, err := s.getByOriginal(ctx, tx, userID, d.Domain, d.OriginalURL, d.OriginalHash)
+		if err != nil && errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return []models.ShortLink{}, fmt.Errorf("failed getting URL `%s`: %w", d.OriginalURL, classify(err))
+		}
+		if short != "" {
+			return []models.ShortLink{{ShortURL: short, OriginalURL: d.OriginalURL, Domain: d.Domain}},
+				fmt.Errorf("URL `%s` is not unique: %w", d.OriginalURL, storeerror.ErrNotUnique)
+		}
+	}
+
+	output = make([]models.ShortLink, 0, len(data))
+	for _, v := range data {
+		if err = insert(ctx, tx, userID, v); err != nil {
+			return []models.ShortLink{}, fmt.Errorf("failed insert URL %s: %w", v.OriginalURL, classify(err))
+		}
+		output = append(output, v)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return []models.ShortLink{}, fmt.Errorf("failed commit transaction: %w", classify(err))
+	}
+	return output, nil
+}
+
+// getByOriginal возвращает короткий код неудаленной ссылки пользователя: со слепым индексом hash,
+// если он задан, иначе без слепого индекса с оригинальной ссылкой original.
+func (s *Store) getByOriginal(ctx context.Context, tx *sql.Tx, userID, domain, original, hash string) (string, error) {
+	query := `select short_url from short_link
+where original_url = ? and original_hash = '' and user_id = ? and domain = ? and is_deleted = false`
+	if hash != "" {
+		query = `select short_url from short_link
+where original_hash = ? and user_id = ? and domain = ? and is_deleted = false`
+		original = hash
+	}
+	row := tx.QueryRowContext(ctx, query, original, userID, domain)
+	var value string
+	err := row.Scan(&value)
+	if err != nil {
+		return "", fmt.Errorf("failed scan url %w", classify(err))
+	}
+	return value, nil
+}
+
+// Ping Проверка соединения с хранилищем.
+func (s *Store) Ping(ctx context.Context) error {
+	err := s.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed ping database: %w", classify(err))
+	}
+
+	return nil
+}
+
+// GetAllURL Возвращает все ссылки пользователя.
+func (s *Store) GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error) {
+	result := []models.ShortenURL{}
+	rows, err := s.db.QueryContext(ctx,
+		"select short_url, original_url, domain from short_link where user_id = ? and is_deleted = false order by id",
+		userID)
+	if err != nil {
+		return result, fmt.Errorf("failed selecting all URLs by user: %w", classify(err))
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		value := models.ShortenURL{}
+		err := rows.Scan(&value.ShortURL, &value.OriginalURL, &value.Domain)
+		if err != nil {
+			return result, fmt.Errorf("failed scan url %w", classify(err))
+		}
+		result = append(result, value)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("failed read URLs by user: %w", classify(err))
+	}
+	return result, nil
+}
+
+// GetUserURLs Возвращает страницу ссылок пользователя.
+func (s *Store) GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error) {
+	result := []models.ShortenURL{}
+	rows, err := s.db.QueryContext(ctx,
+		`select id, short_url, original_url, domain from short_link
+where user_id = ? and is_deleted = false and id > ? order by id limit ?`,
+		userID, page.After, page.Limit)
+	if err != nil {
+		return result, fmt.Errorf("failed selecting URLs by user: %w", classify(err))
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		value := models.ShortenURL{}
+		err := rows.Scan(&value.ID, &value.ShortURL, &value.OriginalURL, &value.Domain)
+		if err != nil {
+			return result, fmt.Errorf("failed scan url %w", classify(err))
+		}
+		result = append(result, value)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("failed read URLs by user: %w", classify(err))
+	}
+	return result, nil
+}
+
+// DeleteShortURLs Мягкое удаляет ссылки.
+func (s *Store) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+	deleted := make([]models.ShortLink, 0, len(shorts))
+	for _, v := range shorts {
+		res, err := tx.ExecContext(ctx,
+			`update short_link set is_deleted = true
+where user_id = ? and domain = ? and short_url = ? and is_deleted = false`,
+			v.UserID, v.Domain, v.ShortURL,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed deleting short url `%s`: %w", v.ShortURL, classify(err))
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed deleting short url `%s`: %w", v.ShortURL, classify(err))
+		}
+		if n > 0 {
+			deleted = append(deleted, v)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed commit transaction: %w", classify(err))
+	}
+	return deleted, nil
+}
+
+// HardDeleteURLs Хард удаление ссылок.
+func (s *Store) HardDeleteURLs(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `delete from short_link where is_deleted = true`)
+	if err != nil {
+		return fmt.Errorf("failed hard deleting URLs: %w", classify(err))
+	}
+	return nil
+}
+
+// GetState Получение статисики.
+func (s *Store) GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error) {
+	stats := models.ShortenStats{CreatedPerDay: []models.DailyCount{}, TopUsers: []models.UserCount{}}
+	row := s.db.QueryRowContext(ctx,
+		`select count(*) filter (where not is_deleted), count(distinct user_id) filter (where not is_deleted),
+count(*) filter (where is_deleted), (select page_count * page_size from pragma_page_count(), pragma_page_size())
+from short_link`,
+	)
+	if err := row.Scan(&stats.URLs, &stats.Users, &stats.Deleted, &stats.StorageBytes); err != nil {
+		return models.ShortenStats{}, fmt.Errorf("failed get stats: %w", classify(err))
+	}
+
+	// окно выбирается по частичному индексу short_link_created_at_idx.
+	rows, err := s.db.QueryContext(ctx,
+		`select date(created_at, 'unixepoch') as day, count(*)
+from short_link where is_deleted = false and created_at >= ? group by day order by day`,
+		query.Since.Unix(),
+	)
+	if err != nil {
+		return models.ShortenStats{}, fmt.Errorf("failed get links per day: %w", classify(err))
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var day models.DailyCount
+		if err = rows.Scan(&day.Day, &day.Count); err != nil {
+			return models.ShortenStats{}, fmt.Errorf("failed scan links per day: %w", classify(err))
+		}
+		stats.CreatedPerDay = append(stats.CreatedPerDay, day)
+	}
+	if err = rows.Err(); err != nil {
+		return models.ShortenStats{}, fmt.Errorf("failed get links per day: %w", classify(err))
+	}
+
+	if query.Top <= 0 {
+		return stats, nil
+	}
+	users, err := s.db.QueryContext(ctx,
+		`select user_id, count(*) as urls from short_link where is_deleted = false
+group by user_id order by urls desc, user_id limit ?`,
+		query.Top,
+	)
+	if err != nil {
+		return models.ShortenStats{}, fmt.Errorf("failed get top users: %w", classify(err))
+	}
+	defer func() { _ = users.Close() }()
+	for users.Next() {
+		var user models.UserCount
+		if err = users.Scan(&user.UserID, &user.URLs); err != nil {
+			return models.ShortenStats{}, fmt.Errorf("failed scan top users: %w", classify(err))
+		}
+		stats.TopUsers = append(stats.TopUsers, user)
+	}
+	if err = users.Err(); err != nil {
+		return models.ShortenStats{}, fmt.Errorf("failed get top users: %w", classify(err))
+	}
+	return stats, nil
+}
+
+// FindOriginal возвращает короткий код неудаленной ссылки пользователя без слепого индекса
+// с оригинальной ссылкой original.
+func (s *Store) FindOriginal(ctx context.Context, userID, domain, original string) (string, error) {
+	row := s.db.QueryRowContext(ctx,
+		`select short_url from short_link
+where user_id = ? and domain = ? and is_deleted = false and original_url = ? and original_hash = '' limit 1`,
+		userID, domain, original,
+	)
+	var short string
+	if err := row.Scan(&short); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storeerror.ErrNotFoundKey
+		}
+		return "", fmt.Errorf("failed find original: %w", classify(err))
+	}
+	return short, nil
+}
+
+// RewriteOriginalURL заменяет оригинальную ссылку и ее слепой индекс, если ссылка не изменилась с момента чтения link.
+func (s *Store) RewriteOriginalURL(ctx context.Context, link models.ShortLink, original, hash string) (bool, error) {
+	if link.OriginalURL == original {
+		return false, nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed begin transaction: %w", classify(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var userID string
+	var isDeleted bool
+	err = tx.QueryRowContext(ctx,
+		`select user_id, is_deleted from short_link where domain = ? and short_url = ? and original_url = ?`,
+		link.Domain, link.ShortURL, link.OriginalURL,
+	).Scan(&userID, &isDeleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed select link: %w", classify(err))
+	}
+	if !isDeleted {
+		short, err := s.getByOriginal(ctx, tx, userID, link.Domain, original, hash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+		if short != "" && short != link.ShortURL {
+			return false, fmt.Errorf("rewrite short url `%s`: %w", link.ShortURL, storeerror.ErrNotUnique)
+		}
+	}
+	_, err = tx.ExecContext(ctx,
+		`update short_link set original_url = ?, original_hash = ? where domain = ? and short_url = ?`,
+		original, hash, link.Domain, link.ShortURL,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed rewrite original url: %w", classify(err))
+	}
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed commit transaction: %w", classify(err))
+	}
+	return true, nil
+}
+
+// Snapshot обходит все ссылки в порядке добавления. Обход выполняется одним запросом,
+// который видит состояние базы на момент своего начала. Обработчик может изменять хранилище.
+func (s *Store) Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error {
+	return s.Walk(ctx, fn)
+}
+
+// Walk обходит все ссылки в порядке добавления.
+func (s *Store) Walk(ctx context.Context, fn func(link models.ShortLink) error) error {
+	rows, err := s.read.QueryContext(ctx,
+		`select short_url, original_url, original_hash, user_id, domain, redirect_code, is_deleted, created_at
+from short_link order by id`)
+	if err != nil {
+		return fmt.Errorf("failed selecting links: %w", classify(err))
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var link models.ShortLink
+		var createdAt int64
+		err := rows.Scan(&link.ShortURL, &link.OriginalURL, &link.OriginalHash, &link.UserID, &link.Domain,
+			&link.RedirectCode, &link.IsDeleted, &createdAt)
+		if err != nil {
+			return fmt.Errorf("failed scan link: %w", classify(err))
+		}
+		link.CreatedAt = models.UnixTime(createdAt)
+		if err = fn(link); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed read links: %w", classify(err))
+	}
+	return nil
+}
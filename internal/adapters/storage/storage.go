@@ -3,73 +3,122 @@ package storage
 import (
 	"context"
 	"errors"
-	"fmt"
+	"strings"
 
 	"go.uber.org/zap"
 
 	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/bolt"
+	"github.com/playmixer/short-link/internal/adapters/storage/cache"
+	"github.com/playmixer/short-link/internal/adapters/storage/crypt"
 	"github.com/playmixer/short-link/internal/adapters/storage/database"
 	"github.com/playmixer/short-link/internal/adapters/storage/file"
 	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/redis"
+	"github.com/playmixer/short-link/internal/adapters/storage/sqlite"
 )
 
 // Config - конфигурация хранилища.
 // Хранилище создастся того типа в зависимости какой параметр будет передан.
+// Параметры отдельных хранилищ остаются псевдонимами STORAGE_DSN и значениями по умолчанию для его параметров.
 type Config struct {
+	// DSN - адрес хранилища, схема выбирает драйвер: memory://, file:///path, bolt:///path,
+	// sqlite:///path, postgres://..., redis://... Имеет приоритет над параметрами отдельных хранилищ.
+	DSN      string           `env:"STORAGE_DSN"`
 	Memory   *memory.Config   // Memory - сохранение в памяти.
 	File     *file.Config     // File - хранение в файле.
 	Database *database.Config // Database - хранение в базе данных.
+	Bolt     *bolt.Config     // Bolt - хранение во встроенной базе bbolt.
+	Redis    *redis.Config    // Redis - хранение в Redis.
+	Cache    *cache.Config    // Cache - кэш переходов для хранения в базе данных.
+	// Encryption - шифрование оригинальных ссылок в любом хранилище.
+	Encryption *crypt.Config
 }
 
 // Store - интерефейс хранилища ссылок.
 type Store interface {
-	// Возвращает оригинальную ссылку.
-	Get(ctx context.Context, short string) (string, error)
+	// Возвращает оригинальную ссылку по домену и короткому коду.
+	Get(ctx context.Context, domain, short string) (models.ShortLink, error)
 	// Возвращает все ссылки пользователя.
 	GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error)
+	// Возвращает страницу неудаленных ссылок пользователя в порядке создания.
+	GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error)
 	// Сохраняет ссылку.
-	Set(ctx context.Context, userID string, short string, url string) (string, error)
+	Set(ctx context.Context, userID string, link models.ShortLink) (string, error)
 	// Сохраняет список ссылок.
 	SetBatch(ctx context.Context, userID string, batch []models.ShortLink) ([]models.ShortLink, error)
 	// Проверка соединения с хранилищем.
 	Ping(ctx context.Context) error
-	// Мягкое удаляет ссылки.
-	DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) error
-	GetState(ctx context.Context) (urls int, users int, err error)
+	// Мягкое удаляет ссылки пользователей. Возвращает ссылки из shorts, которые были удалены:
+	// отсутствующие, чужие и уже удаленные ссылки пропускаются.
+	DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error)
+	GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error)
 	// Хард удаление ссылок.
 	HardDeleteURLs(ctx context.Context) error
 	Close()
 }
 
+// Walker - хранилище, поддерживающее обход всех ссылок, включая удаленные.
+// Ссылки обходятся в постоянном порядке, что позволяет продолжить обход с заданной позиции.
+type Walker interface {
+	Walk(ctx context.Context, fn func(link models.ShortLink) error) error
+}
+
+// Snapshotter - хранилище, поддерживающее согласованный снимок ссылок.
+type Snapshotter interface {
+	// Snapshot обходит все ссылки, включая удаленные, в состоянии на момент начала снимка.
+	// Изменения, сделанные во время обхода, в снимок не попадают.
+	Snapshot(ctx context.Context, fn func(link models.ShortLink) error) error
+}
+
 // NewStore - Создает хранилище.
+// Хранилище выбирается по STORAGE_DSN, без него - по параметрам отдельных хранилищ в прежнем порядке.
 func NewStore(ctx context.Context, cfg *Config, log *zap.Logger) (Store, error) {
-	if cfg.Database != nil && cfg.Database.DSN != "" {
-		cfg.Database.SetLogger(log)
-		store, err := database.New(ctx, cfg.Database)
-		if err != nil {
-			return nil, fmt.Errorf("failed initialize database storage: %w", err)
-		}
-		log.Info("database storage initialized")
-		return store, nil
+	if cfg.DSN != "" {
+		return Open(ctx, cfg.DSN, cfg, log)
+	}
+	scheme, dsn := cfg.legacyDSN()
+	if scheme == "" {
+		return nil, errors.New("storage not found")
 	}
+	return open(ctx, scheme, dsn, cfg, log)
+}
 
-	if cfg.File != nil && cfg.File.StoragePath != "" {
-		store, err := file.New(cfg.File)
-		if err != nil {
-			return nil, fmt.Errorf("failed initialize file storage: %w", err)
-		}
-		log.Info("file storage initialized")
-		return store, nil
+// legacyDSN возвращает схему и DSN хранилища, заданного параметрами отдельных хранилищ.
+func (c *Config) legacyDSN() (scheme, dsn string) {
+	switch {
+	case c.Database != nil && sqlite.IsDSN(c.Database.DSN):
+		return "sqlite", c.Database.DSN
+	case c.Database != nil && c.Database.DSN != "":
+		return "postgres", c.Database.DSN
+	case c.Redis != nil && c.Redis.URL != "":
+		return "redis", c.Redis.URL
+	case c.Bolt != nil && c.Bolt.Path != "":
+		return "bolt", pathDSN("bolt", c.Bolt.Path)
+	case c.File != nil && c.File.StoragePath != "":
+		return "file", pathDSN("file", c.File.StoragePath)
+	case c.Memory != nil:
+		return "memory", "memory://"
 	}
+	return "", ""
+}
 
-	if cfg.Memory != nil {
-		store, err := memory.New(cfg.Memory)
-		if err != nil {
-			return nil, fmt.Errorf("failed initialize memory storage: %w", err)
+// DatabaseDSN возвращает DSN базы PostgreSQL без параметров хранилища или пустую строку,
+// если хранилище не использует PostgreSQL.
+func (c *Config) DatabaseDSN() string {
+	if c.DSN == "" {
+		if scheme, dsn := c.legacyDSN(); scheme == "postgres" {
+			return dsn
 		}
-		log.Info("memory storage initialized")
-		return store, nil
+		return ""
 	}
-
-	return nil, errors.New("storage not found")
+	scheme, _, _ := strings.Cut(c.DSN, ":")
+	if scheme = strings.ToLower(scheme); scheme != "postgres" && scheme != "postgresql" {
+		return ""
+	}
+	dbCfg := database.Config{}
+	if err := parsePostgresDSN(c.DSN, &dbCfg, &cache.Config{}); err != nil {
+		return c.DSN
+	}
+	return dbCfg.DSN
 }
@@ -3,15 +3,21 @@ package storage_test
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/playmixer/short-link/internal/adapters/models"
 	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/bolt"
 	"github.com/playmixer/short-link/internal/adapters/storage/database"
 	"github.com/playmixer/short-link/internal/adapters/storage/file"
 	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/redis"
+	"github.com/playmixer/short-link/internal/adapters/storage/sqlite"
 )
 
 func removeFileStorage(t *testing.T) {
@@ -23,6 +29,8 @@ func removeFileStorage(t *testing.T) {
 }
 func TestNewStore(t *testing.T) {
 	defer removeFileStorage(t)
+	dir := t.TempDir()
+	redisSrv := miniredis.RunT(t)
 	type args struct {
 		cfg *storage.Config
 		log *zap.Logger
@@ -51,6 +59,33 @@ func TestNewStore(t *testing.T) {
 			want:    &file.Store{},
 			wantErr: nil,
 		},
+		{
+			name: "bolt",
+			args: args{
+				cfg: &storage.Config{Bolt: &bolt.Config{Path: filepath.Join(dir, "data.db")}},
+				log: zap.NewNop(),
+			},
+			want:    &bolt.Store{},
+			wantErr: nil,
+		},
+		{
+			name: "sqlite",
+			args: args{
+				cfg: &storage.Config{Database: &database.Config{DSN: sqlite.Scheme + filepath.Join(dir, "data.sqlite")}},
+				log: zap.NewNop(),
+			},
+			want:    &sqlite.Store{},
+			wantErr: nil,
+		},
+		{
+			name: "redis",
+			args: args{
+				cfg: &storage.Config{Redis: &redis.Config{URL: "redis://" + redisSrv.Addr()}},
+				log: zap.NewNop(),
+			},
+			want:    &redis.Store{},
+			wantErr: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -90,3 +125,50 @@ func TestNewStoreErr(t *testing.T) {
 		})
 	}
 }
+
+func TestStore_GetUserURLs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	redisSrv := miniredis.RunT(t)
+	configs := map[string]*storage.Config{
+		"memory": {Memory: &memory.Config{}},
+		"file":   {File: &file.Config{StoragePath: filepath.Join(dir, "data.json")}},
+		"bolt":   {Bolt: &bolt.Config{Path: filepath.Join(dir, "data.db")}},
+		"sqlite": {Database: &database.Config{DSN: sqlite.Scheme + filepath.Join(dir, "data.sqlite")}},
+		"redis":  {Redis: &redis.Config{URL: "redis://" + redisSrv.Addr()}},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			store, err := storage.NewStore(ctx, cfg, zap.NewNop())
+			require.NoError(t, err)
+			t.Cleanup(store.Close)
+
+			shorts := []string{"a1", "a2", "a3", "a4", "a5"}
+			for _, short := range shorts {
+				_, err = store.Set(ctx, "1", models.ShortLink{ShortURL: short, OriginalURL: "https://" + short + ".ru/"})
+				require.NoError(t, err)
+			}
+			_, err = store.Set(ctx, "2", models.ShortLink{ShortURL: "b1", OriginalURL: "https://b1.ru/"})
+			require.NoError(t, err)
+			_, err = store.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a2", UserID: "1"}})
+			require.NoError(t, err)
+
+			got := []string{}
+			page := models.Page{Limit: 2}
+			for {
+				links, err := store.GetUserURLs(ctx, "1", page)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(links), page.Limit)
+				if len(links) == 0 {
+					break
+				}
+				for _, link := range links {
+					require.Greater(t, link.ID, page.After)
+					got = append(got, link.ShortURL)
+				}
+				page.After = links[len(links)-1].ID
+			}
+			require.Equal(t, []string{"a1", "a3", "a4", "a5"}, got)
+		})
+	}
+}
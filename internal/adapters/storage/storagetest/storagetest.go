@@ -0,0 +1,608 @@
+// Модуль storagetest содержит общий набор тестов реализаций storage.Store.
+//
+// Каждое хранилище запускает набор в своих тестах:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Store { return createStore(t) })
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/archive"
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/crypt"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+// Factory создает пустое хранилище для одного теста.
+type Factory func(t *testing.T) storage.Store
+
+// Run запускает набор тестов хранилища.
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Store)
+	}{
+		{"Ping", testPing},
+		{"GetNotFound", testGetNotFound},
+		{"SetGet", testSetGet},
+		{"SetNotUnique", testSetNotUnique},
+		{"SetDuplicateShort", testSetDuplicateShort},
+		{"SetCreatedAt", testSetCreatedAt},
+		{"SetBatch", testSetBatch},
+		{"SetBatchEmpty", testSetBatchEmpty},
+		{"SetBatchNotUnique", testSetBatchNotUnique},
+		{"SetBatchNotUniqueInBatch", testSetBatchNotUniqueInBatch},
+		{"SetBatchDuplicateShort", testSetBatchDuplicateShort},
+		{"OriginalHash", testOriginalHash},
+		{"DeleteShortURLs", testDeleteShortURLs},
+		{"DeleteAllowsReshorten", testDeleteAllowsReshorten},
+		{"HardDeleteURLs", testHardDeleteURLs},
+		{"GetAllURL", testGetAllURL},
+		{"GetUserURLs", testGetUserURLs},
+		{"GetState", testGetState},
+		{"FindOriginal", testFindOriginal},
+		{"RewriteOriginalURL", testRewriteOriginalURL},
+		{"Archive", testArchive},
+		{"Snapshot", testSnapshot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := factory(t)
+			tt.test(t, s)
+		})
+	}
+}
+
+func link(short, original string) models.ShortLink {
+	return models.ShortLink{ShortURL: short, OriginalURL: original}
+}
+
+func hashed(short, original, hash string) models.ShortLink {
+	return models.ShortLink{ShortURL: short, OriginalURL: original, OriginalHash: hash}
+}
+
+func set(t *testing.T, s storage.Store, userID string, links ...models.ShortLink) {
+	t.Helper()
+	for _, l := range links {
+		_, err := s.Set(context.Background(), userID, l)
+		require.NoError(t, err)
+	}
+}
+
+func del(t *testing.T, s storage.Store, links ...models.ShortLink) {
+	t.Helper()
+	_, err := s.DeleteShortURLs(context.Background(), links)
+	require.NoError(t, err)
+}
+
+func shorts(urls []models.ShortenURL) []string {
+	result := make([]string, 0, len(urls))
+	for _, u := range urls {
+		result = append(result, u.ShortURL)
+	}
+	return result
+}
+
+func testPing(t *testing.T, s storage.Store) {
+	require.NoError(t, s.Ping(context.Background()))
+}
+
+func testGetNotFound(t *testing.T, s storage.Store) {
+	_, err := s.Get(context.Background(), "", "unknown")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+}
+
+func testSetGet(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Second)
+	short, err := s.Set(ctx, "1", models.ShortLink{
+		ShortURL:     "abc",
+		OriginalURL:  "https://a.ru/",
+		Domain:       "go.brand.com",
+		RedirectCode: 301,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "abc", short)
+
+	got, err := s.Get(ctx, "go.brand.com", "abc")
+	require.NoError(t, err)
+	require.Equal(t, "abc", got.ShortURL)
+	require.Equal(t, "https://a.ru/", got.OriginalURL)
+	require.Equal(t, "1", got.UserID)
+	require.Equal(t, "go.brand.com", got.Domain)
+	require.Equal(t, 301, got.RedirectCode)
+	require.False(t, got.IsDeleted)
+	require.WithinRange(t, got.CreatedAt, before, time.Now().Add(time.Second))
+
+	_, err = s.Get(ctx, "", "abc")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+}
+
+func testSetNotUnique(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	set(t, s, "1", link("abc", "https://a.ru/"))
+
+	short, err := s.Set(ctx, "1", link("def", "https://a.ru/"))
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "abc", short)
+	_, err = s.Get(ctx, "", "def")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+
+	// уникальность оригинальной ссылки проверяется в пределах пользователя и домена.
+	set(t, s, "2", link("def", "https://a.ru/"))
+	set(t, s, "1", models.ShortLink{ShortURL: "ghi", OriginalURL: "https://a.ru/", Domain: "go.brand.com"})
+}
+
+func testSetDuplicateShort(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	set(t, s, "1", link("abc", "https://a.ru/"))
+
+	// короткий код уникален в домене для всех пользователей.
+	_, err := s.Set(ctx, "2", link("abc", "https://b.ru/"))
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+	got, err := s.Get(ctx, "", "abc")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/", got.OriginalURL)
+
+	set(t, s, "2", models.ShortLink{ShortURL: "abc", OriginalURL: "https://b.ru/", Domain: "go.brand.com"})
+}
+
+func testSetCreatedAt(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	created := models.UnixTime(1700000000)
+	set(t, s, "1", models.ShortLink{ShortURL: "a1", OriginalURL: "https://a.ru/1", CreatedAt: created})
+	_, err := s.SetBatch(ctx, "1", []models.ShortLink{
+		{ShortURL: "a2", OriginalURL: "https://a.ru/2", CreatedAt: created.Add(time.Hour)},
+	})
+	require.NoError(t, err)
+
+	// известное время создания сохраняется, например, при восстановлении из резервной копии.
+	got, err := s.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, created, got.CreatedAt.UTC())
+	got, err = s.Get(ctx, "", "a2")
+	require.NoError(t, err)
+	require.Equal(t, created.Add(time.Hour), got.CreatedAt.UTC())
+}
+
+func testSetBatch(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	batch := []models.ShortLink{
+		link("a1", "https://a.ru/1"),
+		link("a2", "https://a.ru/2"),
+		{ShortURL: "a3", OriginalURL: "https://a.ru/3", Domain: "go.brand.com", RedirectCode: 308},
+	}
+	out, err := s.SetBatch(ctx, "1", batch)
+	require.NoError(t, err)
+	require.Equal(t, batch, out)
+
+	for _, l := range batch {
+		got, err := s.Get(ctx, l.Domain, l.ShortURL)
+		require.NoError(t, err)
+		require.Equal(t, l.OriginalURL, got.OriginalURL)
+		require.Equal(t, l.RedirectCode, got.RedirectCode)
+		require.Equal(t, "1", got.UserID)
+	}
+}
+
+func testSetBatchEmpty(t *testing.T, s storage.Store) {
+	out, err := s.SetBatch(context.Background(), "1", []models.ShortLink{})
+	require.NoError(t, err)
+	require.Empty(t, out)
+}
+
+// requireNotStored проверяет, что ссылки пакета не сохранены.
+func requireNotStored(t *testing.T, s storage.Store, batch []models.ShortLink, except ...string) {
+	t.Helper()
+	for _, l := range batch {
+		if len(except) > 0 && l.ShortURL == except[0] {
+			continue
+		}
+		_, err := s.Get(context.Background(), l.Domain, l.ShortURL)
+		require.ErrorIs(t, err, storeerror.ErrNotFoundKey, l.ShortURL)
+	}
+}
+
+func testSetBatchNotUnique(t *testing.T, s storage.Store) {
+	set(t, s, "1", link("abc", "https://a.ru/2"))
+
+	batch := []models.ShortLink{link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2")}
+	out, err := s.SetBatch(context.Background(), "1", batch)
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, []models.ShortLink{{ShortURL: "abc", OriginalURL: "https://a.ru/2"}}, out)
+	requireNotStored(t, s, batch)
+}
+
+func testSetBatchNotUniqueInBatch(t *testing.T, s storage.Store) {
+	batch := []models.ShortLink{link("a1", "https://a.ru/1"), link("a2", "https://a.ru/1")}
+	out, err := s.SetBatch(context.Background(), "1", batch)
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, []models.ShortLink{{ShortURL: "a1", OriginalURL: "https://a.ru/1"}}, out)
+	requireNotStored(t, s, batch)
+}
+
+func testSetBatchDuplicateShort(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	set(t, s, "2", link("a2", "https://b.ru/"))
+
+	batch := []models.ShortLink{link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2")}
+	_, err := s.SetBatch(ctx, "1", batch)
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+	requireNotStored(t, s, batch, "a2")
+
+	batch = []models.ShortLink{link("b1", "https://a.ru/1"), link("b1", "https://a.ru/2")}
+	_, err = s.SetBatch(ctx, "1", batch)
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+	requireNotStored(t, s, batch)
+}
+
+func testDeleteShortURLs(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	set(t, s, "1", link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2"))
+
+	deleted, err := s.DeleteShortURLs(ctx, []models.ShortLink{
+		{ShortURL: "a1", UserID: "1"},
+		{ShortURL: "a2", UserID: "2"}, // чужая ссылка не удаляется.
+		{ShortURL: "unknown", UserID: "1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []models.ShortLink{{ShortURL: "a1", UserID: "1"}}, deleted)
+
+	got, err := s.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	require.True(t, got.IsDeleted)
+	require.Equal(t, "https://a.ru/1", got.OriginalURL)
+	require.Equal(t, "1", got.UserID)
+	_, err = s.Get(ctx, "", "a2")
+	require.NoError(t, err)
+
+	// повторное удаление не является ошибкой и ничего не удаляет.
+	deleted, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "a1", UserID: "1"}})
+	require.NoError(t, err)
+	require.Empty(t, deleted)
+
+	// удаленный код остается занятым до очистки.
+	_, err = s.Set(ctx, "1", link("a1", "https://a.ru/3"))
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+}
+
+func testDeleteAllowsReshorten(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	set(t, s, "1", link("a1", "https://a.ru/1"))
+	del(t, s, models.ShortLink{ShortURL: "a1", UserID: "1"})
+
+	short, err := s.Set(ctx, "1", link("a2", "https://a.ru/1"))
+	require.NoError(t, err)
+	require.Equal(t, "a2", short)
+	out, err := s.SetBatch(ctx, "2", []models.ShortLink{link("b1", "https://a.ru/1")})
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+
+	// после очистки удаленной ссылки новая ссылка остается уникальной.
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	short, err = s.Set(ctx, "1", link("a3", "https://a.ru/1"))
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "a2", short)
+}
+
+func testHardDeleteURLs(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	set(t, s, "1", link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2"))
+	del(t, s, models.ShortLink{ShortURL: "a1", UserID: "1"})
+
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	_, err := s.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = s.Get(ctx, "", "a2")
+	require.NoError(t, err)
+
+	// код очищенной ссылки снова свободен.
+	set(t, s, "2", link("a1", "https://b.ru/"))
+	require.NoError(t, s.HardDeleteURLs(ctx))
+}
+
+func testGetAllURL(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	urls, err := s.GetAllURL(ctx, "1")
+	require.NoError(t, err)
+	require.NotNil(t, urls)
+	require.Empty(t, urls)
+
+	set(t, s, "1", link("a3", "https://a.ru/3"), link("a1", "https://a.ru/1"))
+	set(t, s, "2", link("b1", "https://b.ru/1"))
+	set(t, s, "1", models.ShortLink{ShortURL: "a2", OriginalURL: "https://a.ru/2", Domain: "go.brand.com"})
+	set(t, s, "1", link("a4", "https://a.ru/4"))
+	del(t, s, models.ShortLink{ShortURL: "a4", UserID: "1"})
+
+	urls, err = s.GetAllURL(ctx, "1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a3", "a1", "a2"}, shorts(urls))
+	require.Equal(t, "https://a.ru/3", urls[0].OriginalURL)
+	require.Equal(t, "go.brand.com", urls[2].Domain)
+}
+
+func testGetUserURLs(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	set(t, s, "1", link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2"), link("a3", "https://a.ru/3"))
+	set(t, s, "2", link("b1", "https://b.ru/1"))
+	set(t, s, "1", link("a4", "https://a.ru/4"), link("a5", "https://a.ru/5"))
+	del(t, s, models.ShortLink{ShortURL: "a2", UserID: "1"})
+
+	got := []string{}
+	page := models.Page{Limit: 2}
+	for {
+		urls, err := s.GetUserURLs(ctx, "1", page)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(urls), page.Limit)
+		if len(urls) == 0 {
+			break
+		}
+		for _, u := range urls {
+			require.Greater(t, u.ID, page.After)
+		}
+		got = append(got, shorts(urls)...)
+		page.After = urls[len(urls)-1].ID
+	}
+	require.Equal(t, []string{"a1", "a3", "a4", "a5"}, got)
+}
+
+func testGetState(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	today := time.Now().UTC().Truncate(time.Hour * 24)
+	query := models.StatsQuery{Since: today, Top: 10}
+	stats, err := s.GetState(ctx, query)
+	require.NoError(t, err)
+	require.Equal(t, 0, stats.URLs)
+	require.Equal(t, 0, stats.Users)
+	require.Equal(t, 0, stats.Deleted)
+	require.Empty(t, stats.CreatedPerDay)
+	require.Empty(t, stats.TopUsers)
+
+	set(t, s, "1", link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2"), link("a3", "https://a.ru/3"))
+	set(t, s, "2", link("b1", "https://b.ru/1"))
+	set(t, s, "3", link("c1", "https://c.ru/1"))
+	del(t, s, models.ShortLink{ShortURL: "a2", UserID: "1"}, models.ShortLink{ShortURL: "c1", UserID: "3"})
+
+	stats, err = s.GetState(ctx, query)
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.URLs)
+	require.Equal(t, 2, stats.Users)
+	require.Equal(t, 2, stats.Deleted)
+	require.Equal(t, []models.DailyCount{{Day: today.Format(models.DayLayout), Count: 3}}, stats.CreatedPerDay)
+	require.Equal(t, []models.UserCount{{UserID: "1", URLs: 2}, {UserID: "2", URLs: 1}}, stats.TopUsers)
+	require.GreaterOrEqual(t, stats.StorageBytes, int64(0))
+
+	// окно и количество пользователей ограничивают выборку.
+	stats, err = s.GetState(ctx, models.StatsQuery{Since: today.Add(time.Hour * 24), Top: 1})
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.URLs)
+	require.Empty(t, stats.CreatedPerDay)
+	require.Equal(t, []models.UserCount{{UserID: "1", URLs: 2}}, stats.TopUsers)
+
+	require.NoError(t, s.HardDeleteURLs(ctx))
+	stats, err = s.GetState(ctx, query)
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.URLs)
+	require.Equal(t, 0, stats.Deleted)
+}
+
+func testFindOriginal(t *testing.T, s storage.Store) {
+	f, ok := s.(crypt.Finder)
+	if !ok {
+		t.Skip("storage does not support finding links by original url")
+	}
+	ctx := context.Background()
+	set(t, s, "1", link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2"))
+	del(t, s, models.ShortLink{ShortURL: "a1", UserID: "1"})
+
+	set(t, s, "1", hashed("a3", "https://a.ru/3", "h3"))
+
+	short, err := f.FindOriginal(ctx, "1", "", "https://a.ru/2")
+	require.NoError(t, err)
+	require.Equal(t, "a2", short)
+
+	// удаленные ссылки, ссылки со слепым индексом, ссылки других пользователей и доменов не находятся.
+	_, err = f.FindOriginal(ctx, "1", "", "https://a.ru/1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = f.FindOriginal(ctx, "1", "", "https://a.ru/3")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = f.FindOriginal(ctx, "2", "", "https://a.ru/2")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = f.FindOriginal(ctx, "1", "go.brand.com", "https://a.ru/2")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+}
+
+func testRewriteOriginalURL(t *testing.T, s storage.Store) {
+	r, ok := s.(crypt.Rewriter)
+	if !ok {
+		t.Skip("storage does not support rewriting original urls")
+	}
+	ctx := context.Background()
+	set(t, s, "1", link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2"))
+
+	ok, err := r.RewriteOriginalURL(ctx, link("a1", "https://a.ru/1"), "https://a.ru/new", "")
+	require.NoError(t, err)
+	require.True(t, ok)
+	got, err := s.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/new", got.OriginalURL)
+	require.Equal(t, "1", got.UserID)
+
+	// индекс оригинальных ссылок следует за заменой.
+	short, err := s.Set(ctx, "1", link("b1", "https://a.ru/new"))
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "a1", short)
+	_, err = s.Set(ctx, "1", link("b2", "https://a.ru/1"))
+	require.NoError(t, err)
+
+	// ссылка, изменившаяся после чтения, и отсутствующая ссылка не заменяются.
+	ok, err = r.RewriteOriginalURL(ctx, link("a1", "https://a.ru/1"), "https://a.ru/other", "")
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = r.RewriteOriginalURL(ctx, link("unknown", "https://a.ru/1"), "https://a.ru/other", "")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// замена не создает второй ссылки пользователя на ту же оригинальную ссылку.
+	_, err = r.RewriteOriginalURL(ctx, link("a2", "https://a.ru/2"), "https://a.ru/new", "")
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	got, err = s.Get(ctx, "", "a2")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/2", got.OriginalURL)
+
+	// удаленная ссылка заменяется без проверки уникальности.
+	del(t, s, models.ShortLink{ShortURL: "a2", UserID: "1"})
+	ok, err = r.RewriteOriginalURL(ctx, link("a2", "https://a.ru/2"), "https://a.ru/new", "")
+	require.NoError(t, err)
+	require.True(t, ok)
+	got, err = s.Get(ctx, "", "a2")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	require.Equal(t, "https://a.ru/new", got.OriginalURL)
+
+	// замена со слепым индексом переносит ссылку в индекс слепых индексов.
+	ok, err = r.RewriteOriginalURL(ctx, link("a1", "https://a.ru/new"), "enc-new", "h-new")
+	require.NoError(t, err)
+	require.True(t, ok)
+	got, err = s.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "enc-new", got.OriginalURL)
+	require.Equal(t, "h-new", got.OriginalHash)
+	short, err = s.Set(ctx, "1", hashed("b3", "enc-other", "h-new"))
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "a1", short)
+	_, err = s.Set(ctx, "1", link("b4", "https://a.ru/new"))
+	require.NoError(t, err)
+}
+
+func testOriginalHash(t *testing.T, s storage.Store) {
+	if _, ok := s.(*crypt.Crypt); ok {
+		t.Skip("storage fills blind index itself")
+	}
+	ctx := context.Background()
+	set(t, s, "1", hashed("a1", "enc-1", "h1"))
+	got, err := s.Get(ctx, "", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "h1", got.OriginalHash)
+
+	// ссылки с равным слепым индексом равны при разных оригинальных ссылках.
+	short, err := s.Set(ctx, "1", hashed("a2", "enc-2", "h1"))
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "a1", short)
+	output, err := s.SetBatch(ctx, "1", []models.ShortLink{hashed("a3", "enc-3", "h1")})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "a1", output[0].ShortURL)
+	output, err = s.SetBatch(ctx, "1", []models.ShortLink{hashed("a4", "enc-4", "h4"), hashed("a5", "enc-5", "h4")})
+	require.ErrorIs(t, err, storeerror.ErrNotUnique)
+	require.Equal(t, "a4", output[0].ShortURL)
+
+	// слепой индекс сравнивается только со слепыми индексами и в пределах пользователя.
+	set(t, s, "1", link("a6", "h1"), hashed("a7", "h1", "h7"))
+	set(t, s, "2", hashed("a8", "enc-1", "h1"))
+
+	// удаленная ссылка освобождает слепой индекс.
+	del(t, s, models.ShortLink{ShortURL: "a1", UserID: "1"})
+	set(t, s, "1", hashed("a9", "enc-9", "h1"))
+}
+
+func testArchive(t *testing.T, s storage.Store) {
+	a, ok := s.(archive.Source)
+	if !ok {
+		t.Skip("storage does not support archiving")
+	}
+	ctx := context.Background()
+	set(t, s, "1", link("a1", "https://a.ru/1"), link("a2", "https://a.ru/2"))
+	del(t, s, models.ShortLink{ShortURL: "a2", UserID: "1"})
+	now := time.Now()
+
+	// удаленные ссылки не архивируются.
+	links, err := a.ArchiveCandidates(ctx, now.Add(time.Hour), time.Time{}, 10)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("storage does not support archiving")
+	}
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Equal(t, "a1", links[0].ShortURL)
+	archived := links[0]
+	// шифрующее хранилище передает в архив зашифрованные ссылки и расшифровывает их само.
+	if d, ok := s.(archive.Decrypter); ok {
+		archived, err = d.DecryptArchived(archived)
+		require.NoError(t, err)
+	}
+	require.Equal(t, "https://a.ru/1", archived.OriginalURL)
+	require.Equal(t, "1", links[0].UserID)
+	require.True(t, links[0].AccessedAt.IsZero())
+	links, err = a.ArchiveCandidates(ctx, now.Add(-time.Hour), now.Add(-time.Hour), 10)
+	require.NoError(t, err)
+	require.Empty(t, links)
+
+	// переход исключает ссылку из неактивных, но не из старых.
+	require.NoError(t, a.TouchLinks(ctx, []models.ShortLink{{ShortURL: "a1"}, {ShortURL: "unknown"}}, now.Add(2*time.Hour)))
+	links, err = a.ArchiveCandidates(ctx, now.Add(time.Hour), time.Time{}, 10)
+	require.NoError(t, err)
+	require.Empty(t, links)
+	stale := models.ArchivedLink{ShortURL: "a1", UserID: "1"}
+	links, err = a.ArchiveCandidates(ctx, time.Time{}, now.Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.False(t, links[0].AccessedAt.IsZero())
+
+	// ссылка, по которой переходили после выбора, не удаляется.
+	removed, err := a.RemoveArchived(ctx, []models.ArchivedLink{stale})
+	require.NoError(t, err)
+	require.Empty(t, removed)
+	removed, err = a.RemoveArchived(ctx, links)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+
+	_, err = s.Get(ctx, "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = s.Set(ctx, "1", link("a3", "https://a.ru/1"))
+	require.NoError(t, err)
+}
+
+func testSnapshot(t *testing.T, s storage.Store) {
+	sn, ok := s.(storage.Snapshotter)
+	if !ok {
+		t.Skip("storage does not support snapshots")
+	}
+	ctx := context.Background()
+	created := models.UnixTime(1700000000)
+	set(t, s, "1",
+		models.ShortLink{ShortURL: "a1", OriginalURL: "https://a.ru/1", CreatedAt: created},
+		models.ShortLink{ShortURL: "a2", OriginalURL: "https://a.ru/2", RedirectCode: 302, CreatedAt: created},
+	)
+	set(t, s, "2", models.ShortLink{ShortURL: "b1", OriginalURL: "https://b.ru/1", Domain: "go.brand.com", CreatedAt: created})
+	del(t, s, models.ShortLink{ShortURL: "a1", UserID: "1"})
+
+	links := map[string]models.ShortLink{}
+	err := sn.Snapshot(ctx, func(link models.ShortLink) error {
+		link.ID, link.CreatedAt = 0, link.CreatedAt.UTC()
+		links[link.ShortURL] = link
+		return nil
+	})
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("storage does not support snapshots")
+	}
+	require.NoError(t, err)
+	require.Equal(t, map[string]models.ShortLink{
+		"a1": {ShortURL: "a1", OriginalURL: "https://a.ru/1", UserID: "1", IsDeleted: true, CreatedAt: created},
+		"a2": {ShortURL: "a2", OriginalURL: "https://a.ru/2", UserID: "1", RedirectCode: 302, CreatedAt: created},
+		"b1": {ShortURL: "b1", OriginalURL: "https://b.ru/1", UserID: "2", Domain: "go.brand.com", CreatedAt: created},
+	}, links)
+
+	// ошибка обработчика прерывает обход.
+	stop := errors.New("stop")
+	err = sn.Snapshot(ctx, func(link models.ShortLink) error { return stop })
+	require.ErrorIs(t, err, stop)
+}
@@ -1,11 +1,106 @@
+// Модуль storeerror содержит типизированные ошибки хранилищ.
+//
+// Каждая ошибка относится к одному виду (Kind), транспорты выбирают код ответа по виду ошибки.
 package storeerror
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+)
+
+// Kind - вид ошибки хранилища.
+type Kind int
+
+// Виды ошибок.
+const (
+	KindUnknown     Kind = iota // ошибка без вида, внутренняя ошибка хранилища.
+	KindNotFound                // ссылка не найдена.
+	KindConflict                // ссылка или короткий код уже существуют.
+	KindDeleted                 // ссылка удалена.
+	KindExpired                 // срок действия ссылки истек.
+	KindUnavailable             // хранилище недоступно.
+	KindTimeout                 // истекло время ожидания хранилища.
+)
+
+// String возвращает название вида ошибки.
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindDeleted:
+		return "deleted"
+	case KindExpired:
+		return "expired"
+	case KindUnavailable:
+		return "unavailable"
+	case KindTimeout:
+		return "timeout"
+	default:
+		return "unknown"
+	}
+}
+
+// Error - ошибка хранилища определенного вида.
+type Error struct {
+	kind Kind
+	msg  string
+}
+
+// New создает ошибку хранилища.
+func New(kind Kind, msg string) *Error {
+	return &Error{kind: kind, msg: msg}
+}
+
+func (e *Error) Error() string { return e.msg }
+
+// Kind возвращает вид ошибки.
+func (e *Error) Kind() Kind { return e.kind }
 
 // Ошибки.
 var (
-	ErrNotFoundKey       = errors.New("not found value by key")
-	ErrNotUnique         = errors.New("row is not unique")
-	ErrDuplicateShortURL = errors.New("short url is duplicate")
-	ErrShortURLDeleted   = errors.New("short url was deleted")
+	ErrNotFoundKey       = New(KindNotFound, "not found value by key")
+	ErrNotUnique         = New(KindConflict, "row is not unique")
+	ErrDuplicateShortURL = New(KindConflict, "short url is duplicate")
+	ErrShortURLDeleted   = New(KindDeleted, "short url was deleted")
+	ErrExpired           = New(KindExpired, "short url has expired")
+	ErrUnavailable       = New(KindUnavailable, "storage is unavailable")
+	ErrTimeout           = New(KindTimeout, "storage timeout")
 )
+
+// KindOf возвращает вид первой ошибки хранилища в цепочке err.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.kind
+	}
+	return KindUnknown
+}
+
+// Classify помечает ошибки соединения и таймауты как ErrUnavailable и ErrTimeout.
+// Ошибки, уже имеющие вид, возвращаются без изменений.
+// Хранилища сначала проверяют ошибки своего драйвера, затем вызывают Classify.
+func Classify(err error) error {
+	if err == nil || KindOf(err) != KindUnknown {
+		return err
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, net.ErrClosed),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &netErr):
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+package storeerror_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want storeerror.Kind
+	}{
+		{"nil", nil, storeerror.KindUnknown},
+		{"unknown", errors.New("failed"), storeerror.KindUnknown},
+		{"kind is kept", fmt.Errorf("failed: %w", storeerror.ErrNotFoundKey), storeerror.KindNotFound},
+		{"deadline", fmt.Errorf("failed: %w", context.DeadlineExceeded), storeerror.KindTimeout},
+		{"refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, storeerror.KindUnavailable},
+		{"closed", fmt.Errorf("failed: %w", net.ErrClosed), storeerror.KindUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := storeerror.Classify(tt.err)
+			assert.Equal(t, tt.want, storeerror.KindOf(err))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
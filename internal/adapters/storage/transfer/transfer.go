@@ -0,0 +1,354 @@
+// Модуль transfer переносит ссылки между хранилищами.
+package transfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+var (
+	// ErrNotWalkable - хранилище не поддерживает обход ссылок.
+	ErrNotWalkable = errors.New("storage does not support walking links")
+	// ErrMismatch - данные хранилищ после переноса различаются.
+	ErrMismatch = errors.New("storages mismatch")
+
+	defaultProgressEvery = 1000
+)
+
+// Options параметры переноса.
+type Options struct {
+	Log           *zap.Logger
+	StatePath     string // файл с позицией переноса для продолжения после сбоя.
+	ProgressEvery int    // период отчета о ходе переноса, в ссылках.
+	DryRun        bool   // только прочитать источник без записи в приемник.
+}
+
+// Result итог переноса.
+type Result struct {
+	Read    int // прочитано ссылок источника.
+	Skipped int // пропущено ссылок, перенесенных ранее.
+	Copied  int // записано ссылок в приемник, при пробном запуске - будет записано.
+	Failed  int // ссылок, которые не удалось записать.
+}
+
+// Summary итог проверки хранилища.
+type Summary struct {
+	Checksum string
+	Count    int
+	Deleted  int
+}
+
+// position - позиция переноса: последняя ссылка прохода, до которой включительно перенесены все ссылки прохода.
+type position struct {
+	Domain  string `json:"domain,omitempty"`
+	Short   string `json:"short"`
+	Deleted bool   `json:"deleted"` // проход удаленных ссылок.
+}
+
+func (p *position) is(link models.ShortLink) bool {
+	return p.Domain == link.Domain && p.Short == link.ShortURL
+}
+
+// copier - состояние переноса.
+type copier struct {
+	walker storage.Walker
+	dst    storage.Store
+	opts   Options
+	pos    *position // позиция переноса, nil - перенос не начат.
+	res    Result
+	failed bool // после ошибки записи позиция не сдвигается, повторный запуск начнет с ошибочной ссылки.
+}
+
+// Copy переносит все ссылки, включая удаленные, из src в dst.
+// Позиция переноса - последняя ссылка, до которой перенесены все ссылки, - сохраняется в StatePath,
+// повторный запуск продолжает после нее. Ссылки, уже существующие в приемнике с теми же данными, пропускаются.
+func Copy(ctx context.Context, src, dst storage.Store, opts Options) (Result, error) {
+	opts = withDefaults(opts)
+	walker, ok := src.(storage.Walker)
+	if !ok {
+		return Result{}, ErrNotWalkable
+	}
+
+	pos, err := readState(opts.StatePath)
+	if err != nil {
+		return Result{}, err
+	}
+	if pos != nil {
+		opts.Log.Info("resume transfer",
+			zap.String("domain", pos.Domain), zap.String("short", pos.Short), zap.Bool("deleted", pos.Deleted))
+	}
+
+	c := &copier{walker: walker, dst: dst, opts: opts, pos: pos}
+	// удаленные ссылки переносятся первыми: пока удаленная ссылка сохраняется, она занимает
+	// оригинальную ссылку пользователя, которую может занимать и неудаленная ссылка источника.
+	for _, deleted := range []bool{true, false} {
+		if err = c.pass(ctx, deleted); err != nil {
+			if !opts.DryRun {
+				_ = writeState(opts.StatePath, c.pos)
+			}
+			return c.res, fmt.Errorf("failed walk source storage: %w", err)
+		}
+	}
+	if !opts.DryRun {
+		if err = writeState(opts.StatePath, c.pos); err != nil {
+			return c.res, err
+		}
+	}
+	res := c.res
+	opts.Log.Info("transfer finished",
+		zap.Int("read", res.Read), zap.Int("copied", res.Copied),
+		zap.Int("skipped", res.Skipped), zap.Int("failed", res.Failed), zap.Bool("dry_run", opts.DryRun))
+	return res, nil
+}
+
+// pass переносит удаленные или неудаленные ссылки источника.
+// Ссылки до позиции переноса в этом проходе пропускаются, ссылки завершенного прохода пропускаются целиком.
+// Если ссылки позиции в источнике больше нет, проход повторяется с начала.
+func (c *copier) pass(ctx context.Context, deleted bool) error {
+	var after *position
+	skipAll := false
+	if c.pos != nil {
+		switch {
+		case c.pos.Deleted == deleted:
+			after = c.pos
+		case deleted:
+			// позиция в проходе неудаленных ссылок: удаленные ссылки уже перенесены.
+			skipAll = true
+		}
+	}
+
+	start := c.res
+	found, err := c.walk(ctx, deleted, after, skipAll)
+	if err != nil {
+		return err
+	}
+	if after != nil && !found {
+		c.opts.Log.Info("transfer position not found, restart pass", zap.Bool("deleted", deleted))
+		c.res = start
+		_, err = c.walk(ctx, deleted, nil, false)
+	}
+	return err
+}
+
+// walk обходит ссылки прохода и переносит ссылки после позиции after.
+// Возвращает true, если ссылка позиции встретилась при обходе.
+func (c *copier) walk(ctx context.Context, deleted bool, after *position, skipAll bool) (bool, error) {
+	skipping := after != nil || skipAll
+	found := false
+	err := c.walker.Walk(ctx, func(link models.ShortLink) error {
+		if link.IsDeleted != deleted {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("transfer interrupted: %w", err)
+		}
+		c.res.Read++
+		if skipping {
+			c.res.Skipped++
+			if after != nil && after.is(link) {
+				skipping, found = false, true
+			}
+		} else {
+			c.copy(ctx, link)
+		}
+		return c.progress()
+	})
+	return found, err
+}
+
+// copy переносит ссылку и сдвигает позицию переноса, если до нее не было ошибок записи.
+func (c *copier) copy(ctx context.Context, link models.ShortLink) {
+	copied, err := copyLink(ctx, c.dst, link, c.opts.DryRun)
+	switch {
+	case err != nil:
+		c.res.Failed++
+		c.failed = true
+		c.opts.Log.Warn("failed copy link",
+			zap.String("domain", link.Domain), zap.String("short", link.ShortURL), zap.Error(err))
+	case copied:
+		c.res.Copied++
+	default:
+		c.res.Skipped++
+	}
+	if !c.failed {
+		c.pos = &position{Domain: link.Domain, Short: link.ShortURL, Deleted: link.IsDeleted}
+	}
+}
+
+// progress периодически сообщает о ходе переноса и сохраняет позицию.
+func (c *copier) progress() error {
+	if c.res.Read%c.opts.ProgressEvery != 0 {
+		return nil
+	}
+	c.opts.Log.Info("transfer progress",
+		zap.Int("read", c.res.Read), zap.Int("copied", c.res.Copied),
+		zap.Int("skipped", c.res.Skipped), zap.Int("failed", c.res.Failed))
+	if c.opts.DryRun {
+		return nil
+	}
+	return writeState(c.opts.StatePath, c.pos)
+}
+
+// copyLink записывает ссылку в приемник, возвращает false, если ссылка уже перенесена.
+// При пробном запуске приемник только проверяется.
+func copyLink(ctx context.Context, dst storage.Store, link models.ShortLink, dryRun bool) (bool, error) {
+	existing, err := dst.Get(ctx, link.Domain, link.ShortURL)
+	if err == nil || errors.Is(err, storeerror.ErrShortURLDeleted) {
+		if sameLink(existing, link) {
+			return false, nil
+		}
+		if !existing.IsDeleted && link.IsDeleted && existing.OriginalURL == link.OriginalURL &&
+			existing.UserID == link.UserID {
+			if dryRun {
+				return true, nil
+			}
+			return true, deleteLink(ctx, dst, link)
+		}
+		return false, fmt.Errorf("short code is taken by another link: %w", storeerror.ErrDuplicateShortURL)
+	}
+	if !errors.Is(err, storeerror.ErrNotFoundKey) {
+		return false, fmt.Errorf("failed check link in target: %w", err)
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	if _, err = dst.Set(ctx, link.UserID, link); err != nil {
+		return false, fmt.Errorf("failed set link: %w", err)
+	}
+	if link.IsDeleted {
+		return true, deleteLink(ctx, dst, link)
+	}
+	return true, nil
+}
+
+func deleteLink(ctx context.Context, dst storage.Store, link models.ShortLink) error {
+	if _, err := dst.DeleteShortURLs(ctx, []models.ShortLink{link}); err != nil {
+		return fmt.Errorf("failed mark link deleted: %w", err)
+	}
+	return nil
+}
+
+func sameLink(a, b models.ShortLink) bool {
+	return a.OriginalURL == b.OriginalURL &&
+		a.UserID == b.UserID &&
+		a.RedirectCode == b.RedirectCode &&
+		a.IsDeleted == b.IsDeleted
+}
+
+// Verify сравнивает количество и контрольные суммы ссылок хранилищ.
+func Verify(ctx context.Context, src, dst storage.Store) (Summary, Summary, error) {
+	srcSum, err := Checksum(ctx, src)
+	if err != nil {
+		return Summary{}, Summary{}, fmt.Errorf("failed checksum source: %w", err)
+	}
+	dstSum, err := Checksum(ctx, dst)
+	if err != nil {
+		return srcSum, Summary{}, fmt.Errorf("failed checksum target: %w", err)
+	}
+	if srcSum != dstSum {
+		return srcSum, dstSum, ErrMismatch
+	}
+	return srcSum, dstSum, nil
+}
+
+// Checksum считает количество ссылок и контрольную сумму, не зависящую от порядка обхода.
+// Время создания учитывается с точностью до секунды.
+func Checksum(ctx context.Context, store storage.Store) (Summary, error) {
+	walker, ok := store.(storage.Walker)
+	if !ok {
+		return Summary{}, ErrNotWalkable
+	}
+	var sum [sha256.Size]byte
+	var res Summary
+	err := walker.Walk(ctx, func(link models.ShortLink) error {
+		h := sha256.Sum256([]byte(strings.Join([]string{
+			link.Domain,
+			link.ShortURL,
+			link.OriginalURL,
+			link.UserID,
+			strconv.Itoa(link.RedirectCode),
+			strconv.FormatBool(link.IsDeleted),
+			createdAt(link),
+		}, "\x00")))
+		for i := range sum {
+			sum[i] ^= h[i]
+		}
+		res.Count++
+		if link.IsDeleted {
+			res.Deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed walk storage: %w", err)
+	}
+	res.Checksum = hex.EncodeToString(sum[:])
+	return res, nil
+}
+
+func createdAt(link models.ShortLink) string {
+	if link.CreatedAt.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(link.CreatedAt.Unix(), 10)
+}
+
+func withDefaults(opts Options) Options {
+	if opts.Log == nil {
+		opts.Log = zap.NewNop()
+	}
+	if opts.ProgressEvery <= 0 {
+		opts.ProgressEvery = defaultProgressEvery
+	}
+	return opts
+}
+
+func readState(path string) (*position, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed read transfer state: %w", err)
+	}
+	pos := &position{}
+	if err = json.Unmarshal(b, pos); err != nil {
+		return nil, fmt.Errorf("failed parse transfer state: %w", err)
+	}
+	return pos, nil
+}
+
+func writeState(path string, pos *position) error {
+	if path == "" || pos == nil {
+		return nil
+	}
+	b, err := json.Marshal(pos)
+	if err != nil {
+		return fmt.Errorf("failed write transfer state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("failed write transfer state: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed write transfer state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package transfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/bolt"
+	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+	"github.com/playmixer/short-link/internal/adapters/storage/transfer"
+)
+
+func createSource(t *testing.T) *memory.Store {
+	t.Helper()
+	ctx := context.Background()
+
+	s, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	links := []models.ShortLink{
+		{ShortURL: "a1", OriginalURL: "https://a.ru/1", UserID: "1", RedirectCode: 307},
+		{ShortURL: "a2", OriginalURL: "https://a.ru/2", UserID: "1", RedirectCode: 301},
+		{ShortURL: "b1", OriginalURL: "https://b.ru/1", UserID: "2", RedirectCode: 307, Domain: "b.ru"},
+		{ShortURL: "b2", OriginalURL: "https://b.ru/2", UserID: "2", RedirectCode: 307},
+	}
+	for _, link := range links {
+		_, err = s.Set(ctx, link.UserID, link)
+		require.NoError(t, err)
+	}
+	_, err = s.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "b2", UserID: "2"}})
+	require.NoError(t, err)
+	return s
+}
+
+func createTarget(t *testing.T) *bolt.Store {
+	t.Helper()
+
+	s, err := bolt.New(&bolt.Config{Path: filepath.Join(t.TempDir(), "data.db")})
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	src := createSource(t)
+	dst := createTarget(t)
+
+	res, err := transfer.Copy(ctx, src, dst, transfer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Result{Read: 4, Copied: 4}, res)
+
+	link, err := dst.Get(ctx, "b.ru", "b1")
+	require.NoError(t, err)
+	require.Equal(t, "2", link.UserID)
+	_, err = dst.Get(ctx, "", "b2")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+
+	srcSum, dstSum, err := transfer.Verify(ctx, src, dst)
+	require.NoError(t, err)
+	require.Equal(t, srcSum, dstSum)
+	require.Equal(t, 4, srcSum.Count)
+	require.Equal(t, 1, srcSum.Deleted)
+
+	// повторный перенос ничего не меняет.
+	res, err = transfer.Copy(ctx, src, dst, transfer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Result{Read: 4, Skipped: 4}, res)
+}
+
+func TestCopy_DryRun(t *testing.T) {
+	ctx := context.Background()
+	src := createSource(t)
+	dst := createTarget(t)
+	state := filepath.Join(t.TempDir(), "state")
+
+	res, err := transfer.Copy(ctx, src, dst, transfer.Options{DryRun: true, StatePath: state})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Result{Read: 4, Copied: 4}, res)
+
+	sum, err := transfer.Checksum(ctx, dst)
+	require.NoError(t, err)
+	require.Equal(t, 0, sum.Count)
+	_, err = os.Stat(state)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, _, err = transfer.Verify(ctx, src, dst)
+	require.ErrorIs(t, err, transfer.ErrMismatch)
+}
+
+func TestCopy_Resume(t *testing.T) {
+	ctx := context.Background()
+	src := createSource(t)
+	dst := createTarget(t)
+	state := filepath.Join(t.TempDir(), "state")
+
+	ctxCancel, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err := transfer.Copy(ctxCancel, src, dst, transfer.Options{StatePath: state})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// удаленные ссылки перенесены, неудаленные - до a1 включительно.
+	require.NoError(t, os.WriteFile(state, []byte(`{"short":"a1","deleted":false}`), 0o600))
+	res, err := transfer.Copy(ctx, src, dst, transfer.Options{StatePath: state, ProgressEvery: 1})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Result{Read: 4, Skipped: 2, Copied: 2}, res)
+
+	b, err := os.ReadFile(state)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"domain":"b.ru","short":"b1","deleted":false}`, string(b))
+
+	sum, err := transfer.Checksum(ctx, dst)
+	require.NoError(t, err)
+	require.Equal(t, 2, sum.Count)
+}
+
+func TestCopy_ResumeMissingPosition(t *testing.T) {
+	ctx := context.Background()
+	src := createSource(t)
+	dst := createTarget(t)
+	state := filepath.Join(t.TempDir(), "state")
+
+	// ссылки позиции нет в источнике, проход повторяется с начала.
+	require.NoError(t, os.WriteFile(state, []byte(`{"short":"purged","deleted":false}`), 0o600))
+	res, err := transfer.Copy(ctx, src, dst, transfer.Options{StatePath: state})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Result{Read: 4, Skipped: 1, Copied: 3}, res)
+	sum, err := transfer.Checksum(ctx, dst)
+	require.NoError(t, err)
+	require.Equal(t, 3, sum.Count)
+}
+
+func TestCopy_ResumeAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	src := createSource(t)
+	dst, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	state := filepath.Join(t.TempDir(), "state")
+
+	_, err = dst.Set(ctx, "3", models.ShortLink{ShortURL: "a1", OriginalURL: "https://c.ru/", RedirectCode: 307})
+	require.NoError(t, err)
+	res, err := transfer.Copy(ctx, src, dst, transfer.Options{StatePath: state})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Result{Read: 4, Copied: 3, Failed: 1}, res)
+
+	// позиция не сдвигается за ссылку, которую не удалось перенести.
+	b, err := os.ReadFile(state)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"short":"b2","deleted":true}`, string(b))
+
+	dst.RemoveShortURL(ctx, "3", "", "a1")
+	res, err = transfer.Copy(ctx, src, dst, transfer.Options{StatePath: state})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Result{Read: 4, Skipped: 3, Copied: 1}, res)
+	_, _, err = transfer.Verify(ctx, src, dst)
+	require.NoError(t, err)
+}
+
+func TestCopy_DeletedBeforeLive(t *testing.T) {
+	ctx := context.Background()
+	src := createTarget(t)
+	// обход bbolt идет в порядке ключей: неудаленная ссылка a встречается раньше удаленной b
+	// с той же оригинальной ссылкой пользователя.
+	_, err := src.Set(ctx, "1", models.ShortLink{ShortURL: "b", OriginalURL: "https://a.ru/"})
+	require.NoError(t, err)
+	_, err = src.DeleteShortURLs(ctx, []models.ShortLink{{ShortURL: "b", UserID: "1"}})
+	require.NoError(t, err)
+	_, err = src.Set(ctx, "1", models.ShortLink{ShortURL: "a", OriginalURL: "https://a.ru/"})
+	require.NoError(t, err)
+
+	dst, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	res, err := transfer.Copy(ctx, src, dst, transfer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Result{Read: 2, Copied: 2}, res)
+	_, _, err = transfer.Verify(ctx, src, dst)
+	require.NoError(t, err)
+}
+
+func TestChecksum_CreatedAt(t *testing.T) {
+	ctx := context.Background()
+	link := models.ShortLink{ShortURL: "a1", OriginalURL: "https://a.ru/1", UserID: "1"}
+	src, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	link.CreatedAt = models.UnixTime(1700000000)
+	_, err = src.Set(ctx, link.UserID, link)
+	require.NoError(t, err)
+	dst, err := memory.New(&memory.Config{})
+	require.NoError(t, err)
+	link.CreatedAt = models.UnixTime(1700000001)
+	_, err = dst.Set(ctx, link.UserID, link)
+	require.NoError(t, err)
+
+	_, _, err = transfer.Verify(ctx, src, dst)
+	require.ErrorIs(t, err, transfer.ErrMismatch)
+}
+
+func TestCopy_Conflict(t *testing.T) {
+	ctx := context.Background()
+	src := createSource(t)
+	dst := createTarget(t)
+
+	_, err := dst.Set(ctx, "3", models.ShortLink{ShortURL: "a1", OriginalURL: "https://c.ru/", RedirectCode: 307})
+	require.NoError(t, err)
+
+	res, err := transfer.Copy(ctx, src, dst, transfer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Failed)
+	require.Equal(t, 3, res.Copied)
+}
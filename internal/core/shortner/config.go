@@ -2,4 +2,7 @@ package shortner
 
 // Config конфигурация сервиса.
 type Config struct {
+	Domains       []string `env:"SHORT_DOMAINS" envSeparator:","`  // дополнительные домены коротких ссылок.
+	RedirectCode  int      `env:"REDIRECT_CODE"`                   // код перенаправления по умолчанию.
+	ReservedCodes []string `env:"RESERVED_CODES" envSeparator:","` // коды, недоступные для коротких ссылок.
 }
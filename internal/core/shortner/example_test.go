@@ -16,12 +16,12 @@ func ExampleShortner_Shorty() {
 	ctx := context.Background()
 
 	store, _ := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
-	_, _ = store.Set(ctx, "1", "VLIWXD", "https://practicum.yandex.ru/")
+	_, _ = store.Set(ctx, "1", models.ShortLink{ShortURL: "VLIWXD", OriginalURL: "https://practicum.yandex.ru/"})
 
 	s := shortner.New(ctx, store)
 
 	// Сокращаем ссылку.
-	shortLink, _ := s.Shorty(ctx, "1", "https://practicum.yandex.ru/")
+	shortLink, _ := s.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://practicum.yandex.ru/"})
 
 	fmt.Println(shortLink)
 	// Output:
@@ -32,12 +32,12 @@ func ExampleShortner_GetURL() {
 	ctx := context.Background()
 
 	store, _ := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
-	_, _ = store.Set(ctx, "1", "VLIWXD", "https://practicum.yandex.ru/")
+	_, _ = store.Set(ctx, "1", models.ShortLink{ShortURL: "VLIWXD", OriginalURL: "https://practicum.yandex.ru/"})
 
 	s := shortner.New(ctx, store)
 
 	// Получаем полную ссылку.
-	link, _ := s.GetURL(ctx, "VLIWXD")
+	link, _ := s.GetURL(ctx, "", "VLIWXD")
 	fmt.Println(link)
 	// Output:
 	// https://practicum.yandex.ru/
@@ -47,7 +47,7 @@ func ExampleShortner_ShortyBatch() {
 	ctx := context.Background()
 
 	store, _ := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
-	_, _ = store.Set(ctx, "1", "VLIWXD", "https://practicum.yandex.ru/")
+	_, _ = store.Set(ctx, "1", models.ShortLink{ShortURL: "VLIWXD", OriginalURL: "https://practicum.yandex.ru/"})
 
 	s := shortner.New(ctx, store)
 
@@ -61,14 +61,14 @@ func ExampleShortner_ShortyBatch() {
 	fmt.Println(output)
 
 	// Output:
-	// [{1 VLIWXD}]
+	// [{1 VLIWXD }]
 }
 
 func ExampleShortner_GetAllURL() {
 	ctx := context.Background()
 
 	store, _ := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
-	_, _ = store.Set(ctx, "1", "VLIWXD", "https://practicum.yandex.ru/")
+	_, _ = store.Set(ctx, "1", models.ShortLink{ShortURL: "VLIWXD", OriginalURL: "https://practicum.yandex.ru/"})
 
 	s := shortner.New(ctx, store)
 
@@ -77,14 +77,38 @@ func ExampleShortner_GetAllURL() {
 	fmt.Println(output)
 
 	// Output:
-	// [{VLIWXD https://practicum.yandex.ru/}]
+	// [{VLIWXD https://practicum.yandex.ru/  0}]
+}
+
+func ExampleShortner_GetUserURLs() {
+	ctx := context.Background()
+
+	store, _ := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
+	_, _ = store.Set(ctx, "1", models.ShortLink{ShortURL: "VLIWXD", OriginalURL: "https://practicum.yandex.ru/"})
+	_, _ = store.Set(ctx, "1", models.ShortLink{ShortURL: "QWERTY", OriginalURL: "https://github.com/"})
+
+	s := shortner.New(ctx, store)
+
+	// Получаем ссылки пользователя по одной на страницу.
+	links, cursor, _ := s.GetUserURLs(ctx, "1", "", 1)
+	for len(links) > 0 {
+		fmt.Println(links[0].ShortURL)
+		if cursor == "" {
+			break
+		}
+		links, cursor, _ = s.GetUserURLs(ctx, "1", cursor, 1)
+	}
+
+	// Output:
+	// VLIWXD
+	// QWERTY
 }
 
 func ExampleShortner_DeleteShortURLs() {
 	ctx := context.Background()
 
 	store, _ := storage.NewStore(ctx, &storage.Config{Memory: &memory.Config{}}, zap.NewNop())
-	_, _ = store.Set(ctx, "1", "VLIWXD", "https://practicum.yandex.ru/")
+	_, _ = store.Set(ctx, "1", models.ShortLink{ShortURL: "VLIWXD", OriginalURL: "https://practicum.yandex.ru/"})
 
 	s := shortner.New(ctx, store)
 
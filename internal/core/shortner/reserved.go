@@ -0,0 +1,104 @@
+package shortner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/playmixer/short-link/internal/adapters/models"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
+)
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`) // допустимый формат пользовательского кода.
+
+// reservedCodes - реестр кодов, которые нельзя использовать как короткие ссылки.
+type reservedCodes struct {
+	mu    *sync.RWMutex
+	codes map[string]struct{}
+}
+
+func newReservedCodes() *reservedCodes {
+	return &reservedCodes{
+		mu:    &sync.RWMutex{},
+		codes: make(map[string]struct{}),
+	}
+}
+
+func (r *reservedCodes) add(codes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range codes {
+		if c = strings.TrimSpace(c); c != "" {
+			r.codes[c] = struct{}{}
+		}
+	}
+}
+
+func (r *reservedCodes) contains(code string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.codes[code]
+	return ok
+}
+
+func (r *reservedCodes) list() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]string, 0, len(r.codes))
+	for c := range r.codes {
+		result = append(result, c)
+	}
+	return result
+}
+
+// SetReservedCodes задает коды, недоступные для коротких ссылок.
+func SetReservedCodes(codes []string) Option {
+	return func(s *Shortner) {
+		s.reserved.add(codes...)
+	}
+}
+
+// ReserveCodes добавляет коды в реестр зарезервированных, например, сегменты маршрутов сервера.
+func (s *Shortner) ReserveCodes(codes ...string) {
+	s.reserved.add(codes...)
+}
+
+// IsReserved проверяет, зарезервирован ли код.
+func (s *Shortner) IsReserved(code string) bool {
+	return s.reserved.contains(code)
+}
+
+// ValidateAlias проверяет пользовательский код короткой ссылки.
+func (s *Shortner) ValidateAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("alias `%s`: %w", alias, ErrInvalidAlias)
+	}
+	if s.IsReserved(alias) {
+		return fmt.Errorf("alias `%s`: %w", alias, ErrReservedCode)
+	}
+	return nil
+}
+
+// ShadowedLinks возвращает сохраненные ссылки, коды которых совпадают с зарезервированными.
+// Такие ссылки недоступны для перехода, так как перекрываются маршрутами сервера.
+func (s *Shortner) ShadowedLinks(ctx context.Context) ([]models.ShortLink, error) {
+	result := []models.ShortLink{}
+	domains := append([]string{""}, s.domains...)
+	for _, code := range s.reserved.list() {
+		for _, domain := range domains {
+			link, err := s.store.Get(ctx, domain, code)
+			if errors.Is(err, storeerror.ErrNotFoundKey) || errors.Is(err, storeerror.ErrShortURLDeleted) {
+				continue
+			}
+			if err != nil {
+				return result, fmt.Errorf("failed check reserved code `%s`: %w", code, err)
+			}
+			link.ShortURL, link.Domain = code, domain
+			result = append(result, link)
+		}
+	}
+	return result, nil
+}
@@ -3,9 +3,15 @@ package shortner
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,25 +27,60 @@ var (
 	numberOfTryGenShortLink      = 3                // попыток для генерации сокращенной ссылки.
 	sizeDeleteChanel             = 1024             // размер канала удаленных ссылок.
 	hardDeletingDelay            = time.Second * 10 // периодичность запуска полного удаления ссылки.
+	defaultPageLimit             = 100              // размер страницы ссылок пользователя по умолчанию.
+	maxPageLimit                 = 1000             // максимальный размер страницы ссылок пользователя.
+	defaultStatsDays             = 7                // окно подсчета созданных ссылок по умолчанию, сутки.
+	maxStatsDays                 = 366              // максимальное окно подсчета созданных ссылок, сутки.
+	defaultStatsTop              = 10               // количество пользователей в статистике по умолчанию.
+	maxStatsTop                  = 100              // максимальное количество пользователей в статистике.
+)
+
+// Ошибки сервиса.
+var (
+	ErrUnknownDomain       = errors.New("unknown short domain")
+	ErrInvalidRedirectCode = errors.New("invalid redirect code")
+	ErrInvalidAlias        = errors.New("invalid short link alias")
+	ErrReservedCode        = errors.New("short code is reserved")
+	ErrInvalidCursor       = errors.New("invalid page cursor")
+	ErrInvalidPageLimit    = errors.New("invalid page limit")
+	ErrInvalidStatsDays    = errors.New("invalid stats days")
+	ErrInvalidStatsTop     = errors.New("invalid stats top")
 )
 
 // Store - интерфейс хранилища ссылок.
 type Store interface {
-	// Возвращает оригинальную ссылку.
-	Get(ctx context.Context, short string) (string, error)
+	// Возвращает оригинальную ссылку по домену и короткому коду.
+	Get(ctx context.Context, domain, short string) (models.ShortLink, error)
 	// Возвращает все ссылки пользователя
 	GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error)
+	// Возвращает страницу неудаленных ссылок пользователя в порядке создания.
+	GetUserURLs(ctx context.Context, userID string, page models.Page) ([]models.ShortenURL, error)
 	// Сохраняет ссылку.
-	Set(ctx context.Context, userID string, short string, url string) (string, error)
+	Set(ctx context.Context, userID string, link models.ShortLink) (string, error)
 	// Сохраняет список ссылок.
 	SetBatch(ctx context.Context, userID string, batch []models.ShortLink) ([]models.ShortLink, error)
 	// Проверка соединения с хранилищем.
 	Ping(ctx context.Context) error
-	// Мягкое удаляет ссылки
-	DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) error
+	// Мягкое удаляет ссылки, возвращает удаленные.
+	DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error)
 	// Хард удаление ссылок
 	HardDeleteURLs(ctx context.Context) error
-	GetState(ctx context.Context) (urls int, users int, err error)
+	GetState(ctx context.Context, query models.StatsQuery) (models.ShortenStats, error)
+}
+
+// Auditor - журнал действий пользователей.
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent) error
+}
+
+// Archive - архив старых и неактивных ссылок.
+type Archive interface {
+	// Touch отмечает переход по ссылке.
+	Touch(link models.ShortLink)
+	// Lookup возвращает ссылку из архива или storeerror.ErrNotFoundKey.
+	Lookup(ctx context.Context, domain, short string) (models.ShortLink, error)
+	// Contains проверяет, есть ли ссылка в архиве.
+	Contains(ctx context.Context, domain, short string) (bool, error)
 }
 
 // Shortner - имплементация сервиса коротких ссылок.
@@ -48,6 +89,12 @@ type Shortner struct {
 	deleteCh chan models.ShortLink
 	log      *zap.Logger
 	gw       *sync.WaitGroup
+	domains  []string
+	// код перенаправления для ссылок без собственного кода.
+	redirectCode int
+	auditor      Auditor
+	reserved     *reservedCodes
+	archive      Archive
 }
 
 // Option интерфейс опции Shortner.
@@ -60,13 +107,52 @@ func SetLogger(log *zap.Logger) Option {
 	}
 }
 
+// SetDomains задает дополнительные домены коротких ссылок.
+func SetDomains(domains []string) Option {
+	return func(s *Shortner) {
+		s.domains = make([]string, 0, len(domains))
+		for _, d := range domains {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d != "" && !slices.Contains(s.domains, d) {
+				s.domains = append(s.domains, d)
+			}
+		}
+	}
+}
+
+// SetRedirectCode задает код перенаправления по умолчанию.
+// Недопустимый или нулевой код оставляет 307 Temporary Redirect.
+func SetRedirectCode(code int) Option {
+	return func(s *Shortner) {
+		if models.IsValidRedirectCode(code) {
+			s.redirectCode = code
+		}
+	}
+}
+
+// SetAuditor установка журнала аудита.
+func SetAuditor(a Auditor) Option {
+	return func(s *Shortner) {
+		s.auditor = a
+	}
+}
+
+// SetArchive установка архива: ссылки, не найденные в хранилище, ищутся в архиве.
+func SetArchive(a Archive) Option {
+	return func(s *Shortner) {
+		s.archive = a
+	}
+}
+
 // New создает Shortner.
 func New(ctx context.Context, s Store, options ...Option) *Shortner {
 	sh := &Shortner{
-		store:    s,
-		deleteCh: make(chan models.ShortLink, sizeDeleteChanel),
-		log:      zap.NewNop(),
-		gw:       &sync.WaitGroup{},
+		store:        s,
+		deleteCh:     make(chan models.ShortLink, sizeDeleteChanel),
+		log:          zap.NewNop(),
+		gw:           &sync.WaitGroup{},
+		redirectCode: http.StatusTemporaryRedirect,
+		reserved:     newReservedCodes(),
 	}
 
 	for _, opt := range options {
@@ -79,19 +165,44 @@ func New(ctx context.Context, s Store, options ...Option) *Shortner {
 }
 
 // Shorty сокращает ссылку.
-func (s *Shortner) Shorty(ctx context.Context, userID, link string) (sLink string, err error) {
-	if _, err = url.Parse(link); err != nil {
+func (s *Shortner) Shorty(ctx context.Context, userID string, link models.ShortLink) (sLink string, err error) {
+	if _, err = url.Parse(link.OriginalURL); err != nil {
 		return "", fmt.Errorf("error parsing link: %w", err)
 	}
+	if !s.isDomainAllowed(link.Domain) {
+		return "", fmt.Errorf("domain `%s`: %w", link.Domain, ErrUnknownDomain)
+	}
+	if link.RedirectCode != 0 && !models.IsValidRedirectCode(link.RedirectCode) {
+		return "", fmt.Errorf("redirect code %d: %w", link.RedirectCode, ErrInvalidRedirectCode)
+	}
+
+	if link.ShortURL != "" {
+		if err = s.ValidateAlias(link.ShortURL); err != nil {
+			return "", err
+		}
+		if err = s.checkArchived(ctx, link.Domain, link.ShortURL); err != nil {
+			return "", fmt.Errorf("alias `%s`: %w", link.ShortURL, err)
+		}
+		sLink, err = s.store.Set(ctx, userID, link)
+		if err != nil {
+			return sLink, fmt.Errorf("failed setting URL %s: %w", link.OriginalURL, err)
+		}
+		s.audit(ctx, userID, models.AuditActionCreate, link.Domain, sLink)
+		return sLink, nil
+	}
 
 	var i int
 	for {
-		sLink = util.RandomString(lengthShortLink)
-		sLink, err = s.store.Set(ctx, userID, sLink, link)
+		link.ShortURL, err = s.generateCode(ctx, link.Domain)
+		if err != nil {
+			return "", err
+		}
+		sLink, err = s.store.Set(ctx, userID, link)
 		if err != nil && !errors.Is(err, storeerror.ErrDuplicateShortURL) {
-			return sLink, fmt.Errorf("failed setting URL %s: %w", link, err)
+			return sLink, fmt.Errorf("failed setting URL %s: %w", link.OriginalURL, err)
 		}
 		if err == nil {
+			s.audit(ctx, userID, models.AuditActionCreate, link.Domain, sLink)
 			return sLink, nil
 		}
 		i++
@@ -104,14 +215,62 @@ func (s *Shortner) Shorty(ctx context.Context, userID, link string) (sLink strin
 }
 
 // GetURL возвращает оригинальную ссылку.
-func (s *Shortner) GetURL(ctx context.Context, short string) (string, error) {
-	link, err := s.store.Get(ctx, short)
+// Домен определяется по хосту запроса, неизвестный хост соответствует домену по умолчанию.
+func (s *Shortner) GetURL(ctx context.Context, host, short string) (string, error) {
+	link, err := s.GetLink(ctx, host, short)
 	if err != nil {
-		return "", fmt.Errorf("error getting link: %w", err)
+		return "", err
+	}
+	return link.OriginalURL, nil
+}
+
+// GetLink возвращает короткую ссылку с кодом перенаправления.
+// Ссылка, не найденная в хранилище, ищется в архиве.
+func (s *Shortner) GetLink(ctx context.Context, host, short string) (models.ShortLink, error) {
+	domain := s.ResolveDomain(host)
+	link, err := s.store.Get(ctx, domain, short)
+	if errors.Is(err, storeerror.ErrNotFoundKey) && s.archive != nil {
+		archived, archErr := s.archive.Lookup(ctx, domain, short)
+		switch {
+		case archErr == nil:
+			link, err = archived, nil
+		case !errors.Is(archErr, storeerror.ErrNotFoundKey):
+			s.log.Error("failed lookup archive", zap.String("short", short), zap.Error(archErr))
+		}
+	}
+	if err != nil {
+		return models.ShortLink{}, fmt.Errorf("error getting link: %w", err)
+	}
+	if s.archive != nil {
+		s.archive.Touch(link)
+	}
+	if link.RedirectCode == 0 {
+		link.RedirectCode = s.redirectCode
 	}
 	return link, nil
 }
 
+// Domains возвращает список дополнительных доменов коротких ссылок.
+func (s *Shortner) Domains() []string {
+	return slices.Clone(s.domains)
+}
+
+// ResolveDomain возвращает домен коротких ссылок по хосту запроса.
+func (s *Shortner) ResolveDomain(host string) string {
+	host = strings.ToLower(host)
+	if slices.Contains(s.domains, host) {
+		return host
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil && slices.Contains(s.domains, h) {
+		return h
+	}
+	return ""
+}
+
+func (s *Shortner) isDomainAllowed(domain string) bool {
+	return domain == "" || slices.Contains(s.domains, domain)
+}
+
 // ShortyBatch сокращает список ссылок.
 func (s *Shortner) ShortyBatch(ctx context.Context, userID string, batch []models.ShortenBatchRequest) (
 	output []models.ShortenBatchResponse,
@@ -119,10 +278,22 @@ func (s *Shortner) ShortyBatch(ctx context.Context, userID string, batch []model
 ) {
 	payload := make([]models.ShortLink, 0, len(batch))
 	for _, batchRequest := range batch {
-		short := util.RandomString(lengthShortLink)
+		if !s.isDomainAllowed(batchRequest.Domain) {
+			return []models.ShortenBatchResponse{}, fmt.Errorf("domain `%s`: %w", batchRequest.Domain, ErrUnknownDomain)
+		}
+		if batchRequest.RedirectCode != 0 && !models.IsValidRedirectCode(batchRequest.RedirectCode) {
+			return []models.ShortenBatchResponse{},
+				fmt.Errorf("redirect code %d: %w", batchRequest.RedirectCode, ErrInvalidRedirectCode)
+		}
+		short, err := s.generateCode(ctx, batchRequest.Domain)
+		if err != nil {
+			return []models.ShortenBatchResponse{}, err
+		}
 		payload = append(payload, models.ShortLink{
-			ShortURL:    short,
-			OriginalURL: batchRequest.OriginalURL,
+			ShortURL:     short,
+			OriginalURL:  batchRequest.OriginalURL,
+			Domain:       batchRequest.Domain,
+			RedirectCode: batchRequest.RedirectCode,
 		})
 	}
 	results, err := s.store.SetBatch(ctx, userID, payload)
@@ -130,10 +301,11 @@ func (s *Shortner) ShortyBatch(ctx context.Context, userID string, batch []model
 
 	for i := range results {
 		for l := range batch {
-			if results[i].OriginalURL == batch[l].OriginalURL {
+			if results[i].OriginalURL == batch[l].OriginalURL && results[i].Domain == batch[l].Domain {
 				output = append(output, models.ShortenBatchResponse{
 					CorrelationID: batch[l].CorrelationID,
 					ShortURL:      results[i].ShortURL,
+					Domain:        results[i].Domain,
 				})
 				break
 			}
@@ -142,6 +314,9 @@ func (s *Shortner) ShortyBatch(ctx context.Context, userID string, batch []model
 	if err != nil {
 		return output, fmt.Errorf("failed insert list URLs: %w", err)
 	}
+	for _, r := range results {
+		s.audit(ctx, userID, models.AuditActionCreate, r.Domain, r.ShortURL)
+	}
 
 	return output, nil
 }
@@ -155,6 +330,42 @@ func (s *Shortner) PingStore(ctx context.Context) error {
 	return nil
 }
 
+// GetUserURL возвращает ссылку пользователя вместе с удаленной.
+// Ссылка другого пользователя считается ненайденной, удаленная ссылка возвращается с ошибкой storeerror.ErrShortURLDeleted.
+func (s *Shortner) GetUserURL(ctx context.Context, userID, domain, short string) (models.ShortLink, error) {
+	link, err := s.store.Get(ctx, domain, short)
+	if err != nil && !errors.Is(err, storeerror.ErrShortURLDeleted) {
+		return models.ShortLink{}, fmt.Errorf("failed get user link: %w", err)
+	}
+	if link.UserID != userID {
+		return models.ShortLink{}, fmt.Errorf("failed get user link: %w", storeerror.ErrNotFoundKey)
+	}
+	if link.RedirectCode == 0 {
+		link.RedirectCode = s.redirectCode
+	}
+	if err != nil {
+		return link, fmt.Errorf("failed get user link: %w", err)
+	}
+	return link, nil
+}
+
+// DeleteUserURL удаляет ссылку пользователя.
+// Ошибки совпадают с GetUserURL: ссылка другого пользователя не найдена, повторное удаление - storeerror.ErrShortURLDeleted.
+func (s *Shortner) DeleteUserURL(ctx context.Context, userID, domain, short string) error {
+	deleted, err := s.deleteShortURLs(ctx, []models.ShortLink{{UserID: userID, Domain: domain, ShortURL: short}})
+	if err != nil {
+		return err
+	}
+	if len(deleted) > 0 {
+		return nil
+	}
+	// ссылка не удалена: ее нет, она принадлежит другому пользователю или уже удалена.
+	if _, err = s.GetUserURL(ctx, userID, domain, short); err != nil {
+		return err
+	}
+	return fmt.Errorf("failed delete user link: %w", storeerror.ErrShortURLDeleted)
+}
+
 // GetAllURL возврашает ссылки пользователя.
 func (s *Shortner) GetAllURL(ctx context.Context, userID string) ([]models.ShortenURL, error) {
 	data, err := s.store.GetAllURL(ctx, userID)
@@ -164,25 +375,109 @@ func (s *Shortner) GetAllURL(ctx context.Context, userID string) ([]models.Short
 	return data, nil
 }
 
+// GetUserURLs возвращает страницу ссылок пользователя в порядке создания и курсор следующей страницы.
+// Пустой курсор запрашивает первую страницу, limit 0 - размер страницы по умолчанию.
+// Для последней страницы возвращается пустой курсор.
+func (s *Shortner) GetUserURLs(ctx context.Context, userID, cursor string, limit int) (
+	links []models.ShortenURL,
+	next string,
+	err error,
+) {
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	if limit < 0 || limit > maxPageLimit {
+		return nil, "", fmt.Errorf("limit %d out of range [1, %d]: %w", limit, maxPageLimit, ErrInvalidPageLimit)
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// лишняя ссылка показывает, что следующая страница не пуста.
+	links, err = s.store.GetUserURLs(ctx, userID, models.Page{After: after, Limit: limit + 1})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed get user URLs: %w", err)
+	}
+	if len(links) > limit {
+		links = links[:limit]
+		next = encodeCursor(links[limit-1].ID)
+	}
+	return links, next, nil
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("failed decode cursor: %w", ErrInvalidCursor)
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("failed parse cursor: %w", ErrInvalidCursor)
+	}
+	return id, nil
+}
+
 // DeleteShortURLs мягкое удаление ссылки.
+// В журнал аудита записываются только удаленные ссылки: отсутствующие, чужие и уже удаленные пропускаются.
 func (s *Shortner) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) error {
-	err := s.store.DeleteShortURLs(ctx, shorts)
+	_, err := s.deleteShortURLs(ctx, shorts)
+	return err
+}
+
+// deleteShortURLs удаляет ссылки, записывает в журнал аудита и возвращает удаленные.
+func (s *Shortner) deleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
+	deleted, err := s.store.DeleteShortURLs(ctx, shorts)
 	if err != nil {
-		return fmt.Errorf("failed delete short URLs: %w", err)
+		return nil, fmt.Errorf("failed delete short URLs: %w", err)
 	}
-	return nil
+	for _, short := range deleted {
+		s.audit(ctx, short.UserID, models.AuditActionDelete, short.Domain, short.ShortURL)
+	}
+	return deleted, nil
 }
 
 // GetState Получение статисики.
-func (s *Shortner) GetState(ctx context.Context) (models.ShortenStats, error) {
-	var err error
-	res := models.ShortenStats{}
+// days - окно подсчета созданных ссылок в сутках UTC, включая текущие; top - количество пользователей
+// с наибольшим числом ссылок. 0 - значение по умолчанию. Сутки без созданных ссылок возвращаются с нулем.
+func (s *Shortner) GetState(ctx context.Context, days, top int) (models.ShortenStats, error) {
+	if days == 0 {
+		days = defaultStatsDays
+	}
+	if days < 0 || days > maxStatsDays {
+		return models.ShortenStats{}, fmt.Errorf("days %d out of range [1, %d]: %w", days, maxStatsDays, ErrInvalidStatsDays)
+	}
+	if top == 0 {
+		top = defaultStatsTop
+	}
+	if top < 0 || top > maxStatsTop {
+		return models.ShortenStats{}, fmt.Errorf("top %d out of range [1, %d]: %w", top, maxStatsTop, ErrInvalidStatsTop)
+	}
 
-	res.URLs, res.Users, err = s.store.GetState(ctx)
+	since := time.Now().UTC().Truncate(time.Hour*24).AddDate(0, 0, 1-days)
+	res, err := s.store.GetState(ctx, models.StatsQuery{Since: since, Top: top})
 	if err != nil {
 		return res, fmt.Errorf("faield get stats: %w", err)
 	}
-
+	counts := make(map[string]int, len(res.CreatedPerDay))
+	for _, day := range res.CreatedPerDay {
+		counts[day.Day] = day.Count
+	}
+	res.CreatedPerDay = make([]models.DailyCount, 0, days)
+	for i := range days {
+		day := since.AddDate(0, 0, i).Format(models.DayLayout)
+		res.CreatedPerDay = append(res.CreatedPerDay, models.DailyCount{Day: day, Count: counts[day]})
+	}
+	if res.TopUsers == nil {
+		res.TopUsers = []models.UserCount{}
+	}
 	return res, nil
 }
 
@@ -207,6 +502,58 @@ func (s *Shortner) workerDeleteingShorts(ctx context.Context) {
 	}
 }
 
+// generateCode генерирует случайный код короткой ссылки, не совпадающий с зарезервированными
+// и с кодами архивных ссылок домена.
+func (s *Shortner) generateCode(ctx context.Context, domain string) (string, error) {
+	var err error
+	for range numberOfTryGenShortLink {
+		code := util.RandomString(lengthShortLink)
+		for s.IsReserved(code) {
+			code = util.RandomString(lengthShortLink)
+		}
+		if err = s.checkArchived(ctx, domain, code); err == nil {
+			return code, nil
+		}
+		if !errors.Is(err, storeerror.ErrDuplicateShortURL) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("failed to generate a unique short link: %w", err)
+}
+
+// checkArchived возвращает storeerror.ErrDuplicateShortURL, если код занят архивной ссылкой.
+// Архивная ссылка продолжает работать, поэтому ее код нельзя выдать повторно.
+func (s *Shortner) checkArchived(ctx context.Context, domain, short string) error {
+	if s.archive == nil {
+		return nil
+	}
+	ok, err := s.archive.Contains(ctx, domain, short)
+	if err != nil {
+		return fmt.Errorf("failed check archive: %w", err)
+	}
+	if ok {
+		return fmt.Errorf("short url `%s` is archived: %w", short, storeerror.ErrDuplicateShortURL)
+	}
+	return nil
+}
+
+// audit записывает действие пользователя в журнал аудита.
+func (s *Shortner) audit(ctx context.Context, userID, action, domain, short string) {
+	if s.auditor == nil {
+		return
+	}
+	err := s.auditor.Record(ctx, models.AuditEvent{
+		UserID:   userID,
+		Action:   action,
+		Domain:   domain,
+		ShortURL: short,
+	})
+	if err != nil {
+		s.log.Error("failed record audit event",
+			zap.String("action", action), zap.String("short", short), zap.Error(err))
+	}
+}
+
 // Wait - ждет завершения горутин.
 func (s *Shortner) Wait() {
 	s.gw.Wait()
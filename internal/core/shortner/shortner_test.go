@@ -3,14 +3,18 @@ package shortner
 
 import (
 	"context"
+	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/playmixer/short-link/internal/adapters/models"
 	"github.com/playmixer/short-link/internal/adapters/storage"
 	"github.com/playmixer/short-link/internal/adapters/storage/memory"
+	"github.com/playmixer/short-link/internal/adapters/storage/storeerror"
 )
 
 func createStorage(t *testing.T) storage.Store {
@@ -49,7 +53,7 @@ func TestShortner_Shorty(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			link, err := sh.Shorty(context.Background(), tt.args.userID, tt.args.link)
+			link, err := sh.Shorty(context.Background(), tt.args.userID, models.ShortLink{OriginalURL: tt.args.link})
 			require.NoError(t, err)
 			if tt.wantSLink != "" {
 				require.Equal(t, tt.wantSLink, link)
@@ -89,3 +93,301 @@ func TestSetLogger(t *testing.T) {
 		t.Fatal("logger not set")
 	}
 }
+
+func TestShortner_ResolveDomain(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{name: "configured", host: "go.brand.com", want: "go.brand.com"},
+		{name: "with port", host: "Go.Brand.com:443", want: "go.brand.com"},
+		{name: "unknown", host: "localhost:8080", want: ""},
+		{name: "empty", host: "", want: ""},
+	}
+
+	sh := New(context.Background(), createStorage(t), SetDomains([]string{" Go.Brand.com ", "go.brand.com"}))
+	require.Equal(t, []string{"go.brand.com"}, sh.Domains())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, sh.ResolveDomain(tt.host))
+		})
+	}
+}
+
+func TestShortner_ShortyUnknownDomain(t *testing.T) {
+	sh := New(context.Background(), createStorage(t))
+	_, err := sh.Shorty(context.Background(), "1", models.ShortLink{OriginalURL: "https://github.com/", Domain: "evil.com"})
+	require.ErrorIs(t, err, ErrUnknownDomain)
+}
+
+func TestShortner_ValidateAlias(t *testing.T) {
+	sh := New(context.Background(), createStorage(t), SetReservedCodes([]string{"admin"}))
+	sh.ReserveCodes("ping", "api")
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr error
+	}{
+		{name: "valid", alias: "my-link_1"},
+		{name: "configured reserved", alias: "admin", wantErr: ErrReservedCode},
+		{name: "route reserved", alias: "ping", wantErr: ErrReservedCode},
+		{name: "invalid symbols", alias: "a/b", wantErr: ErrInvalidAlias},
+		{name: "empty", alias: "", wantErr: ErrInvalidAlias},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sh.ValidateAlias(tt.alias)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestShortner_ShortyAlias(t *testing.T) {
+	ctx := context.Background()
+	sh := New(ctx, createStorage(t))
+	sh.ReserveCodes("ping")
+
+	short, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://github.com/", ShortURL: "gh"})
+	require.NoError(t, err)
+	require.Equal(t, "gh", short)
+
+	_, err = sh.Shorty(ctx, "2", models.ShortLink{OriginalURL: "https://gitlab.com/", ShortURL: "gh"})
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+
+	_, err = sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://gitlab.com/", ShortURL: "ping"})
+	require.ErrorIs(t, err, ErrReservedCode)
+}
+
+func TestShortner_ShadowedLinks(t *testing.T) {
+	ctx := context.Background()
+	store := createStorage(t)
+	_, err := store.Set(ctx, "1", models.ShortLink{OriginalURL: "https://github.com/", ShortURL: "ping"})
+	require.NoError(t, err)
+	_, err = store.Set(ctx, "1", models.ShortLink{OriginalURL: "https://gitlab.com/", ShortURL: "abc"})
+	require.NoError(t, err)
+
+	sh := New(ctx, store)
+	sh.ReserveCodes("ping", "api")
+	links, err := sh.ShadowedLinks(ctx)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Equal(t, "ping", links[0].ShortURL)
+	require.Equal(t, "https://github.com/", links[0].OriginalURL)
+}
+
+func TestShortner_GetUserURLs(t *testing.T) {
+	ctx := context.Background()
+	sh := New(ctx, createStorage(t))
+	for _, short := range []string{"a1", "a2", "a3"} {
+		_, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://" + short + ".ru/", ShortURL: short})
+		require.NoError(t, err)
+	}
+
+	links, next, err := sh.GetUserURLs(ctx, "1", "", 2)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, "a1", links[0].ShortURL)
+	require.NotEmpty(t, next)
+
+	links, next, err = sh.GetUserURLs(ctx, "1", next, 2)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Equal(t, "a3", links[0].ShortURL)
+	require.Empty(t, next)
+
+	_, _, err = sh.GetUserURLs(ctx, "1", "not a cursor", 2)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+	_, _, err = sh.GetUserURLs(ctx, "1", "", maxPageLimit+1)
+	require.ErrorIs(t, err, ErrInvalidPageLimit)
+}
+
+func TestShortner_GetState(t *testing.T) {
+	ctx := context.Background()
+	sh := New(ctx, createStorage(t))
+	for _, short := range []string{"a1", "a2"} {
+		_, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://" + short + ".ru/", ShortURL: short})
+		require.NoError(t, err)
+	}
+
+	stats, err := sh.GetState(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.URLs)
+	require.Len(t, stats.CreatedPerDay, defaultStatsDays)
+	today := stats.CreatedPerDay[defaultStatsDays-1]
+	require.Equal(t, time.Now().UTC().Format(models.DayLayout), today.Day)
+	require.Equal(t, 2, today.Count)
+	require.Equal(t, 0, stats.CreatedPerDay[0].Count)
+	require.Equal(t, []models.UserCount{{UserID: "1", URLs: 2}}, stats.TopUsers)
+
+	stats, err = sh.GetState(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats.CreatedPerDay, 1)
+
+	_, err = sh.GetState(ctx, maxStatsDays+1, 0)
+	require.ErrorIs(t, err, ErrInvalidStatsDays)
+	_, err = sh.GetState(ctx, 0, -1)
+	require.ErrorIs(t, err, ErrInvalidStatsTop)
+}
+
+func TestShortner_UserURL(t *testing.T) {
+	ctx := context.Background()
+	sh := New(ctx, createStorage(t), SetDomains([]string{"go.brand.com"}))
+	_, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://a.ru/", ShortURL: "a1", Domain: "go.brand.com"})
+	require.NoError(t, err)
+
+	link, err := sh.GetUserURL(ctx, "1", "go.brand.com", "a1")
+	require.NoError(t, err)
+	require.Equal(t, "https://a.ru/", link.OriginalURL)
+	require.Equal(t, http.StatusTemporaryRedirect, link.RedirectCode)
+	require.False(t, link.CreatedAt.IsZero())
+
+	_, err = sh.GetUserURL(ctx, "2", "go.brand.com", "a1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	_, err = sh.GetUserURL(ctx, "1", "", "a1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	require.ErrorIs(t, sh.DeleteUserURL(ctx, "2", "go.brand.com", "a1"), storeerror.ErrNotFoundKey)
+
+	require.NoError(t, sh.DeleteUserURL(ctx, "1", "go.brand.com", "a1"))
+	_, err = sh.GetUserURL(ctx, "1", "go.brand.com", "a1")
+	require.ErrorIs(t, err, storeerror.ErrShortURLDeleted)
+	require.ErrorIs(t, sh.DeleteUserURL(ctx, "1", "go.brand.com", "a1"), storeerror.ErrShortURLDeleted)
+	_, err = sh.GetUserURL(ctx, "2", "go.brand.com", "a1")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+}
+
+// testAuditor журнал аудита в памяти.
+type testAuditor struct {
+	events []models.AuditEvent
+}
+
+func (a *testAuditor) Record(ctx context.Context, event models.AuditEvent) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func TestShortner_DeleteShortURLsAudit(t *testing.T) {
+	ctx := context.Background()
+	auditor := &testAuditor{}
+	sh := New(ctx, createStorage(t), SetAuditor(auditor))
+	_, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://a.ru/", ShortURL: "a1"})
+	require.NoError(t, err)
+	_, err = sh.Shorty(ctx, "2", models.ShortLink{OriginalURL: "https://b.ru/", ShortURL: "b1"})
+	require.NoError(t, err)
+	auditor.events = nil
+
+	err = sh.DeleteShortURLs(ctx, []models.ShortLink{
+		{UserID: "1", ShortURL: "a1"},
+		{UserID: "1", ShortURL: "b1"}, // чужая ссылка.
+		{UserID: "1", ShortURL: "unknown"},
+	})
+	require.NoError(t, err)
+	// повторное удаление.
+	require.NoError(t, sh.DeleteShortURLs(ctx, []models.ShortLink{{UserID: "1", ShortURL: "a1"}}))
+
+	require.Len(t, auditor.events, 1)
+	require.Equal(t, models.AuditActionDelete, auditor.events[0].Action)
+	require.Equal(t, "a1", auditor.events[0].ShortURL)
+}
+
+// racingStore хранилище, в котором ссылку удаляют одновременно с запросом пользователя.
+type racingStore struct {
+	storage.Store
+}
+
+func (s *racingStore) DeleteShortURLs(ctx context.Context, shorts []models.ShortLink) ([]models.ShortLink, error) {
+	if _, err := s.Store.DeleteShortURLs(ctx, shorts); err != nil {
+		return nil, err
+	}
+	return s.Store.DeleteShortURLs(ctx, shorts)
+}
+
+func TestShortner_DeleteUserURLConcurrent(t *testing.T) {
+	ctx := context.Background()
+	auditor := &testAuditor{}
+	sh := New(ctx, &racingStore{createStorage(t)}, SetAuditor(auditor))
+	_, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://a.ru/", ShortURL: "a1"})
+	require.NoError(t, err)
+	auditor.events = nil
+
+	require.ErrorIs(t, sh.DeleteUserURL(ctx, "1", "", "a1"), storeerror.ErrShortURLDeleted)
+	require.Empty(t, auditor.events)
+}
+
+// testArchive архив из одной ссылки.
+type testArchive struct {
+	link    models.ShortLink
+	touched []string
+	checked []string
+	busy    int // количество следующих проверенных кодов, занятых в архиве.
+}
+
+func (a *testArchive) Touch(link models.ShortLink) {
+	a.touched = append(a.touched, link.ShortURL)
+}
+
+func (a *testArchive) Lookup(ctx context.Context, domain, short string) (models.ShortLink, error) {
+	if domain == a.link.Domain && short == a.link.ShortURL {
+		return a.link, nil
+	}
+	return models.ShortLink{}, storeerror.ErrNotFoundKey
+}
+
+func (a *testArchive) Contains(ctx context.Context, domain, short string) (bool, error) {
+	a.checked = append(a.checked, short)
+	if a.busy > 0 {
+		a.busy--
+		return true, nil
+	}
+	return domain == a.link.Domain && short == a.link.ShortURL, nil
+}
+
+func TestShortner_GetLinkArchive(t *testing.T) {
+	ctx := context.Background()
+	archive := &testArchive{link: models.ShortLink{ShortURL: "old", OriginalURL: "https://old.ru/"}}
+	sh := New(ctx, createStorage(t), SetArchive(archive))
+	short, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://new.ru/"})
+	require.NoError(t, err)
+
+	original, err := sh.GetURL(ctx, "", "old")
+	require.NoError(t, err)
+	require.Equal(t, "https://old.ru/", original)
+	original, err = sh.GetURL(ctx, "", short)
+	require.NoError(t, err)
+	require.Equal(t, "https://new.ru/", original)
+	_, err = sh.GetURL(ctx, "", "unknown")
+	require.ErrorIs(t, err, storeerror.ErrNotFoundKey)
+	require.Equal(t, []string{"old", short}, archive.touched)
+}
+
+func TestShortner_ArchivedCodeTaken(t *testing.T) {
+	ctx := context.Background()
+	archive := &testArchive{link: models.ShortLink{ShortURL: "old", OriginalURL: "https://old.ru/"}}
+	sh := New(ctx, createStorage(t), SetArchive(archive))
+
+	// код архивной ссылки не выдается как пользовательский.
+	_, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://new.ru/", ShortURL: "old"})
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+	original, err := sh.GetURL(ctx, "", "old")
+	require.NoError(t, err)
+	require.Equal(t, "https://old.ru/", original)
+
+	// сгенерированный код, занятый в архиве, генерируется заново.
+	archive.checked, archive.busy = nil, 2
+	short, err := sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://new.ru/"})
+	require.NoError(t, err)
+	require.Len(t, archive.checked, 3)
+	require.Equal(t, archive.checked[2], short)
+
+	archive.busy = numberOfTryGenShortLink
+	_, err = sh.Shorty(ctx, "1", models.ShortLink{OriginalURL: "https://other.ru/"})
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+	archive.busy = numberOfTryGenShortLink
+	_, err = sh.ShortyBatch(ctx, "1", []models.ShortenBatchRequest{{OriginalURL: "https://other.ru/"}})
+	require.ErrorIs(t, err, storeerror.ErrDuplicateShortURL)
+}
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE public.short_link DROP CONSTRAINT IF EXISTS short_link_domain_short_url_pk;
+DELETE FROM public.short_link WHERE domain <> '';
+ALTER TABLE public.short_link DROP COLUMN IF EXISTS domain;
+ALTER TABLE public.short_link ADD CONSTRAINT short_url_pk PRIMARY KEY (short_url);
+CREATE UNIQUE INDEX IF NOT EXISTS short_link_short_url_idx ON public.short_link (short_url);
+
+COMMIT;
\ No newline at end of file
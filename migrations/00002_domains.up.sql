@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE public.short_link ADD COLUMN IF NOT EXISTS domain varchar DEFAULT '' NOT NULL;
+ALTER TABLE public.short_link DROP CONSTRAINT IF EXISTS short_url_pk;
+DROP INDEX IF EXISTS short_link_short_url_idx;
+ALTER TABLE public.short_link ADD CONSTRAINT short_link_domain_short_url_pk PRIMARY KEY (domain, short_url);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE public.short_link DROP COLUMN IF EXISTS redirect_code;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE public.short_link ADD COLUMN IF NOT EXISTS redirect_code int4 DEFAULT 0 NOT NULL;
+
+COMMIT;
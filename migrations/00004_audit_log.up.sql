@@ -0,0 +1,33 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS public.audit_log (
+	id int8 NOT NULL,
+	created_at timestamptz NOT NULL,
+	user_id varchar NOT NULL,
+	action varchar NOT NULL,
+	domain varchar DEFAULT '' NOT NULL,
+	short_url varchar NOT NULL,
+	transport varchar NOT NULL,
+	ip varchar NOT NULL,
+	prev_hash varchar NOT NULL,
+	hash varchar NOT NULL,
+	CONSTRAINT audit_log_pk PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON public.audit_log (user_id);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON public.audit_log (created_at);
+
+CREATE OR REPLACE FUNCTION public.audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+	BEFORE UPDATE OR DELETE ON public.audit_log
+	FOR EACH ROW EXECUTE FUNCTION public.audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+	BEFORE TRUNCATE ON public.audit_log
+	FOR EACH STATEMENT EXECUTE FUNCTION public.audit_log_append_only();
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS public.short_link_user_id_id_idx;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+CREATE INDEX IF NOT EXISTS short_link_user_id_id_idx ON public.short_link (user_id, id);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS public.short_link_user_id_original_url_idx;
+
+COMMIT;
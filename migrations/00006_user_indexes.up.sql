@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+-- выборки по пользователю используют индекс short_link_user_id_id_idx.
+CREATE INDEX IF NOT EXISTS short_link_user_id_original_url_idx ON public.short_link (user_id, original_url);
+
+COMMIT;
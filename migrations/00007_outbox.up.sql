@@ -0,0 +1,17 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS public.outbox (
+	id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	event_type varchar NOT NULL,
+	user_id varchar NOT NULL,
+	domain varchar DEFAULT '' NOT NULL,
+	short_url varchar NOT NULL,
+	original_url varchar NOT NULL,
+	sent_at timestamptz,
+	CONSTRAINT outbox_pk PRIMARY KEY (id)
+);
+-- relay выбирает только неотправленные события.
+CREATE INDEX IF NOT EXISTS outbox_unsent_idx ON public.outbox (id) WHERE sent_at IS NULL;
+
+COMMIT;
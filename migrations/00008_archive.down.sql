@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+DROP TABLE IF EXISTS public.short_link_archive;
+DROP INDEX IF EXISTS short_link_created_at_idx;
+DROP INDEX IF EXISTS short_link_activity_idx;
+ALTER TABLE public.short_link DROP COLUMN IF EXISTS accessed_at;
+ALTER TABLE public.short_link DROP COLUMN IF EXISTS created_at;
+
+COMMIT;
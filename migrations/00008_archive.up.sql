@@ -0,0 +1,22 @@
+BEGIN TRANSACTION;
+
+-- ссылки, созданные до миграции, считаются созданными в момент ее применения.
+ALTER TABLE public.short_link ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now() NOT NULL;
+ALTER TABLE public.short_link ADD COLUMN IF NOT EXISTS accessed_at timestamptz;
+CREATE INDEX IF NOT EXISTS short_link_activity_idx ON public.short_link (coalesce(accessed_at, created_at)) WHERE is_deleted = false;
+CREATE INDEX IF NOT EXISTS short_link_created_at_idx ON public.short_link (created_at) WHERE is_deleted = false;
+
+CREATE TABLE IF NOT EXISTS public.short_link_archive (
+	domain varchar DEFAULT '' NOT NULL,
+	short_url varchar NOT NULL,
+	original_url varchar NOT NULL,
+	user_id varchar NOT NULL,
+	redirect_code int4 DEFAULT 0 NOT NULL,
+	created_at timestamptz NOT NULL,
+	accessed_at timestamptz,
+	archived_at timestamptz DEFAULT now() NOT NULL,
+	CONSTRAINT short_link_archive_pk PRIMARY KEY (domain, short_url)
+);
+CREATE INDEX IF NOT EXISTS short_link_archive_archived_at_idx ON public.short_link_archive (archived_at);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS public.short_link_user_id_original_hash_idx;
+ALTER TABLE public.short_link DROP COLUMN IF EXISTS original_hash;
+
+COMMIT;
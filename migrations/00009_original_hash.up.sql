@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+-- слепой индекс зашифрованной оригинальной ссылки, у открытых ссылок пустой.
+ALTER TABLE public.short_link ADD COLUMN IF NOT EXISTS original_hash varchar;
+CREATE INDEX IF NOT EXISTS short_link_user_id_original_hash_idx ON public.short_link (user_id, domain, original_hash) WHERE original_hash IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,9 @@
+// Модуль migrations содержит миграции базы данных Postgres, встроенные в бинарный файл.
+package migrations
+
+import "embed"
+
+// FS файлы миграций.
+//
+//go:embed *.sql
+var FS embed.FS
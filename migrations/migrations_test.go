@@ -0,0 +1,37 @@
+package migrations_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/playmixer/short-link/migrations"
+)
+
+func TestFS(t *testing.T) {
+	source, err := iofs.New(migrations.FS, ".")
+	require.NoError(t, err)
+	defer func() { _ = source.Close() }()
+
+	version, err := source.First()
+	require.NoError(t, err)
+	for {
+		r, _, err := source.ReadUp(version)
+		require.NoError(t, err, "up migration %d", version)
+		require.NoError(t, r.Close())
+		r, _, err = source.ReadDown(version)
+		require.NoError(t, err, "down migration %d", version)
+		require.NoError(t, r.Close())
+
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		require.NoError(t, err)
+		version = next
+	}
+	require.Equal(t, uint(9), version)
+}